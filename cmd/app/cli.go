@@ -0,0 +1,178 @@
+package main
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gorm.io/gorm/logger"
+)
+
+const usage = `用法: b1cron [命令] [选项]
+
+不带命令时与 serve 相同。
+
+服务:
+  serve                       启动 Web 服务和调度器
+
+通过运行中的服务的 API（需要 API 令牌）:
+  task list                   列出任务
+  task create                 创建任务
+  task run <id|slug>          立即执行任务
+  task enable <id|slug>       启用任务
+  task disable <id|slug>      禁用任务
+  exec logs <id>              查看执行记录的输出（-task <id|slug> 查看任务最近一次执行）
+
+直接操作数据库（服务停止时也可以使用）:
+  user create <用户名>         创建用户
+  user reset-password <用户名> 重置密码并解除锁定，用于找回管理员密码
+  db migrate                  执行数据库迁移
+  db backup <文件>             备份数据库
+  config validate             检查配置文件
+
+各命令的选项见 b1cron <命令> <子命令> -h。
+`
+
+// errUsage 参数错误，输出用法后以状态码 2 退出
+var errUsage = errors.New("usage")
+
+// command 子命令，args 为子命令之后的参数
+type command func(args []string) error
+
+var commands = map[string]map[string]command{
+	"task": {
+		"list":    taskList,
+		"create":  taskCreate,
+		"run":     taskRun,
+		"enable":  func(args []string) error { return taskSetEnabled("enable", args, true) },
+		"disable": func(args []string) error { return taskSetEnabled("disable", args, false) },
+	},
+	"exec": {
+		"logs": execLogs,
+	},
+	"user": {
+		"create":         userCreate,
+		"reset-password": userResetPassword,
+	},
+	"db": {
+		"migrate": dbMigrate,
+		"backup":  dbBackup,
+	},
+	"config": {
+		"validate": configValidate,
+	},
+}
+
+// runCommand 执行子命令并返回进程退出码
+func runCommand(name string, args []string) int {
+	switch name {
+	case "serve":
+		serve(args)
+		return 0
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	}
+
+	group, ok := commands[name]
+	if !ok || len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	cmd, ok := group[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s %s\n\n%s", name, args[0], usage)
+		return 2
+	}
+
+	if err := cmd(args[1:]); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet 创建子命令的选项集，解析失败时由 runCommand 统一处理退出码
+func newFlagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "用法: b1cron %s [选项] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs 解析选项并返回位置参数，位置参数前后都可以有选项；数量不符时输出用法
+func parseArgs(flags *flag.FlagSet, args []string, positional int) ([]string, error) {
+	var rest []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		rest = append(rest, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if positional >= 0 && len(rest) != positional {
+		flags.Usage()
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+// openDatabase 按配置文件打开已有的数据库，不执行迁移，不输出 SQL 日志
+func openDatabase(configPath string) (*config.Config, error) {
+	return loadDatabase(configPath, database.OpenDatabase)
+}
+
+// migrateDatabase 按配置文件打开数据库并执行迁移，不输出 SQL 日志
+func migrateDatabase(configPath string) (*config.Config, error) {
+	return loadDatabase(configPath, database.InitDatabase)
+}
+
+func loadDatabase(configPath string, open func(dbPath string) error) (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	database.LogLevel = logger.Silent
+	if err := open(cfg.Database.Path); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readInput 读取文件内容，"-" 表示标准输入
+func readInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// randomPassword 生成一次性的随机密码，用户登录后必须修改
+func randomPassword() (string, error) {
+	buf := make([]byte, 9)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cliActor 命令行直接操作数据库时写入审计日志的操作者名称
+const cliActor = "cli"
+
+// recordCLIUserAudit 记录命令行对用户的操作
+func recordCLIUserAudit(action string, user *models.User, before, after audit.Snapshot) {
+	audit.Record(audit.Entry{
+		Action:     action,
+		ActorName:  cliActor,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// userCreate 直接在数据库中创建用户，首次登录必须修改密码
+func userCreate(args []string) error {
+	flags := newFlagSet("user create", "<用户名>")
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	role := flags.String("role", auth.RoleViewer, "角色: admin、operator 或 viewer")
+	password := flags.String("password", "", "初始密码，留空时随机生成并输出")
+	rest, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, err := openDatabase(*configPath); err != nil {
+		return err
+	}
+	generated := *password == ""
+	if generated {
+		var err error
+		if *password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+
+	// 与 Web 界面创建用户相同，新用户加入默认命名空间，用户和成员关系在同一个事务中写入
+	defaultID, err := database.DefaultNamespaceID()
+	if err != nil {
+		return err
+	}
+	user, err := service.NewUserService().CreateUser(rest[0], *password, *role, []uint{defaultID})
+	if err != nil {
+		return err
+	}
+	recordCLIUserAudit(audit.ActionUserCreate, user, nil, audit.UserSnapshot(user, []uint{defaultID}))
+	fmt.Printf("User %s created (id %d, role %s)\n", user.Username, user.ID, user.Role)
+	if generated {
+		fmt.Printf("Password: %s\n", *password)
+	}
+	return nil
+}
+
+// userResetPassword 重置本地用户的密码并解除登录锁定，现有会话全部失效；
+// 忘记管理员密码时可以在服务器上用它找回
+func userResetPassword(args []string) error {
+	flags := newFlagSet("user reset-password", "<用户名>")
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	password := flags.String("password", "", "新密码，留空时随机生成并输出")
+	rest, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, err := openDatabase(*configPath); err != nil {
+		return err
+	}
+	users := service.NewUserService()
+	user, err := users.GetUserByUsername(rest[0])
+	if err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		if *password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+	namespaceIDs, err := service.NewNamespaceService().UserNamespaceIDs(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := users.ResetPassword(user.ID, *password); err != nil {
+		return err
+	}
+	// 与管理界面重置密码、解除锁定相同，分别记录审计日志，只记录密码被重置
+	snapshot := audit.UserSnapshot(user, namespaceIDs)
+	after := audit.UserSnapshot(user, namespaceIDs)
+	after["password_reset"] = true
+	recordCLIUserAudit(audit.ActionUserUpdate, user, snapshot, after)
+
+	if _, err := users.UnlockUser(user.ID); err != nil {
+		return err
+	}
+	recordCLIUserAudit(audit.ActionUserUnlock, user, nil, nil)
+
+	fmt.Printf("Password of %s reset, it must be changed at the next login\n", user.Username)
+	if generated {
+		fmt.Printf("Password: %s\n", *password)
+	}
+	return nil
+}
+
+// dbMigrate 执行数据库迁移，升级前可以先单独运行
+func dbMigrate(args []string) error {
+	flags := newFlagSet("db migrate", "")
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := migrateDatabase(*configPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database %s is up to date\n", cfg.Database.Path)
+	return nil
+}
+
+// dbBackup 把数据库备份到新文件
+func dbBackup(args []string) error {
+	flags := newFlagSet("db backup", "<文件>")
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	rest, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	if _, err := openDatabase(*configPath); err != nil {
+		return err
+	}
+	if err := database.Backup(rest[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Database backed up to %s\n", rest[0])
+	return nil
+}
+
+// configValidate 加载并校验配置文件
+func configValidate(args []string) error {
+	flags := newFlagSet("config validate", "")
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	// 服务启动时会忽略未知的配置项，这里严格检查以发现拼写错误
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config.Config{}); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	fmt.Printf("%s is valid (listen %s, database %s)\n", *configPath, cfg.GetAddr(), cfg.Database.Path)
+	return nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/models"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiClient 通过 API 令牌访问运行中的服务
+type apiClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// clientOptions 访问 API 的子命令共用的选项
+type clientOptions struct {
+	server *string
+	token  *string
+	config *string
+}
+
+func addClientFlags(flags *flag.FlagSet) *clientOptions {
+	return &clientOptions{
+		server: flags.String("server", "", "服务地址，默认读取 B1CRON_SERVER，未设置时按配置文件中的端口访问本机"),
+		token:  flags.String("token", "", "API 令牌，默认读取 B1CRON_TOKEN"),
+		config: flags.String("config", "config.yaml", "配置文件路径，仅用于确定默认服务地址"),
+	}
+}
+
+func (o *clientOptions) client() (*apiClient, error) {
+	token := firstNonEmpty(*o.token, os.Getenv("B1CRON_TOKEN"))
+	if token == "" {
+		return nil, fmt.Errorf("an API token is required, pass -token or set B1CRON_TOKEN")
+	}
+
+	server := firstNonEmpty(*o.server, os.Getenv("B1CRON_SERVER"))
+	if server == "" {
+		server = o.localServer()
+	}
+
+	return &apiClient{
+		server: strings.TrimRight(server, "/"),
+		token:  token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// localServer 按配置文件中的监听地址访问本机；只读取 server 部分，不做完整的配置校验
+func (o *clientOptions) localServer() string {
+	var file struct {
+		Server config.ServerConfig `yaml:"server"`
+	}
+	data, err := os.ReadFile(*o.config)
+	if err != nil || yaml.Unmarshal(data, &file) != nil || file.Server.Port == 0 {
+		return "http://127.0.0.1:8080"
+	}
+
+	host := file.Server.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(file.Server.Port))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// do 发送请求，body 不为 nil 时编码为 JSON，响应解码到 out；非 2xx 响应返回服务端的错误信息
+func (c *apiClient) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.server, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && (apiErr.Error != "" || apiErr.Message != "") {
+			return fmt.Errorf("%s: %s%s", resp.Status, apiErr.Error, apiErr.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// resolveTask 把任务 ID 或 slug 转换为任务 ID
+func (c *apiClient) resolveTask(ref string) (uint, error) {
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return uint(id), nil
+	}
+
+	var tasks []models.Task
+	if err := c.do(http.MethodGet, "/api/tasks", nil, &tasks); err != nil {
+		return 0, err
+	}
+	for _, task := range tasks {
+		if task.Slug == ref {
+			return task.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("task not found: %s", ref)
+}
+
+// taskList 列出当前令牌可以访问的任务
+func taskList(args []string) error {
+	flags := newFlagSet("task list", "")
+	opts := addClientFlags(flags)
+	asJSON := flags.Bool("json", false, "输出 JSON")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	var tasks []models.Task
+	if err := client.do(http.MethodGet, "/api/tasks", nil, &tasks); err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tasks)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSLUG\tENABLED\tSCHEDULE\tNAME")
+	for _, task := range tasks {
+		schedule := task.ScheduleSpec
+		if task.ScheduleType == "once" && task.ExecuteAt != nil {
+			schedule = "once " + task.ExecuteAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%v\t%s\t%s\n", task.ID, task.Slug, task.IsEnabled, schedule, task.Name)
+	}
+	return w.Flush()
+}
+
+// envFlag 可重复的 KEY=VALUE 选项
+type envFlag map[string]string
+
+func (e envFlag) String() string { return "" }
+
+func (e envFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE")
+	}
+	e[key] = val
+	return nil
+}
+
+// taskCreate 创建任务，脚本内容来自 -command 或 -file
+func taskCreate(args []string) error {
+	flags := newFlagSet("task create", "")
+	opts := addClientFlags(flags)
+	name := flags.String("name", "", "任务名称（必填）")
+	schedule := flags.String("schedule", "", "Cron 表达式，例如 \"0 2 * * *\"")
+	at := flags.String("at", "", "一次性任务的执行时间，格式 YYYY-MM-DDTHH:MM（与 -schedule 二选一）")
+	scriptType := flags.String("type", "command", "脚本类型: command 或运行时名称，如 shell、python、node")
+	command := flags.String("command", "", "命令或脚本内容")
+	file := flags.String("file", "", "从文件读取脚本内容，- 表示标准输入")
+	requirements := flags.String("requirements", "", "Python 任务的依赖文件（pip requirements 格式）")
+	libraries := flags.String("libraries", "", "共享库模块，逗号分隔，例如 retry,slack_notify@3")
+	slug := flags.String("slug", "", "任务标识，留空时根据名称生成")
+	namespaceID := flags.Uint("namespace-id", 0, "命名空间 ID，默认 default")
+	disabled := flags.Bool("disabled", false, "创建后先不启用")
+	env := envFlag{}
+	flags.Var(env, "env", "环境变量 KEY=VALUE，可重复")
+	if _, err := parseArgs(flags, args, 0); err != nil {
+		return err
+	}
+
+	if *name == "" || (*schedule == "") == (*at == "") || (*command == "") == (*file == "") {
+		fmt.Fprintln(flags.Output(), "-name, one of -schedule or -at, and one of -command or -file are required")
+		flags.Usage()
+		return errUsage
+	}
+	if *file != "" {
+		content, err := readInput(*file)
+		if err != nil {
+			return err
+		}
+		command = &content
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	req := map[string]interface{}{
+		"name":          *name,
+		"command":       *command,
+		"script_type":   *scriptType,
+		"schedule_type": "cron",
+		"schedule_spec": *schedule,
+		"is_enabled":    !*disabled,
+		"namespace_id":  *namespaceID,
+		"slug":          *slug,
+	}
+	if *at != "" {
+		req["schedule_type"] = "once"
+		req["execute_at"] = *at
+	}
+	if len(env) > 0 {
+		req["env"] = env
+	}
+	if *requirements != "" {
+		content, err := readInput(*requirements)
+		if err != nil {
+			return err
+		}
+		req["requirements"] = content
+	}
+	if *libraries != "" {
+		req["libraries"] = strings.Split(*libraries, ",")
+	}
+
+	var task models.Task
+	if err := client.do(http.MethodPost, "/api/tasks", req, &task); err != nil {
+		return err
+	}
+	fmt.Printf("Task %d (%s) created\n", task.ID, task.Slug)
+	return nil
+}
+
+// taskRun 立即在服务端执行一次任务
+func taskRun(args []string) error {
+	flags := newFlagSet("task run", "<id|slug>")
+	opts := addClientFlags(flags)
+	rest, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	id, err := client.resolveTask(rest[0])
+	if err != nil {
+		return err
+	}
+	if err := client.do(http.MethodPost, fmt.Sprintf("/api/tasks/%d/run", id), nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("Task %d started, see b1cron exec logs -task %d\n", id, id)
+	return nil
+}
+
+// taskSetEnabled 启用或禁用任务，状态已经一致时不做修改
+func taskSetEnabled(name string, args []string, enabled bool) error {
+	flags := newFlagSet("task "+name, "<id|slug>")
+	opts := addClientFlags(flags)
+	rest, err := parseArgs(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	id, err := client.resolveTask(rest[0])
+	if err != nil {
+		return err
+	}
+	var task struct {
+		IsEnabled bool `json:"is_enabled"`
+	}
+	if err := client.do(http.MethodGet, fmt.Sprintf("/api/tasks/%d", id), nil, &task); err != nil {
+		return err
+	}
+
+	state := map[bool]string{true: "enabled", false: "disabled"}[enabled]
+	if task.IsEnabled == enabled {
+		fmt.Printf("Task %d is already %s\n", id, state)
+		return nil
+	}
+	if err := client.do(http.MethodPatch, fmt.Sprintf("/api/tasks/%d/toggle", id), nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("Task %d %s\n", id, state)
+	return nil
+}
+
+// execLogs 输出一条执行记录的输出和错误信息
+func execLogs(args []string) error {
+	flags := newFlagSet("exec logs", "[<执行记录 id>]")
+	opts := addClientFlags(flags)
+	taskRef := flags.String("task", "", "查看该任务（ID 或 slug）最近一次执行，代替执行记录 ID")
+	rest, err := parseArgs(flags, args, -1)
+	if err != nil {
+		return err
+	}
+	if (len(rest) == 1) == (*taskRef != "") || len(rest) > 1 {
+		flags.Usage()
+		return errUsage
+	}
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	var execution models.TaskExecution
+	if *taskRef != "" {
+		id, err := client.resolveTask(*taskRef)
+		if err != nil {
+			return err
+		}
+		var executions []models.TaskExecution
+		if err := client.do(http.MethodGet, fmt.Sprintf("/api/tasks/%d/executions?limit=1", id), nil, &executions); err != nil {
+			return err
+		}
+		if len(executions) == 0 {
+			return fmt.Errorf("task %d has no executions", id)
+		}
+		execution = executions[0]
+	} else {
+		id, err := strconv.ParseUint(rest[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid execution ID: %s", rest[0])
+		}
+		if err := client.do(http.MethodGet, fmt.Sprintf("/api/executions/%d", id), nil, &execution); err != nil {
+			return err
+		}
+	}
+
+	exitCode := "-"
+	if execution.ExitCode != nil {
+		exitCode = strconv.Itoa(*execution.ExitCode)
+	}
+	fmt.Fprintf(os.Stderr, "# execution %d of task %d: %s, exit code %s, started %s, %dms\n",
+		execution.ID, execution.TaskID, execution.Status, exitCode, execution.StartedAt.Local().Format("2006-01-02 15:04:05"), execution.Duration)
+	fmt.Print(execution.Output)
+	if execution.Output != "" && !strings.HasSuffix(execution.Output, "\n") {
+		fmt.Println()
+	}
+	if execution.ErrorMsg != "" {
+		fmt.Fprintln(os.Stderr, execution.ErrorMsg)
+	}
+	if execution.Status == "failed" {
+		return fmt.Errorf("execution %d failed", execution.ID)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"b1cron/internal/audit"
 	"b1cron/internal/auth"
 	"b1cron/internal/config"
 	"b1cron/internal/database"
@@ -20,15 +21,25 @@ import (
 	"syscall"
 	"time"
 
+	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	jwt "github.com/appleboy/gin-jwt/v2"
 )
 
 func main() {
+	// 第一个参数不是选项时按子命令处理，否则与 serve 相同，直接启动服务
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+	serve(os.Args[1:])
+}
+
+// serve 启动 Web 服务和调度器，直到收到中断信号
+func serve(args []string) {
 	// 解析命令行参数
-	configPath := flag.String("config", "config.yaml", "配置文件路径")
-	flag.Parse()
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := flags.String("config", "config.yaml", "配置文件路径")
+	flags.Parse(args)
 
 	// 加载配置
 	cfg, err := config.LoadConfig(*configPath)
@@ -44,6 +55,11 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// 旧版本的审计日志中记录了环境变量的值
+	if err := audit.RedactStoredSecrets(); err != nil {
+		log.Fatal("Failed to redact audit logs:", err)
+	}
+
 	// 创建默认用户
 	if err := createDefaultUser(cfg); err != nil {
 		log.Fatal("Failed to create default user:", err)
@@ -55,16 +71,55 @@ func main() {
 		log.Fatal("Failed to create scheduler service:", err)
 	}
 
+	// 声明了依赖的 Python 任务在缓存的虚拟环境中执行
+	venvService := service.NewVenvService(cfg)
+	schedulerService.SetTaskEnvironment(venvService)
+
+	// 脚本内容保存在数据库中，执行前和任务引用的共享库模块一起写入临时目录；旧版本的脚本文件在启动时迁移到数据库
+	libraryService := service.NewLibraryService(cfg)
+	taskService := service.NewTaskService(schedulerService, venvService, libraryService, cfg)
+	if err := taskService.MigrateScriptFiles(); err != nil {
+		log.Fatal("Failed to migrate script files:", err)
+	}
+	schedulerService.SetScriptMaterializer(taskService)
+
 	if err := schedulerService.Start(); err != nil {
 		log.Fatal("Failed to start scheduler service:", err)
 	}
 
 	// 创建服务和处理器
-	taskService := service.NewTaskService(schedulerService, cfg)
-	taskHandler := handler.NewTaskHandler(taskService)
+	if err := taskService.EnsureInitialRevisions(); err != nil {
+		log.Fatal("Failed to record initial task revisions:", err)
+	}
+	if err := taskService.EnsureTaskSlugs(); err != nil {
+		log.Fatal("Failed to generate task slugs:", err)
+	}
+	namespaceService := service.NewNamespaceService()
+	auditService := service.NewAuditService()
+	taskHandler := handler.NewTaskHandler(taskService, namespaceService, auditService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	namespaceHandler := handler.NewNamespaceHandler(namespaceService)
+	libraryHandler := handler.NewLibraryHandler(libraryService, namespaceService)
+	apiTokenHandler := handler.NewAPITokenHandler(service.NewAPITokenService())
+	healthHandler := handler.NewHealthHandler(schedulerService)
+	loginGuard := auth.NewLoginGuard(cfg.Login)
+	userService := service.NewUserService()
+	userHandler := handler.NewUserHandler(userService, namespaceService, loginGuard)
+	twoFactorHandler := handler.NewTwoFactorHandler(service.NewTwoFactorService(), userService)
+	sessionHandler := handler.NewSessionHandler(service.NewSessionService(), userService)
+
+	// 启动执行记录清理服务
+	retentionService := service.NewRetentionService(cfg)
+	retentionService.Start()
+	retentionHandler := handler.NewRetentionHandler(retentionService, cfg)
+
+	// 启动 GitOps 清单同步（未启用时不做任何事）
+	gitopsService := service.NewGitOpsService(taskService, cfg)
+	gitopsService.Start()
+	gitopsHandler := handler.NewGitOpsHandler(gitopsService, namespaceService)
 
 	// 创建JWT中间件
-	jwtMiddleware, err := auth.NewJWTMiddleware(cfg)
+	jwtMiddleware, err := auth.NewJWTMiddleware(cfg, loginGuard)
 	if err != nil {
 		log.Fatal("Failed to initialize JWT middleware:", err)
 	}
@@ -73,8 +128,18 @@ func main() {
 		log.Fatal("Failed to init JWT middleware:", err)
 	}
 
+	// 单点登录（可选）
+	var oidcHandler *handler.OIDCHandler
+	if cfg.OIDC.Enabled {
+		oidcProvider, err := auth.NewOIDCProvider(cfg.OIDC, cfg.JWT.Secure)
+		if err != nil {
+			log.Fatal("Failed to initialize OIDC provider:", err)
+		}
+		oidcHandler = handler.NewOIDCHandler(oidcProvider, jwtMiddleware, userService, namespaceService)
+	}
+
 	// 设置路由
-	router := setupRouter(jwtMiddleware, taskHandler, cfg)
+	router := setupRouter(jwtMiddleware, taskHandler, healthHandler, retentionHandler, userHandler, namespaceHandler, apiTokenHandler, auditHandler, twoFactorHandler, sessionHandler, oidcHandler, gitopsHandler, libraryHandler, cfg)
 
 	// 创建HTTP服务器
 	srv := &http.Server{
@@ -100,6 +165,12 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// 停止执行记录清理
+	retentionService.Stop()
+
+	// 停止 GitOps 同步
+	gitopsService.Stop()
+
 	// 停止调度器
 	if err := schedulerService.Stop(); err != nil {
 		log.Printf("Error stopping scheduler: %v", err)
@@ -113,9 +184,14 @@ func main() {
 	log.Println("Server exited")
 }
 
-func setupRouter(jwtMiddleware *jwt.GinJWTMiddleware, taskHandler *handler.TaskHandler, cfg *config.Config) *gin.Engine {
+func setupRouter(jwtMiddleware *jwt.GinJWTMiddleware, taskHandler *handler.TaskHandler, healthHandler *handler.HealthHandler, retentionHandler *handler.RetentionHandler, userHandler *handler.UserHandler, namespaceHandler *handler.NamespaceHandler, apiTokenHandler *handler.APITokenHandler, auditHandler *handler.AuditHandler, twoFactorHandler *handler.TwoFactorHandler, sessionHandler *handler.SessionHandler, oidcHandler *handler.OIDCHandler, gitopsHandler *handler.GitOpsHandler, libraryHandler *handler.LibraryHandler, cfg *config.Config) *gin.Engine {
 	router := gin.Default()
 
+	// 只信任配置的反向代理发来的 X-Forwarded-For，否则客户端可以伪造登录限制和审计日志使用的 IP
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatal("Invalid trusted proxies:", err)
+	}
+
 	// 添加模板函数
 	router.SetFuncMap(template.FuncMap{
 		"toFloat64": func(i int64) float64 {
@@ -158,7 +234,10 @@ func setupRouter(jwtMiddleware *jwt.GinJWTMiddleware, taskHandler *handler.TaskH
 	})
 
 	router.LoadHTMLGlob("templates/*")
-	
+
+	// Cookie 认证的写请求需要带上 CSRF 令牌，API 令牌请求除外
+	router.Use(auth.CSRFMiddleware(cfg.JWT.Secure))
+
 	// 静态文件服务
 	router.Static("/static", "./static")
 
@@ -166,13 +245,29 @@ func setupRouter(jwtMiddleware *jwt.GinJWTMiddleware, taskHandler *handler.TaskH
 	router.GET("/", func(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/login")
 	})
-	
+
 	router.GET("/login", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "login.html", nil)
+		c.HTML(http.StatusOK, "login.html", gin.H{
+			"oidcEnabled": oidcHandler != nil,
+			"oidcName":    cfg.OIDC.DisplayName,
+		})
 	})
 
 	router.POST("/login", jwtMiddleware.LoginHandler)
 
+	// 单点登录：跳转到身份提供方，回调后签发与密码登录相同的 JWT Cookie
+	if oidcHandler != nil {
+		router.GET("/auth/oidc/login", oidcHandler.Login)
+		router.GET("/auth/oidc/callback", oidcHandler.Callback)
+	}
+
+	// 健康检查（无需认证，供容器探针使用）
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+
+	// API 同时接受 Bearer 令牌和 JWT Cookie
+	apiMiddleware := auth.APIMiddleware(jwtMiddleware)
+
 	// Protected routes (authentication required)
 	auth := router.Group("/")
 	auth.Use(jwtMiddleware.MiddlewareFunc())
@@ -180,29 +275,81 @@ func setupRouter(jwtMiddleware *jwt.GinJWTMiddleware, taskHandler *handler.TaskH
 		auth.GET("/dashboard", taskHandler.ShowDashboard)
 		// 添加其他需要保护的页面路由
 		auth.GET("/settings", taskHandler.ShowDashboard) // 临时使用dashboard处理器
-		auth.GET("/profile", taskHandler.ShowDashboard)   // 临时使用dashboard处理器
-		
+		auth.GET("/profile", taskHandler.ShowDashboard)  // 临时使用dashboard处理器
+
 		// 退出登录
 		auth.POST("/logout", jwtMiddleware.LogoutHandler)
-		auth.GET("/logout", func(c *gin.Context) {
-			// 清除cookie
-			c.SetCookie(cfg.JWT.CookieName, "", -1, "/", "", cfg.JWT.Secure, cfg.JWT.HTTPOnly)
-			c.Redirect(http.StatusFound, "/login")
-		})
+		auth.GET("/logout", jwtMiddleware.LogoutHandler)
 	}
 
+	// 所有 /api 路由都经过认证中间件，按 auth.RequiredRole 校验角色（令牌还需对应的权限范围）
 	api := router.Group("/api")
-	api.Use(jwtMiddleware.MiddlewareFunc())
+	api.Use(apiMiddleware)
 	{
 		api.GET("/tasks", taskHandler.GetTasks)
 		api.POST("/tasks", taskHandler.CreateTask)
+		api.GET("/tasks/export", taskHandler.ExportTasks)
+		api.GET("/tasks/stats", taskHandler.GetTasksStats)
+		api.POST("/tasks/import", taskHandler.ImportTasks)
+		api.POST("/tasks/import/crontab", taskHandler.ImportCrontab)
+		api.POST("/tasks/validate", taskHandler.ValidateScript)
 		api.GET("/tasks/:id", taskHandler.GetTask)
 		api.PUT("/tasks/:id", taskHandler.UpdateTask)
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
 		api.PATCH("/tasks/:id/toggle", taskHandler.ToggleTask)
+		api.POST("/tasks/:id/run", taskHandler.RunTask)
 		api.GET("/tasks/:id/executions", taskHandler.GetTaskExecutions)
+		api.GET("/tasks/:id/stats", taskHandler.GetTaskStats)
+		api.PUT("/tasks/:id/retention", taskHandler.UpdateTaskRetention)
+		api.PUT("/tasks/:id/namespace", taskHandler.MoveTask)
+		api.GET("/tasks/:id/audit", taskHandler.GetTaskAudit)
+		api.GET("/tasks/:id/revisions", taskHandler.GetTaskRevisions)
+		api.GET("/tasks/:id/revisions/diff", taskHandler.DiffTaskRevisions)
+		api.GET("/tasks/:id/revisions/:rev", taskHandler.GetTaskRevision)
+		api.POST("/tasks/:id/revisions/:rev/restore", taskHandler.RestoreTaskRevision)
+		api.GET("/tasks/:id/venv", taskHandler.GetTaskVenv)
+		api.POST("/tasks/:id/venv/rebuild", taskHandler.RebuildTaskVenv)
+		api.GET("/audit", auditHandler.GetAuditLogs)
 		api.GET("/executions/recent", taskHandler.GetRecentExecutions)
+		api.GET("/executions/export", taskHandler.ExportExecutions)
+		api.GET("/executions/search", taskHandler.SearchExecutions)
+		api.GET("/executions/:id", taskHandler.GetExecution)
 		api.POST("/change-password", taskHandler.ChangePassword)
+		api.GET("/me", userHandler.GetCurrentUser)
+		api.GET("/users", userHandler.GetUsers)
+		api.POST("/users", userHandler.CreateUser)
+		api.PUT("/users/:id", userHandler.UpdateUser)
+		api.DELETE("/users/:id", userHandler.DeleteUser)
+		api.POST("/users/:id/unlock", userHandler.UnlockUser)
+		api.DELETE("/users/:id/2fa", twoFactorHandler.ResetUser)
+		api.GET("/users/:id/sessions", sessionHandler.GetUserSessions)
+		api.DELETE("/users/:id/sessions", sessionHandler.RevokeUserSessions)
+		api.GET("/tokens", apiTokenHandler.GetTokens)
+		api.POST("/tokens", apiTokenHandler.CreateToken)
+		api.DELETE("/tokens/:id", apiTokenHandler.RevokeToken)
+		api.GET("/2fa", twoFactorHandler.GetStatus)
+		api.POST("/2fa/setup", twoFactorHandler.BeginSetup)
+		api.POST("/2fa/enable", twoFactorHandler.Enable)
+		api.POST("/2fa/disable", twoFactorHandler.Disable)
+		api.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+		api.GET("/sessions", sessionHandler.GetSessions)
+		api.POST("/sessions/revoke-others", sessionHandler.RevokeOtherSessions)
+		api.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+		api.GET("/namespaces", namespaceHandler.GetNamespaces)
+		api.POST("/namespaces", namespaceHandler.CreateNamespace)
+		api.DELETE("/namespaces/:id", namespaceHandler.DeleteNamespace)
+		api.POST("/namespaces/:id/members", namespaceHandler.AddMember)
+		api.DELETE("/namespaces/:id/members/:user_id", namespaceHandler.RemoveMember)
+		api.GET("/library", libraryHandler.GetModules)
+		api.POST("/library", libraryHandler.CreateModule)
+		api.GET("/library/:id", libraryHandler.GetModule)
+		api.PUT("/library/:id", libraryHandler.UpdateModule)
+		api.DELETE("/library/:id", libraryHandler.DeleteModule)
+		api.GET("/library/:id/versions/:version", libraryHandler.GetModuleVersion)
+		api.GET("/retention", retentionHandler.GetRetention)
+		api.POST("/retention/prune", retentionHandler.Prune)
+		api.GET("/gitops", gitopsHandler.GetStatus)
+		api.POST("/gitops/sync", gitopsHandler.Sync)
 	}
 
 	return router
@@ -227,6 +374,7 @@ func createDefaultUser(cfg *config.Config) error {
 		Username:            cfg.DefaultUser.Username,
 		PasswordHash:        string(hashedPassword),
 		ForcePasswordChange: true, // 默认用户首次登录必须修改密码
+		Role:                auth.RoleAdmin,
 	}
 
 	if err := database.GetDB().Create(user).Error; err != nil {
@@ -235,4 +383,4 @@ func createDefaultUser(cfg *config.Config) error {
 
 	log.Printf("Default user created: %s/%s", cfg.DefaultUser.Username, cfg.DefaultUser.Password)
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,265 @@
+// mock-oidc 本地开发和测试用的 OIDC 身份提供方：自动批准授权请求，按命令行参数返回固定的用户和用户组。
+// 只支持授权码 + PKCE(S256) 流程，切勿用于生产环境。
+//
+//	go run ./cmd/mock-oidc -user alice -groups b1cron-admins
+//
+// b1cron 的配置：issuer 为 http://localhost:9000，client_id 为 b1cron（与 -client-id 一致）。
+// 授权地址可以带 login_hint 参数临时替换用户名，便于测试多个用户。
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authRequest 已签发但尚未兑换的授权码
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	username      string
+	expiresAt     time.Time
+}
+
+type mockProvider struct {
+	issuer   string
+	clientID string
+	secret   string
+	groups   []string
+	username string
+	key      *rsa.PrivateKey
+	keyID    string
+
+	mu    sync.Mutex
+	codes map[string]*authRequest
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "监听地址")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer 地址，必须与 b1cron 配置中的 oidc.issuer 一致")
+	clientID := flag.String("client-id", "b1cron", "允许的 client_id")
+	clientSecret := flag.String("client-secret", "", "client_secret，为空时不校验")
+	username := flag.String("user", "alice", "登录的用户名（preferred_username）")
+	groups := flag.String("groups", "b1cron-admins", "用户组，逗号分隔")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal("Failed to generate signing key:", err)
+	}
+
+	p := &mockProvider{
+		issuer:   strings.TrimRight(*issuer, "/"),
+		clientID: *clientID,
+		secret:   *clientSecret,
+		username: *username,
+		key:      key,
+		keyID:    randomString(8),
+		codes:    make(map[string]*authRequest),
+	}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			p.groups = append(p.groups, group)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+
+	log.Printf("Mock OIDC provider listening on %s (issuer %s, user %s, groups %v)", *addr, p.issuer, p.username, p.groups)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func randomString(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func (p *mockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+	})
+}
+
+func (p *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": p.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize 自动批准授权请求，带着授权码跳转回客户端
+func (p *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if q.Get("client_id") != p.clientID || redirectURI == "" {
+		http.Error(w, "unknown client_id or missing redirect_uri", http.StatusBadRequest)
+		return
+	}
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := target.Query()
+	params.Set("state", q.Get("state"))
+	if q.Get("response_type") != "code" || q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		params.Set("error", "invalid_request")
+		params.Set("error_description", "authorization code flow with PKCE (S256) is required")
+	} else {
+		username := p.username
+		if hint := q.Get("login_hint"); hint != "" {
+			username = hint
+		}
+		code := randomString(24)
+		p.mu.Lock()
+		p.codes[code] = &authRequest{
+			clientID:      p.clientID,
+			redirectURI:   redirectURI,
+			codeChallenge: q.Get("code_challenge"),
+			nonce:         q.Get("nonce"),
+			username:      username,
+			expiresAt:     time.Now().Add(time.Minute),
+		}
+		p.mu.Unlock()
+		params.Set("code", code)
+	}
+
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token 兑换授权码：校验 PKCE verifier 后返回签名的 ID Token
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.clientID || (p.secret != "" && subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.secret)) != 1) {
+		tokenError(w, "invalid_client", "client authentication failed")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	req, ok := p.codes[code]
+	delete(p.codes, code) // 授权码只能使用一次
+	p.mu.Unlock()
+	if !ok || time.Now().After(req.expiresAt) {
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != req.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri mismatch")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.codeChallenge {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	now := time.Now()
+	idToken, err := p.sign(map[string]interface{}{
+		"iss":                p.issuer,
+		"sub":                "mock|" + req.username,
+		"aud":                req.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              req.nonce,
+		"preferred_username": req.username,
+		"email":              req.username + "@example.com",
+		"groups":             p.groups,
+	})
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(24),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// sign 生成 RS256 签名的 JWT
+func (p *mockProvider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": p.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign id_token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,248 @@
+package audit
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 审计动作
+const (
+	ActionTaskCreate         = "task.create"
+	ActionTaskUpdate         = "task.update"
+	ActionTaskDelete         = "task.delete"
+	ActionTaskToggle         = "task.toggle"
+	ActionTaskRun            = "task.run"
+	ActionTaskScriptTampered = "task.script_tampered"
+	ActionUserCreate         = "user.create"
+	ActionUserUpdate         = "user.update"
+	ActionUserDelete         = "user.delete"
+	ActionPasswordChange     = "user.password_change"
+	ActionUserLock           = "user.lock"
+	ActionUserUnlock         = "user.unlock"
+	ActionTOTPEnable         = "user.totp_enable"
+	ActionTOTPDisable        = "user.totp_disable"
+	ActionSessionRevoke      = "user.session_revoke"
+	ActionLibraryCreate      = "library.create"
+	ActionLibraryUpdate      = "library.update"
+	ActionLibraryDelete      = "library.delete"
+	ActionLoginSuccess       = "login.success"
+	ActionLoginFailure       = "login.failure"
+)
+
+// 登录失败原因，记录在 login.failure 审计日志中
+const (
+	LoginUnknownUser   = "unknown_user"
+	LoginBadPassword   = "bad_password"
+	LoginBadTOTP       = "bad_totp"
+	LoginAccountLocked = "account_locked"
+	LoginRateLimited   = "rate_limited"
+	LoginSSOError      = "sso_error"    // 单点登录回调校验失败
+	LoginSSONoRole     = "sso_no_role"  // 用户组没有映射到任何角色
+	LoginSSOConflict   = "sso_conflict" // 用户名已被其他账户使用
+)
+
+// 审计对象类型
+const (
+	TargetTask    = "task"
+	TargetUser    = "user"
+	TargetLibrary = "library"
+)
+
+// Snapshot 对象在某一时刻的字段值，用于计算变更前后的差异
+type Snapshot map[string]interface{}
+
+// FieldChange 单个字段的变更
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// SecretValues 值需要保密的字段（任务的环境变量），审计日志只记录变量名以及值是否变化
+type SecretValues map[string]string
+
+// RedactSecretChange 隐藏变更前后的值：值显示为 <redacted>，与变更前不同的值显示为 <redacted, changed>
+func RedactSecretChange(before, after map[string]string) FieldChange {
+	redactedBefore := make(map[string]string, len(before))
+	for key := range before {
+		redactedBefore[key] = "<redacted>"
+	}
+	redactedAfter := make(map[string]string, len(after))
+	for key, value := range after {
+		redactedAfter[key] = "<redacted>"
+		if previous, ok := before[key]; ok && previous != value {
+			redactedAfter[key] = "<redacted, changed>"
+		}
+	}
+	return FieldChange{Before: redactedBefore, After: redactedAfter}
+}
+
+// Entry 一条待记录的审计日志
+type Entry struct {
+	Action     string
+	Actor      *models.User // 为空时使用 ActorName
+	ActorName  string
+	IP         string
+	TargetType string
+	TargetID   uint
+	TargetName string
+	Before     Snapshot // 创建时为空
+	After      Snapshot // 删除时为空
+}
+
+// TaskSnapshot 任务字段和脚本内容的快照
+func TaskSnapshot(task *models.Task, script string) Snapshot {
+	var executeAt interface{}
+	if task.ExecuteAt != nil {
+		executeAt = task.ExecuteAt.Format(time.RFC3339)
+	}
+	return Snapshot{
+		"name":               task.Name,
+		"slug":               task.Slug,
+		"script":             script,
+		"script_type":        task.ScriptType,
+		"schedule_spec":      task.ScheduleSpec,
+		"schedule_type":      task.ScheduleType,
+		"execute_at":         executeAt,
+		"is_enabled":         task.IsEnabled,
+		"namespace_id":       task.NamespaceID,
+		"retention_days":     task.RetentionDays,
+		"retention_max_rows": task.RetentionMaxRows,
+		"env":                SecretValues(task.Env),
+		"requirements":       task.Requirements,
+		"libraries":          task.Libraries,
+		"manifest":           task.Manifest,
+	}
+}
+
+// UserSnapshot 用户账户字段的快照（不包含密码）
+func UserSnapshot(user *models.User, namespaceIDs []uint) Snapshot {
+	return Snapshot{
+		"username":      user.Username,
+		"role":          user.Role,
+		"namespace_ids": namespaceIDs,
+	}
+}
+
+// Diff 计算两个快照之间发生变化的字段
+func Diff(before, after Snapshot) map[string]FieldChange {
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	changes := make(map[string]FieldChange)
+	for _, key := range names {
+		b, a := before[key], after[key]
+		secretBefore, isSecretBefore := b.(SecretValues)
+		secretAfter, isSecretAfter := a.(SecretValues)
+		if isSecretBefore || isSecretAfter {
+			if !sameSecretValues(secretBefore, secretAfter) {
+				changes[key] = RedactSecretChange(secretBefore, secretAfter)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(b, a) {
+			changes[key] = FieldChange{Before: b, After: a}
+		}
+	}
+	return changes
+}
+
+// sameSecretValues 两组保密值是否相同，nil 与空集合视为相同
+func sameSecretValues(a, b SecretValues) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RedactStoredSecrets 隐藏旧版本写入审计日志的环境变量值，启动时执行，已隐藏的记录不受影响
+func RedactStoredSecrets() error {
+	var logs []models.AuditLog
+	if err := database.GetDB().Select("id", "changes").
+		Where("target_type = ? AND changes LIKE ?", TargetTask, `%"env":%`).
+		Find(&logs).Error; err != nil {
+		return fmt.Errorf("failed to get audit logs: %w", err)
+	}
+
+	redacted := 0
+	for _, entry := range logs {
+		var changes map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+			continue
+		}
+		var env struct {
+			Before map[string]string `json:"before"`
+			After  map[string]string `json:"after"`
+		}
+		if err := json.Unmarshal(changes["env"], &env); err != nil {
+			continue
+		}
+		change := RedactSecretChange(env.Before, env.After)
+		data, err := json.Marshal(change)
+		if err != nil || string(data) == string(changes["env"]) {
+			continue
+		}
+		changes["env"] = data
+		if data, err = json.Marshal(changes); err != nil {
+			continue
+		}
+		if err := database.GetDB().Model(&models.AuditLog{}).Where("id = ?", entry.ID).UpdateColumn("changes", string(data)).Error; err != nil {
+			return fmt.Errorf("failed to redact audit log %d: %w", entry.ID, err)
+		}
+		redacted++
+	}
+	if redacted > 0 {
+		log.Printf("Redacted environment variable values in %d audit log entries", redacted)
+	}
+	return nil
+}
+
+// Record 写入审计日志；审计失败只记录日志，不影响业务操作
+func Record(entry Entry) {
+	record := models.AuditLog{
+		Action:     entry.Action,
+		ActorName:  entry.ActorName,
+		IP:         entry.IP,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		TargetName: entry.TargetName,
+	}
+	if entry.Actor != nil {
+		record.ActorID = &entry.Actor.ID
+		record.ActorName = entry.Actor.Username
+	}
+
+	if entry.Before != nil || entry.After != nil {
+		data, err := json.Marshal(Diff(entry.Before, entry.After))
+		if err != nil {
+			log.Printf("Failed to encode audit changes: %v", err)
+		} else {
+			record.Changes = string(data)
+		}
+	}
+
+	if err := database.GetDB().Create(&record).Error; err != nil {
+		log.Printf("Failed to record audit log %s: %v", entry.Action, err)
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenPrefix API 令牌的固定前缀，便于识别和泄露扫描
+const APITokenPrefix = "b1c_"
+
+// APITokenKey 通过 API 令牌认证时令牌在 gin.Context 中的键
+const APITokenKey = "api_token"
+
+// API 令牌权限范围，分别对应 viewer、operator、admin 角色可执行的操作，高一级的范围包含低一级的
+const (
+	ScopeRead  = "read"
+	ScopeRun   = "run"
+	ScopeWrite = "write"
+)
+
+var roleScopes = map[string]string{
+	RoleViewer:   ScopeRead,
+	RoleOperator: ScopeRun,
+	RoleAdmin:    ScopeWrite,
+}
+
+// ValidScope 是否为有效的令牌权限范围
+func ValidScope(scope string) bool {
+	for _, s := range roleScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIToken 计算令牌哈希，数据库只保存哈希值
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenHasScope 令牌是否包含访问路由所需的权限范围；权限范围逐级包含（write ⊇ run ⊇ read），
+// 按令牌中最高的权限范围对应的角色判断
+func tokenHasScope(token *models.APIToken, requiredRole string) bool {
+	for _, scope := range strings.Split(token.Scopes, ",") {
+		for role, roleScope := range roleScopes {
+			if strings.TrimSpace(scope) == roleScope && HasRole(role, requiredRole) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sessionOnlyRoutes 只允许通过登录会话访问的路由，避免令牌创建新令牌、修改密码、两步验证或管理会话
+var sessionOnlyRoutes = map[string]bool{
+	"/api/tokens":                 true,
+	"/api/tokens/:id":             true,
+	"/api/change-password":        true,
+	"/api/2fa":                    true,
+	"/api/2fa/setup":              true,
+	"/api/2fa/enable":             true,
+	"/api/2fa/disable":            true,
+	"/api/2fa/recovery-codes":     true,
+	"/api/sessions":               true,
+	"/api/sessions/:id":           true,
+	"/api/sessions/revoke-others": true,
+}
+
+// authenticateAPIToken 校验令牌并返回令牌及其所属用户
+func authenticateAPIToken(raw string) (*models.APIToken, *models.User, error) {
+	var token models.APIToken
+	if err := database.GetDB().Where("token_hash = ?", HashAPIToken(raw)).First(&token).Error; err != nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	if token.RevokedAt != nil {
+		return nil, nil, fmt.Errorf("token revoked")
+	}
+	if token.ExpiresAt != nil && token.ExpiresAt.Before(time.Now()) {
+		return nil, nil, fmt.Errorf("token expired")
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, token.UserID).Error; err != nil {
+		return nil, nil, fmt.Errorf("invalid token")
+	}
+	return &token, &user, nil
+}
+
+// CurrentAPIToken 获取当前请求使用的 API 令牌，会话认证时返回 nil
+func CurrentAPIToken(c *gin.Context) *models.APIToken {
+	if value, exists := c.Get(APITokenKey); exists {
+		if token, ok := value.(*models.APIToken); ok {
+			return token
+		}
+	}
+	return nil
+}
+
+// APIMiddleware 接受 Authorization: Bearer 中的 API 令牌，没有令牌时回退到 JWT Cookie 认证
+func APIMiddleware(jwtMiddleware *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	jwtHandler := jwtMiddleware.MiddlewareFunc()
+
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		raw := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		if header == "" || raw == header || !strings.HasPrefix(raw, APITokenPrefix) {
+			jwtHandler(c)
+			return
+		}
+
+		token, user, err := authenticateAPIToken(raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		required := RequiredRole(c.Request.Method, c.FullPath())
+		if sessionOnlyRoutes[c.FullPath()] || !HasRole(user.Role, required) || !tokenHasScope(token, required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "token does not have permission to access this resource",
+			})
+			return
+		}
+
+		now := time.Now()
+		if err := database.GetDB().Model(token).UpdateColumn("last_used_at", now).Error; err != nil {
+			fmt.Printf("Warning: failed to update token last used time: %v\n", err)
+		}
+		token.LastUsedAt = &now
+
+		c.Set(CurrentUserKey, user)
+		c.Set(APITokenKey, token)
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRF 令牌采用双重提交：令牌保存在前端脚本可读的 Cookie 中，写操作需要在请求头中带上相同的值。
+// 其他站点无法读取该 Cookie，也就无法伪造请求头
+const (
+	CSRFCookieName = "b1cron_csrf"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// csrfTokenBytes CSRF 令牌的随机字节数
+const csrfTokenBytes = 32
+
+// csrfSafeMethods 不修改数据的请求，无需校验 CSRF 令牌
+var csrfSafeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+}
+
+// usesAPIToken 请求是否通过 API 令牌认证；令牌由客户端主动放在请求头中，不受 CSRF 影响，
+// 且 APIMiddleware 对这类请求不会回退到 Cookie 认证
+func usesAPIToken(c *gin.Context) bool {
+	header := c.GetHeader("Authorization")
+	return strings.HasPrefix(header, "Bearer ") && strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")), APITokenPrefix)
+}
+
+// CSRFMiddleware 为浏览器下发 CSRF 令牌 Cookie，并校验 Cookie 认证的写请求
+func CSRFMiddleware(secureCookie bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(CSRFCookieName)
+		if err != nil || token == "" {
+			buf := make([]byte, csrfTokenBytes)
+			if _, err := rand.Read(buf); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to generate CSRF token"})
+				return
+			}
+			token = ""
+			c.SetSameSite(http.SameSiteStrictMode)
+			// 前端脚本需要读取令牌，不能设置 HttpOnly
+			c.SetCookie(CSRFCookieName, base64.RawURLEncoding.EncodeToString(buf), 0, "/", "", secureCookie, false)
+		}
+
+		if csrfSafeMethods[c.Request.Method] || usesAPIToken(c) {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader(CSRFHeaderName)
+		if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "CSRF token missing or invalid, please reload the page"})
+			return
+		}
+		c.Next()
+	}
+}
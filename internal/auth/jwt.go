@@ -1,10 +1,15 @@
 package auth
 
 import (
+	"b1cron/internal/audit"
 	"b1cron/internal/config"
 	"b1cron/internal/database"
 	"b1cron/internal/models"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,60 +21,203 @@ import (
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"` // 开启两步验证的用户需要提交动态码或恢复码
 }
 
-func NewJWTMiddleware(cfg *config.Config) (*jwt.GinJWTMiddleware, error) {
+// recordLogin 记录登录审计日志；登录失败时操作者未经验证，只记录尝试的用户名和失败原因
+func recordLogin(c *gin.Context, action string, user *models.User, username, reason string) {
+	entry := audit.Entry{
+		Action:     action,
+		ActorName:  username,
+		IP:         c.ClientIP(),
+		TargetType: audit.TargetUser,
+		TargetName: username,
+	}
+	if user != nil {
+		entry.TargetID = user.ID
+		if action == audit.ActionLoginSuccess {
+			entry.Actor = user
+		}
+	}
+	if reason != "" {
+		entry.After = audit.Snapshot{"reason": reason}
+	}
+	audit.Record(entry)
+}
+
+// rejectLogin 拒绝登录请求，Unauthorized 根据 context 中的信息返回状态码和 Retry-After
+func rejectLogin(c *gin.Context, reject loginReject) {
+	c.Set(loginRejectKey, reject)
+}
+
+// loginFailed 记录一次密码或动态码错误：计入IP和用户名的退避，累加账户失败次数，必要时锁定账户
+func loginFailed(c *gin.Context, guard *LoginGuard, user *models.User, username, reason string) {
+	ip := c.ClientIP()
+	if guard.Fail(ip, username) {
+		log.Printf("Login from %s blocked after too many failed attempts", ip)
+	}
+	recordLogin(c, audit.ActionLoginFailure, user, username, reason)
+	if user == nil {
+		return
+	}
+
+	locked, err := guard.registerUserFailure(user)
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	if locked {
+		audit.Record(audit.Entry{
+			Action:     audit.ActionUserLock,
+			ActorName:  "system",
+			IP:         ip,
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			TargetName: user.Username,
+		})
+	}
+}
+
+func NewJWTMiddleware(cfg *config.Config, guard *LoginGuard) (*jwt.GinJWTMiddleware, error) {
+	timeout := time.Hour * time.Duration(cfg.JWT.ExpireHours)
+
 	return jwt.New(&jwt.GinJWTMiddleware{
 		Realm:       "B1Cron",
 		Key:         []byte(cfg.JWT.Secret),
-		Timeout:     time.Hour * time.Duration(cfg.JWT.ExpireHours),
+		Timeout:     timeout,
 		MaxRefresh:  time.Hour * time.Duration(cfg.JWT.RefreshExpireHours),
 		IdentityKey: "username",
-		
+
 		PayloadFunc: func(data interface{}) jwt.MapClaims {
-			if v, ok := data.(*models.User); ok {
+			if v, ok := data.(*sessionUser); ok {
 				return jwt.MapClaims{
-					"username":              v.Username,
-					"user_id":               v.ID,
-					"force_password_change": v.ForcePasswordChange,
+					"username":              v.user.Username,
+					"user_id":               v.user.ID,
+					"force_password_change": v.user.ForcePasswordChange,
+					"role":                  v.user.Role,
+					sessionIDClaim:          v.session.ID,
 				}
 			}
 			return jwt.MapClaims{}
 		},
-		
+
 		IdentityHandler: func(c *gin.Context) interface{} {
 			claims := jwt.ExtractClaims(c)
 			return &models.User{
 				Username: claims["username"].(string),
 			}
 		},
-		
+
 		Authenticator: func(c *gin.Context) (interface{}, error) {
 			var loginReq LoginRequest
 			if err := c.ShouldBindJSON(&loginReq); err != nil {
 				return "", jwt.ErrMissingLoginValues
 			}
 
+			ip := c.ClientIP()
+			if wait := guard.Check(ip, loginReq.Username); wait > 0 {
+				recordLogin(c, audit.ActionLoginFailure, nil, loginReq.Username, audit.LoginRateLimited)
+				rejectLogin(c, loginReject{code: http.StatusTooManyRequests, retryAfter: wait})
+				return nil, ErrTooManyAttempts
+			}
+
 			var user models.User
 			if err := database.GetDB().Where("username = ?", loginReq.Username).First(&user).Error; err != nil {
+				loginFailed(c, guard, nil, loginReq.Username, audit.LoginUnknownUser)
 				return nil, jwt.ErrFailedAuthentication
 			}
 
+			if IsLocked(&user) {
+				recordLogin(c, audit.ActionLoginFailure, &user, loginReq.Username, audit.LoginAccountLocked)
+				rejectLogin(c, loginReject{code: http.StatusForbidden, retryAfter: time.Until(*user.LockedUntil)})
+				return nil, ErrAccountLocked
+			}
+
 			if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginReq.Password)); err != nil {
+				loginFailed(c, guard, &user, loginReq.Username, audit.LoginBadPassword)
+				return nil, jwt.ErrFailedAuthentication
+			}
+
+			// 两步验证：密码正确后还需要动态码或恢复码，通过后才签发 JWT
+			if user.TOTPEnabled {
+				if loginReq.TOTPCode == "" {
+					rejectLogin(c, loginReject{code: http.StatusUnauthorized, totpRequired: true})
+					return nil, ErrTOTPRequired
+				}
+				ok, err := VerifySecondFactor(&user, loginReq.TOTPCode)
+				if err != nil {
+					log.Printf("Failed to verify second factor for %s: %v", user.Username, err)
+				}
+				if !ok {
+					loginFailed(c, guard, &user, loginReq.Username, audit.LoginBadTOTP)
+					rejectLogin(c, loginReject{code: http.StatusUnauthorized, totpRequired: true})
+					return nil, ErrInvalidTOTP
+				}
+			}
+
+			guard.Succeed(ip, loginReq.Username)
+			if err := guard.clearUserFailures(&user); err != nil {
+				log.Printf("%v", err)
+			}
+			// 每次登录创建一个会话，JWT 中记录会话ID，会话删除后 JWT 立即失效
+			identity, err := createSession(c, &user, SessionMethodPassword, timeout)
+			if err != nil {
+				log.Printf("%v", err)
 				return nil, jwt.ErrFailedAuthentication
 			}
+			recordLogin(c, audit.ActionLoginSuccess, &user, loginReq.Username, "")
 
 			// 将用户数据存储到context中，供LoginResponse使用
 			c.Set("user_data", &user)
-			
-			return &user, nil
+
+			return identity, nil
 		},
-		
+
 		Authorizator: func(data interface{}, c *gin.Context) bool {
-			return true
+			identity, ok := data.(*models.User)
+			if !ok {
+				return false
+			}
+
+			// 每次请求从数据库读取角色，角色变更或用户删除后立即生效
+			var user models.User
+			if err := database.GetDB().Where("username = ?", identity.Username).First(&user).Error; err != nil {
+				return false
+			}
+			// 退出登录、修改密码或被管理员强制下线后会话被删除
+			if !validateSession(c, &user) {
+				c.Set(sessionRevokedKey, true)
+				return false
+			}
+			c.Set(CurrentUserKey, &user)
+
+			return HasRole(user.Role, RequiredRole(c.Request.Method, c.FullPath()))
 		},
-		
+
 		Unauthorized: func(c *gin.Context, code int, message string) {
+			// 登录被限流、账户被锁定或需要两步验证时返回对应的状态码和提示
+			if value, exists := c.Get(loginRejectKey); exists {
+				reject := value.(loginReject)
+				response := gin.H{
+					"code":    reject.code,
+					"message": message,
+				}
+				if reject.retryAfter > 0 {
+					retryAfter := int(math.Ceil(reject.retryAfter.Seconds()))
+					c.Header("Retry-After", strconv.Itoa(retryAfter))
+					response["retry_after"] = retryAfter
+				}
+				if reject.totpRequired {
+					response["totp_required"] = true
+				}
+				c.JSON(reject.code, response)
+				return
+			}
+
+			if _, revoked := c.Get(sessionRevokedKey); revoked {
+				code = http.StatusUnauthorized
+				message = ErrSessionRevoked.Error()
+			}
+
 			// 如果是API请求，返回JSON错误
 			if strings.HasPrefix(c.Request.URL.Path, "/api/") {
 				c.JSON(code, gin.H{
@@ -81,19 +229,19 @@ func NewJWTMiddleware(cfg *config.Config) (*jwt.GinJWTMiddleware, error) {
 				c.Redirect(http.StatusFound, "/login")
 			}
 		},
-		
-		TokenLookup: "cookie:" + cfg.JWT.CookieName,
+
+		TokenLookup:   "cookie:" + cfg.JWT.CookieName,
 		TokenHeadName: "Bearer",
-		
+
 		TimeFunc: time.Now,
-		
+
 		SendCookie:     true,
 		SecureCookie:   cfg.JWT.Secure,
 		CookieHTTPOnly: cfg.JWT.HTTPOnly,
 		CookieDomain:   "",
 		CookieName:     cfg.JWT.CookieName,
 		CookieSameSite: http.SameSiteDefaultMode,
-		
+
 		LoginResponse: func(c *gin.Context, code int, token string, expire time.Time) {
 			// 获取用户信息以检查是否需要强制修改密码
 			user, exists := c.Get("user_data")
@@ -102,18 +250,25 @@ func NewJWTMiddleware(cfg *config.Config) (*jwt.GinJWTMiddleware, error) {
 				"token":  token,
 				"expire": expire.Format(time.RFC3339),
 			}
-			
+
 			if exists && user != nil {
 				if u, ok := user.(*models.User); ok && u.ForcePasswordChange {
 					response["force_password_change"] = true
 					response["message"] = "首次登录，请修改密码"
 				}
 			}
-			
+
 			c.JSON(http.StatusOK, response)
 		},
-		
+
 		LogoutResponse: func(c *gin.Context, code int) {
+			// 删除当前会话，已签发的 JWT 随之失效
+			if session := CurrentSession(c); session != nil {
+				if err := database.GetDB().Delete(session).Error; err != nil {
+					log.Printf("Failed to delete session on logout: %v", err)
+				}
+			}
+
 			// 检查是否是API请求
 			if strings.HasPrefix(c.Request.URL.Path, "/api/") {
 				c.JSON(http.StatusOK, gin.H{
@@ -126,4 +281,23 @@ func NewJWTMiddleware(cfg *config.Config) (*jwt.GinJWTMiddleware, error) {
 			}
 		},
 	})
-}
\ No newline at end of file
+}
+
+// IssueSessionCookie 创建会话并签发与密码登录相同的 JWT Cookie，供单点登录等不经过 LoginHandler 的登录方式使用
+func IssueSessionCookie(mw *jwt.GinJWTMiddleware, c *gin.Context, user *models.User, method string) error {
+	identity, err := createSession(c, user, method, mw.Timeout)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := mw.TokenGenerator(identity)
+	if err != nil {
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if mw.CookieSameSite != 0 {
+		c.SetSameSite(mw.CookieSameSite)
+	}
+	c.SetCookie(mw.CookieName, token, int(mw.CookieMaxAge.Seconds()), "/", mw.CookieDomain, mw.SecureCookie, mw.CookieHTTPOnly)
+	return nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed login attempts, please try again later")
+	ErrAccountLocked   = errors.New("account is temporarily locked, please try again later or contact an admin")
+	ErrTOTPRequired    = errors.New("two-factor authentication code required")
+	ErrInvalidTOTP     = errors.New("invalid two-factor authentication code")
+)
+
+// loginRejectKey 登录被拒绝时保存在 context 中的状态码、重试时间等信息，供 Unauthorized 使用
+const loginRejectKey = "login_reject"
+
+type loginReject struct {
+	code         int
+	retryAfter   time.Duration
+	totpRequired bool // 密码正确，需要提交动态码或恢复码
+}
+
+// loginAttempt 某个IP或用户名最近的失败记录
+type loginAttempt struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// LoginGuard 登录失败跟踪：按IP和用户名做指数退避，IP 失败过多时暂时封禁；
+// 用户名连续失败达到上限后在数据库中锁定账户，管理员可以解锁
+type LoginGuard struct {
+	mu       sync.Mutex
+	attempts map[string]*loginAttempt
+
+	maxFailures   int
+	lockout       time.Duration
+	ipMaxFailures int
+	window        time.Duration
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+}
+
+func NewLoginGuard(cfg config.LoginConfig) *LoginGuard {
+	return &LoginGuard{
+		attempts:      make(map[string]*loginAttempt),
+		maxFailures:   cfg.MaxFailures,
+		lockout:       parseLoginDuration(cfg.LockoutDuration),
+		ipMaxFailures: cfg.IPMaxFailures,
+		window:        parseLoginDuration(cfg.FailureWindow),
+		baseDelay:     parseLoginDuration(cfg.BaseDelay),
+		maxDelay:      parseLoginDuration(cfg.MaxDelay),
+	}
+}
+
+// parseLoginDuration 配置已在加载时校验，这里忽略解析错误
+func parseLoginDuration(value string) time.Duration {
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// delay 第 n 次失败后需要等待的时间：baseDelay * 2^(n-1)，不超过 maxDelay
+func (g *LoginGuard) delay(failures int) time.Duration {
+	d := g.baseDelay
+	for i := 1; i < failures && d < g.maxDelay; i++ {
+		d *= 2
+	}
+	if d > g.maxDelay {
+		d = g.maxDelay
+	}
+	return d
+}
+
+// Check 返回该IP和用户名还需要等待多久才能再次尝试登录
+func (g *LoginGuard) Check(ip, username string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{ipKey(ip), usernameKey(username)} {
+		if attempt, ok := g.attempts[key]; ok && attempt.blockedUntil.After(now) {
+			if d := attempt.blockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// Fail 记录一次失败的登录，返回该IP是否因失败过多被封禁
+func (g *LoginGuard) Fail(ip, username string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.prune(now)
+
+	ipBlocked := false
+	for _, key := range []string{ipKey(ip), usernameKey(username)} {
+		attempt, ok := g.attempts[key]
+		if !ok || now.Sub(attempt.lastFailure) > g.window {
+			attempt = &loginAttempt{}
+			g.attempts[key] = attempt
+		}
+		attempt.failures++
+		attempt.lastFailure = now
+		attempt.blockedUntil = now.Add(g.delay(attempt.failures))
+
+		if key == ipKey(ip) && g.ipMaxFailures > 0 && attempt.failures >= g.ipMaxFailures {
+			attempt.blockedUntil = now.Add(g.lockout)
+			ipBlocked = true
+		}
+	}
+	return ipBlocked
+}
+
+// Succeed 登录成功后清除该IP和用户名的失败记录
+func (g *LoginGuard) Succeed(ip, username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.attempts, ipKey(ip))
+	delete(g.attempts, usernameKey(username))
+}
+
+// Reset 清除用户名的失败记录，管理员解锁账户时使用
+func (g *LoginGuard) Reset(username string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.attempts, usernameKey(username))
+}
+
+// prune 清理已过统计窗口且不再封禁的记录，避免内存无限增长
+func (g *LoginGuard) prune(now time.Time) {
+	for key, attempt := range g.attempts {
+		if now.Sub(attempt.lastFailure) > g.window && !attempt.blockedUntil.After(now) {
+			delete(g.attempts, key)
+		}
+	}
+}
+
+// IsLocked 账户是否处于锁定状态
+func IsLocked(user *models.User) bool {
+	return user.LockedUntil != nil && user.LockedUntil.After(time.Now())
+}
+
+// registerUserFailure 累加账户的连续失败次数，达到上限时锁定账户，返回是否刚被锁定
+func (g *LoginGuard) registerUserFailure(user *models.User) (bool, error) {
+	updates := map[string]interface{}{
+		"failed_logins": user.FailedLogins + 1,
+	}
+	locked := g.maxFailures > 0 && user.FailedLogins+1 >= g.maxFailures
+	if locked {
+		lockedUntil := time.Now().Add(g.lockout)
+		updates["locked_until"] = &lockedUntil
+		// 锁定后重新计数，解锁后再次连续失败才会再锁定
+		updates["failed_logins"] = 0
+	}
+
+	if err := database.GetDB().Model(user).UpdateColumns(updates).Error; err != nil {
+		return false, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return locked, nil
+}
+
+// clearUserFailures 登录成功后清除账户的失败次数
+func (g *LoginGuard) clearUserFailures(user *models.User) error {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	updates := map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}
+	if err := database.GetDB().Model(user).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"b1cron/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateCookie 保存授权请求的 state、nonce 和 PKCE verifier，回调时校验后删除
+const (
+	oidcStateCookie = "b1cron_oidc"
+	oidcStateMaxAge = 10 * 60 // 秒
+	oidcCookiePath  = "/auth/oidc"
+)
+
+// 用户的登录方式，保存在 User.AuthProvider
+const (
+	ProviderLocal = "local"
+	ProviderOIDC  = "oidc"
+)
+
+var ErrOIDCNoRole = errors.New("no role is mapped to the user's groups")
+
+// OIDCIdentity 从 ID Token 中解析出的用户身份
+type OIDCIdentity struct {
+	Subject  string
+	Username string
+	Groups   []string
+	Role     string // 按用户组映射出的角色
+}
+
+// oidcState 授权请求的一次性参数
+type oidcState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+}
+
+// OIDCProvider OIDC 授权码 + PKCE 登录流程；身份提供方的配置在首次使用时发现，失败后下次请求重试
+type OIDCProvider struct {
+	cfg          config.OIDCConfig
+	secureCookie bool
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(cfg config.OIDCConfig, secureCookie bool) (*OIDCProvider, error) {
+	for group, role := range cfg.RoleMapping {
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q for oidc group %q", role, group)
+		}
+	}
+	if cfg.DefaultRole != "" && !ValidRole(cfg.DefaultRole) {
+		return nil, fmt.Errorf("invalid oidc default role: %s", cfg.DefaultRole)
+	}
+
+	return &OIDCProvider{
+		cfg:          cfg,
+		secureCookie: secureCookie,
+	}, nil
+}
+
+// DisplayName 登录页按钮上显示的名称
+func (p *OIDCProvider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// discover 获取身份提供方的端点和签名密钥
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// 使用独立的 context，避免请求结束后密钥刷新失败
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), &http.Client{Timeout: 10 * time.Second}), p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址，并把一次性参数写入 Cookie
+func (p *OIDCProvider) AuthCodeURL(c *gin.Context) (string, error) {
+	oauth2Config, _, err := p.discover(c.Request.Context())
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	params := oidcState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode oidc state: %w", err)
+	}
+	// 回调是从身份提供方跳转回来的顶级导航，需要 Lax 才能带上 Cookie
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, base64.RawURLEncoding.EncodeToString(data), oidcStateMaxAge, oidcCookiePath, "", p.secureCookie, true)
+
+	return oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(params.Verifier)), nil
+}
+
+// Exchange 处理回调：校验 state，用授权码和 PKCE verifier 换取 ID Token，校验签名和 nonce 后返回用户身份
+func (p *OIDCProvider) Exchange(c *gin.Context) (*OIDCIdentity, error) {
+	params, err := p.readState(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		return nil, fmt.Errorf("identity provider returned error: %s %s", errCode, c.Query("error_description"))
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(params.State)) != 1 {
+		return nil, fmt.Errorf("oidc state mismatch")
+	}
+
+	oauth2Config, verifier, err := p.discover(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	token, err := oauth2Config.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(params.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(params.Nonce)) != 1 {
+		return nil, fmt.Errorf("oidc nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id_token claims: %w", err)
+	}
+
+	identity := &OIDCIdentity{
+		Subject: idToken.Subject,
+		Groups:  claimStrings(claims[p.cfg.GroupsClaim]),
+	}
+	identity.Username, _ = claims[p.cfg.UsernameClaim].(string)
+	if identity.Username == "" {
+		return nil, fmt.Errorf("id_token has no %s claim", p.cfg.UsernameClaim)
+	}
+
+	identity.Role = p.mapRole(identity.Groups)
+	if identity.Role == "" {
+		return identity, ErrOIDCNoRole
+	}
+	return identity, nil
+}
+
+// readState 读取并删除授权请求时写入的 Cookie
+func (p *OIDCProvider) readState(c *gin.Context) (*oidcState, error) {
+	value, err := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath, "", p.secureCookie, true)
+	if err != nil {
+		return nil, fmt.Errorf("oidc login session expired, please try again")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oidc state cookie")
+	}
+	var params oidcState
+	if err := json.Unmarshal(data, &params); err != nil || params.State == "" {
+		return nil, fmt.Errorf("invalid oidc state cookie")
+	}
+	return &params, nil
+}
+
+// mapRole 按用户组映射角色，多个组匹配时取最高角色，没有匹配时使用默认角色
+func (p *OIDCProvider) mapRole(groups []string) string {
+	role := ""
+	for _, group := range groups {
+		if mapped, ok := p.cfg.RoleMapping[group]; ok && roleLevels[mapped] > roleLevels[role] {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = p.cfg.DefaultRole
+	}
+	return role
+}
+
+// claimStrings 用户组 claim 可能是字符串数组或单个字符串
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"b1cron/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用户角色
+const (
+	RoleAdmin    = "admin"    // 管理任务和用户
+	RoleOperator = "operator" // 查看、立即执行和启用/禁用任务
+	RoleViewer   = "viewer"   // 只读
+)
+
+// CurrentUserKey 通过认证的用户在 gin.Context 中的键
+const CurrentUserKey = "current_user"
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// routeRoles 需要特殊权限的路由（method + 路由模板），未列出的路由：
+// GET 请求 viewer 即可访问，其余写操作需要 admin
+var routeRoles = map[string]string{
+	"POST /logout":                     RoleViewer,
+	"POST /api/change-password":        RoleViewer,
+	"PATCH /api/tasks/:id/toggle":      RoleOperator,
+	"POST /api/tasks/:id/run":          RoleOperator,
+	"POST /api/tasks/:id/venv/rebuild": RoleOperator,
+	"GET /api/users":                   RoleAdmin,
+	"GET /api/audit":                   RoleAdmin,
+	"GET /api/users/:id/sessions":      RoleAdmin,
+	"DELETE /api/sessions/:id":         RoleViewer,
+	"POST /api/sessions/revoke-others": RoleViewer,
+	"POST /api/tokens":                 RoleViewer,
+	"DELETE /api/tokens/:id":           RoleViewer,
+	"POST /api/2fa/setup":              RoleViewer,
+	"POST /api/2fa/enable":             RoleViewer,
+	"POST /api/2fa/disable":            RoleViewer,
+	"POST /api/2fa/recovery-codes":     RoleViewer,
+}
+
+// ValidRole 是否为有效角色
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// HasRole 用户角色是否满足最低角色要求
+func HasRole(role, required string) bool {
+	return roleLevels[role] >= roleLevels[required]
+}
+
+// RequiredRole 获取访问路由所需的最低角色
+func RequiredRole(method, fullPath string) string {
+	if role, ok := routeRoles[method+" "+fullPath]; ok {
+		return role
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return RoleViewer
+	}
+	return RoleAdmin
+}
+
+// CurrentUser 获取当前请求的已认证用户
+func CurrentUser(c *gin.Context) *models.User {
+	if value, exists := c.Get(CurrentUserKey); exists {
+		if user, ok := value.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
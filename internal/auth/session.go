@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 会话的登录方式
+const (
+	SessionMethodPassword = "password"
+	SessionMethodOIDC     = "oidc"
+)
+
+// CurrentSessionKey 当前请求的登录会话在 gin.Context 中的键
+const CurrentSessionKey = "current_session"
+
+// sessionRevokedKey JWT 有效但会话已失效，Unauthorized 据此要求重新登录
+const sessionRevokedKey = "session_revoked"
+
+// sessionIDClaim JWT 中的会话ID
+const sessionIDClaim = "sid"
+
+// sessionTouchInterval 最后活动时间的更新间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// maxUserAgentLength 保存的 User-Agent 最大长度
+const maxUserAgentLength = 255
+
+var ErrSessionRevoked = errors.New("session has been revoked, please log in again")
+
+// sessionUser 登录成功的用户及新建的会话，由 PayloadFunc 写入 JWT
+type sessionUser struct {
+	user    *models.User
+	session *models.Session
+}
+
+// createSession 登录成功后创建会话，并顺带清理已过期的会话
+func createSession(c *gin.Context, user *models.User, method string, ttl time.Duration) (*sessionUser, error) {
+	now := time.Now()
+	if err := database.GetDB().Where("expires_at < ?", now).Delete(&models.Session{}).Error; err != nil {
+		log.Printf("Failed to delete expired sessions: %v", err)
+	}
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &models.Session{
+		ID:         uuid.NewString(),
+		UserID:     user.ID,
+		AuthMethod: method,
+		IP:         c.ClientIP(),
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := database.GetDB().Create(session).Error; err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &sessionUser{user: user, session: session}, nil
+}
+
+// validateSession 校验 JWT 中的会话仍然有效且属于该用户，并更新最后活动时间
+func validateSession(c *gin.Context, user *models.User) bool {
+	sessionID, _ := jwt.ExtractClaims(c)[sessionIDClaim].(string)
+	if sessionID == "" {
+		return false
+	}
+
+	var session models.Session
+	if err := database.GetDB().Where("id = ? AND user_id = ?", sessionID, user.ID).First(&session).Error; err != nil {
+		return false
+	}
+	now := time.Now()
+	if session.ExpiresAt.Before(now) {
+		return false
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := database.GetDB().Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+			log.Printf("Failed to update session last seen time: %v", err)
+		}
+		session.LastSeenAt = now
+	}
+	c.Set(CurrentSessionKey, &session)
+	return true
+}
+
+// CurrentSession 获取当前请求的登录会话，通过 API 令牌认证时返回 nil
+func CurrentSession(c *gin.Context) *models.Session {
+	if value, exists := c.Get(CurrentSessionKey); exists {
+		if session, ok := value.(*models.Session); ok {
+			return session
+		}
+	}
+	return nil
+}
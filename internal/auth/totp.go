@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// TOTPIssuer 认证器应用中显示的发行方名称
+const TOTPIssuer = "B1Cron"
+
+const (
+	totpPeriod = 30 // 秒
+	totpSkew   = 1  // 允许前后各一个时间步的时钟误差
+)
+
+var totpOpts = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// GenerateTOTPKey 为用户生成新的 TOTP 密钥
+func GenerateTOTPKey(username string) (*otp.Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      TOTPIssuer,
+		AccountName: username,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp key: %w", err)
+	}
+	return key, nil
+}
+
+// ValidateTOTP 校验动态码，返回匹配的时间步；不接受不晚于 lastStep 的时间步，防止同一个码被重复使用
+func ValidateTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	current := now.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step := current + offset
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// isTOTPCode 六位数字为动态码，其余按恢复码处理
+func isTOTPCode(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// recoveryCodeAlphabet 去掉了容易混淆的字符
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes 生成 n 个形如 xxxxx-xxxxx 的一次性恢复码
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	// 丢弃超出字母表整数倍的字节，避免取模带来的偏差
+	limit := byte(256 / len(recoveryCodeAlphabet) * len(recoveryCodeAlphabet))
+	codes := make([]string, n)
+	buf := make([]byte, 1)
+	for i := range codes {
+		chars := make([]byte, 0, 10)
+		for len(chars) < cap(chars) {
+			if _, err := rand.Read(buf); err != nil {
+				return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+			}
+			if buf[0] < limit {
+				chars = append(chars, recoveryCodeAlphabet[int(buf[0])%len(recoveryCodeAlphabet)])
+			}
+		}
+		codes[i] = string(chars[:5]) + "-" + string(chars[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode 计算恢复码哈希，忽略大小写、空格和连字符
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+	return HashAPIToken(normalized)
+}
+
+// VerifySecondFactor 校验登录第二步的动态码或恢复码；动态码会记录时间步，恢复码使用后即失效
+func VerifySecondFactor(user *models.User, code string) (bool, error) {
+	if isTOTPCode(code) {
+		step, ok := ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// 条件更新，并发提交同一个码时只有一个请求成功
+		result := database.GetDB().Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			UpdateColumn("totp_last_step", step)
+		if result.Error != nil {
+			return false, fmt.Errorf("failed to record totp step: %w", result.Error)
+		}
+		user.TOTPLastStep = step
+		return result.RowsAffected == 1, nil
+	}
+
+	result := database.GetDB().Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, HashRecoveryCode(code)).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,13 +18,20 @@ type Config struct {
 	Database    DatabaseConfig    `yaml:"database"`
 	JWT         JWTConfig         `yaml:"jwt"`
 	DefaultUser DefaultUserConfig `yaml:"default_user"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Login       LoginConfig       `yaml:"login"`
+	OIDC        OIDCConfig        `yaml:"oidc"`
+	GitOps      GitOpsConfig      `yaml:"gitops"`
+	Runtimes    []RuntimeConfig   `yaml:"runtimes"`
+	Python      PythonConfig      `yaml:"python"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port int    `yaml:"port"`
-	Host string `yaml:"host"`
-	Mode string `yaml:"mode"`
+	Port           int      `yaml:"port"`
+	Host           string   `yaml:"host"`
+	Mode           string   `yaml:"mode"`
+	TrustedProxies []string `yaml:"trusted_proxies"` // 反向代理的 IP 或 CIDR，只信任这些地址发来的 X-Forwarded-For；默认不信任任何代理
 }
 
 // DatabaseConfig 数据库配置
@@ -32,12 +43,12 @@ type DatabaseConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret               string `yaml:"secret"`
-	ExpireHours          int    `yaml:"expire_hours"`
-	RefreshExpireHours   int    `yaml:"refresh_expire_hours"`
-	CookieName           string `yaml:"cookie_name"`
-	HTTPOnly             bool   `yaml:"http_only"`
-	Secure               bool   `yaml:"secure"`
+	Secret             string `yaml:"secret"`
+	ExpireHours        int    `yaml:"expire_hours"`
+	RefreshExpireHours int    `yaml:"refresh_expire_hours"`
+	CookieName         string `yaml:"cookie_name"`
+	HTTPOnly           bool   `yaml:"http_only"`
+	Secure             bool   `yaml:"secure"`
 }
 
 // DefaultUserConfig 默认用户配置
@@ -46,6 +57,143 @@ type DefaultUserConfig struct {
 	Password string `yaml:"password"`
 }
 
+// RetentionConfig 执行记录保留策略配置
+type RetentionConfig struct {
+	MaxAgeDays     int    `yaml:"max_age_days"`      // 保留天数，0 表示不限制
+	MaxRowsPerTask int    `yaml:"max_rows_per_task"` // 每个任务最多保留的记录数，0 表示不限制
+	PruneInterval  string `yaml:"prune_interval"`    // 清理间隔，例如 "1h"
+	DryRun         bool   `yaml:"dry_run"`           // 只生成报告，不实际删除
+	Vacuum         string `yaml:"vacuum"`            // 清理后的空间回收方式: none, incremental, full
+}
+
+// LoginConfig 登录防暴力破解配置
+type LoginConfig struct {
+	MaxFailures     int    `yaml:"max_failures"`     // 同一用户名连续失败多少次后锁定账户，0 表示不锁定
+	LockoutDuration string `yaml:"lockout_duration"` // 账户锁定时长，例如 "15m"
+	IPMaxFailures   int    `yaml:"ip_max_failures"`  // 同一IP在统计窗口内失败多少次后暂时封禁，0 表示不封禁
+	FailureWindow   string `yaml:"failure_window"`   // 失败次数统计窗口，超过后重新计数
+	BaseDelay       string `yaml:"base_delay"`       // 失败后首次需要等待的时间，之后每次失败翻倍
+	MaxDelay        string `yaml:"max_delay"`        // 等待时间上限
+}
+
+// OIDCConfig OIDC 单点登录配置（授权码模式 + PKCE）
+type OIDCConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	DisplayName   string            `yaml:"display_name"` // 登录页按钮上显示的身份提供方名称
+	Issuer        string            `yaml:"issuer"`       // 身份提供方地址，通过 /.well-known/openid-configuration 自动发现
+	ClientID      string            `yaml:"client_id"`
+	ClientSecret  string            `yaml:"client_secret"`  // 公共客户端可留空，仅依靠 PKCE
+	RedirectURL   string            `yaml:"redirect_url"`   // 例如 https://cron.example.com/auth/oidc/callback
+	Scopes        []string          `yaml:"scopes"`         // 默认 openid profile email
+	UsernameClaim string            `yaml:"username_claim"` // 作为用户名的 claim，默认 preferred_username
+	GroupsClaim   string            `yaml:"groups_claim"`   // 用户组 claim，默认 groups
+	RoleMapping   map[string]string `yaml:"role_mapping"`   // 用户组 → 角色，多个组匹配时取最高角色
+	DefaultRole   string            `yaml:"default_role"`   // 没有匹配的用户组时的角色，为空则拒绝登录
+}
+
+// GitOpsConfig 从本地目录的任务清单持续同步任务
+type GitOpsConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Dir      string `yaml:"dir"`      // 清单目录（包含子目录中的 *.yaml、*.yml、*.json），相对路径基于配置文件所在目录
+	Interval string `yaml:"interval"` // 检查间隔，例如 "30s"
+	DryRun   bool   `yaml:"dry_run"`  // 只报告偏差，不修改任务
+}
+
+// RuntimeConfig 脚本运行时：脚本类型对应的文件扩展名、执行命令和脚本头部
+type RuntimeConfig struct {
+	Name      string `yaml:"name"`      // 脚本类型，也是脚本文件所在的子目录名
+	Label     string `yaml:"label"`     // 界面中显示的名称
+	Extension string `yaml:"extension"` // 脚本文件扩展名，不带点
+	Command   string `yaml:"command"`   // 执行命令模板，{file} 替换为脚本文件路径
+	Header    string `yaml:"header"`    // 写在脚本开头的头部模板，{name}、{created}、{id} 替换为任务名称、创建时间和任务 ID
+	Template  string `yaml:"template"`  // 新建任务时编辑器中的初始内容
+	Editor    string `yaml:"editor"`    // 编辑器的语法高亮模式（CodeMirror mode）
+	Check     string `yaml:"check"`     // 保存前的语法检查命令模板，{file} 替换为脚本文件路径，失败时拒绝保存；程序不存在时跳过
+	Lint      string `yaml:"lint"`      // 可选的静态检查命令模板，发现的问题只作为提示；程序不存在时跳过
+}
+
+// PythonConfig Python 任务声明依赖时使用的虚拟环境
+type PythonConfig struct {
+	Interpreter  string `yaml:"interpreter"`   // 创建虚拟环境所用的解释器，默认 python3
+	Wheelhouse   string `yaml:"wheelhouse"`    // 本地 wheel 或索引目录，只从这里安装依赖（离线可用），相对路径基于配置文件所在目录
+	IndexURL     string `yaml:"index_url"`     // 不使用 wheelhouse 时的包索引地址；两者都为空时使用数据目录下的 wheels 目录
+	BuildTimeout string `yaml:"build_timeout"` // 构建一个虚拟环境的超时时间，例如 "10m"
+}
+
+// runtimeNamePattern 运行时名称同时用作目录名，只允许小写字母、数字和连字符
+var runtimeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// runtimeExtensionPattern 脚本文件扩展名
+var runtimeExtensionPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// DefaultRuntimes 内置的脚本运行时，shell 和 python 的头部与之前生成的脚本保持一致
+func DefaultRuntimes() []RuntimeConfig {
+	return []RuntimeConfig{
+		{
+			Name:      "shell",
+			Label:     "Shell脚本",
+			Extension: "sh",
+			Command:   "/bin/bash {file}",
+			Header:    "#!/bin/bash\n# B1Cron Task: {name}\n# Created: {created}\n# Task ID: {id}\n\n",
+			Template:  "#!/bin/bash\n\n# 在这里编写您的Shell脚本\n",
+			Editor:    "shell",
+			Check:     "bash -n {file}",
+			Lint:      "shellcheck -f gcc -s bash {file}",
+		},
+		{
+			Name:      "bash-strict",
+			Label:     "Bash脚本（严格模式）",
+			Extension: "sh",
+			Command:   "/bin/bash {file}",
+			Header:    "#!/bin/bash\n# B1Cron Task: {name}\n# Created: {created}\n# Task ID: {id}\nset -euo pipefail\n\n",
+			Template:  "# 任一命令失败、使用未定义的变量或管道中的命令失败时脚本立即退出\n",
+			Editor:    "shell",
+			Check:     "bash -n {file}",
+			Lint:      "shellcheck -f gcc -s bash {file}",
+		},
+		{
+			// 使用 python3 命令，依赖 shell 的 PATH 环境变量查找，在 Docker Alpine 环境中更加可靠
+			Name:      "python",
+			Label:     "Python脚本",
+			Extension: "py",
+			Command:   "python3 {file}",
+			Header:    "#!/usr/bin/env python3\n# -*- coding: utf-8 -*-\n# B1Cron Task: {name}\n# Created: {created}\n# Task ID: {id}\n\n",
+			Template:  "#!/usr/bin/env python3\n# -*- coding: utf-8 -*-\n# 在这里编写您的Python脚本\n",
+			Editor:    "python",
+			Check:     "python3 -m py_compile {file}",
+		},
+		{
+			Name:      "node",
+			Label:     "Node.js脚本",
+			Extension: "js",
+			Command:   "node {file}",
+			Header:    "#!/usr/bin/env node\n// B1Cron Task: {name}\n// Created: {created}\n// Task ID: {id}\n\n",
+			Template:  "// 在这里编写您的Node.js脚本\n",
+			Editor:    "javascript",
+			Check:     "node --check {file}",
+		},
+		{
+			Name:      "perl",
+			Label:     "Perl脚本",
+			Extension: "pl",
+			Command:   "perl {file}",
+			Header:    "#!/usr/bin/env perl\n# B1Cron Task: {name}\n# Created: {created}\n# Task ID: {id}\n\n",
+			Template:  "use strict;\nuse warnings;\n\n# 在这里编写您的Perl脚本\n",
+			Editor:    "perl",
+			// 不内置 perl -c：它会执行 BEGIN 块和 use 加载的模块，保存时就会运行脚本中的代码
+		},
+		{
+			Name:      "powershell",
+			Label:     "PowerShell脚本",
+			Extension: "ps1",
+			Command:   "pwsh -NoProfile -NonInteractive -File {file}",
+			Header:    "#!/usr/bin/env pwsh\n# B1Cron Task: {name}\n# Created: {created}\n# Task ID: {id}\n\n",
+			Template:  "# 在这里编写您的PowerShell脚本\n",
+			Editor:    "powershell",
+		},
+	}
+}
+
 var GlobalConfig *Config
 
 // LoadConfig 加载配置文件
@@ -81,6 +229,14 @@ func LoadConfig(configPath string) (*Config, error) {
 	if !filepath.IsAbs(config.Database.Path) {
 		config.Database.Path = filepath.Join(filepath.Dir(configPath), config.Database.Path)
 	}
+	if config.GitOps.Dir != "" && !filepath.IsAbs(config.GitOps.Dir) {
+		config.GitOps.Dir = filepath.Join(filepath.Dir(configPath), config.GitOps.Dir)
+	}
+	if config.Python.Wheelhouse == "" && config.Python.IndexURL == "" {
+		config.Python.Wheelhouse = filepath.Join(filepath.Dir(config.Database.Path), "wheels")
+	} else if config.Python.Wheelhouse != "" && !filepath.IsAbs(config.Python.Wheelhouse) {
+		config.Python.Wheelhouse = filepath.Join(filepath.Dir(configPath), config.Python.Wheelhouse)
+	}
 
 	// 设置全局配置
 	GlobalConfig = &config
@@ -94,6 +250,11 @@ func validateConfig(config *Config) error {
 	if config.Server.Port <= 0 || config.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", config.Server.Port)
 	}
+	for _, proxy := range config.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid server trusted proxy: %s", proxy)
+		}
+	}
 
 	// 验证JWT配置
 	if config.JWT.Secret == "" || config.JWT.Secret == "b1cron-jwt-secret-key-change-in-production" {
@@ -111,14 +272,198 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("database path cannot be empty")
 	}
 
+	// 验证执行记录保留策略
+	if config.Retention.MaxAgeDays < 0 {
+		return fmt.Errorf("invalid retention max_age_days: %d", config.Retention.MaxAgeDays)
+	}
+	if config.Retention.MaxRowsPerTask < 0 {
+		return fmt.Errorf("invalid retention max_rows_per_task: %d", config.Retention.MaxRowsPerTask)
+	}
+	if config.Retention.PruneInterval == "" {
+		config.Retention.PruneInterval = "1h"
+	}
+	if d, err := time.ParseDuration(config.Retention.PruneInterval); err != nil || d <= 0 {
+		return fmt.Errorf("invalid retention prune_interval: %s", config.Retention.PruneInterval)
+	}
+	switch config.Retention.Vacuum {
+	case "":
+		config.Retention.Vacuum = "incremental"
+	case "none", "incremental", "full":
+	default:
+		return fmt.Errorf("invalid retention vacuum mode: %s", config.Retention.Vacuum)
+	}
+
+	// 验证登录防暴力破解配置
+	if config.Login.MaxFailures < 0 {
+		return fmt.Errorf("invalid login max_failures: %d", config.Login.MaxFailures)
+	}
+	if config.Login.IPMaxFailures < 0 {
+		return fmt.Errorf("invalid login ip_max_failures: %d", config.Login.IPMaxFailures)
+	}
+	loginDurations := []struct {
+		name  string
+		value *string
+		def   string
+	}{
+		{"lockout_duration", &config.Login.LockoutDuration, "15m"},
+		{"failure_window", &config.Login.FailureWindow, "15m"},
+		{"base_delay", &config.Login.BaseDelay, "1s"},
+		{"max_delay", &config.Login.MaxDelay, "30s"},
+	}
+	for _, item := range loginDurations {
+		if *item.value == "" {
+			*item.value = item.def
+		}
+		if d, err := time.ParseDuration(*item.value); err != nil || d < 0 {
+			return fmt.Errorf("invalid login %s: %s", item.name, *item.value)
+		}
+	}
+
+	// 验证 OIDC 配置
+	if config.OIDC.Enabled {
+		if config.OIDC.Issuer == "" || config.OIDC.ClientID == "" || config.OIDC.RedirectURL == "" {
+			return fmt.Errorf("oidc issuer, client_id and redirect_url are required")
+		}
+		if len(config.OIDC.Scopes) == 0 {
+			config.OIDC.Scopes = []string{"openid", "profile", "email"}
+		}
+		if config.OIDC.UsernameClaim == "" {
+			config.OIDC.UsernameClaim = "preferred_username"
+		}
+		if config.OIDC.GroupsClaim == "" {
+			config.OIDC.GroupsClaim = "groups"
+		}
+		if config.OIDC.DisplayName == "" {
+			config.OIDC.DisplayName = "SSO"
+		}
+	}
+
+	// 验证 GitOps 配置
+	if config.GitOps.Interval == "" {
+		config.GitOps.Interval = "30s"
+	}
+	if d, err := time.ParseDuration(config.GitOps.Interval); err != nil || d <= 0 {
+		return fmt.Errorf("invalid gitops interval: %s", config.GitOps.Interval)
+	}
+	if config.GitOps.Enabled && config.GitOps.Dir == "" {
+		return fmt.Errorf("gitops dir is required when gitops is enabled")
+	}
+
+	// 验证脚本运行时
+	runtimes, err := mergeRuntimes(config.Runtimes)
+	if err != nil {
+		return err
+	}
+	config.Runtimes = runtimes
+
+	// 验证 Python 虚拟环境配置
+	if config.Python.Interpreter == "" {
+		config.Python.Interpreter = "python3"
+	}
+	if config.Python.BuildTimeout == "" {
+		config.Python.BuildTimeout = "10m"
+	}
+	if d, err := time.ParseDuration(config.Python.BuildTimeout); err != nil || d <= 0 {
+		return fmt.Errorf("invalid python build_timeout: %s", config.Python.BuildTimeout)
+	}
+
 	return nil
 }
 
+// mergeRuntimes 在内置运行时的基础上合并配置的运行时：
+// 与内置运行时同名的条目覆盖其中非空的字段，其余的条目作为新的运行时追加在后面
+func mergeRuntimes(configured []RuntimeConfig) ([]RuntimeConfig, error) {
+	runtimes := DefaultRuntimes()
+	index := make(map[string]int, len(runtimes))
+	for i, runtime := range runtimes {
+		index[runtime.Name] = i
+	}
+
+	seen := make(map[string]bool, len(configured))
+	for _, item := range configured {
+		if !runtimeNamePattern.MatchString(item.Name) || item.Name == "command" {
+			return nil, fmt.Errorf("invalid runtime name: %q", item.Name)
+		}
+		if seen[item.Name] {
+			return nil, fmt.Errorf("duplicate runtime: %s", item.Name)
+		}
+		seen[item.Name] = true
+
+		i, ok := index[item.Name]
+		if !ok {
+			index[item.Name] = len(runtimes)
+			runtimes = append(runtimes, item)
+			continue
+		}
+		merged := &runtimes[i]
+		for _, field := range []struct{ dst, src *string }{
+			{&merged.Label, &item.Label},
+			{&merged.Extension, &item.Extension},
+			{&merged.Command, &item.Command},
+			{&merged.Header, &item.Header},
+			{&merged.Template, &item.Template},
+			{&merged.Editor, &item.Editor},
+			{&merged.Check, &item.Check},
+			{&merged.Lint, &item.Lint},
+		} {
+			if *field.src != "" {
+				*field.dst = *field.src
+			}
+		}
+	}
+
+	for i := range runtimes {
+		runtime := &runtimes[i]
+		if !runtimeExtensionPattern.MatchString(runtime.Extension) {
+			return nil, fmt.Errorf("invalid extension for runtime %s: %q", runtime.Name, runtime.Extension)
+		}
+		if !strings.Contains(runtime.Command, "{file}") {
+			return nil, fmt.Errorf("command of runtime %s must contain {file}", runtime.Name)
+		}
+		for _, tool := range []struct{ name, template string }{{"check", runtime.Check}, {"lint", runtime.Lint}} {
+			if tool.template != "" && !strings.Contains(tool.template, "{file}") {
+				return nil, fmt.Errorf("%s of runtime %s must contain {file}", tool.name, runtime.Name)
+			}
+		}
+		if runtime.Label == "" {
+			runtime.Label = runtime.Name
+		}
+	}
+	return runtimes, nil
+}
+
 // GetAddr 获取服务器监听地址
 func (c *Config) GetAddr() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+// GetPruneInterval 获取执行记录清理间隔
+func (c *Config) GetPruneInterval() time.Duration {
+	d, err := time.ParseDuration(c.Retention.PruneInterval)
+	if err != nil || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// GetGitOpsInterval 获取 GitOps 清单检查间隔
+func (c *Config) GetGitOpsInterval() time.Duration {
+	d, err := time.ParseDuration(c.GitOps.Interval)
+	if err != nil || d <= 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// GetVenvBuildTimeout 获取虚拟环境的构建超时时间
+func (c *Config) GetVenvBuildTimeout() time.Duration {
+	d, err := time.ParseDuration(c.Python.BuildTimeout)
+	if err != nil || d <= 0 {
+		return 10 * time.Minute
+	}
+	return d
+}
+
 // IsDevelopment 是否为开发模式
 func (c *Config) IsDevelopment() bool {
 	return c.Server.Mode == "debug"
@@ -127,4 +472,4 @@ func (c *Config) IsDevelopment() bool {
 // IsProduction 是否为生产模式
 func (c *Config) IsProduction() bool {
 	return c.Server.Mode == "release"
-}
\ No newline at end of file
+}
@@ -14,24 +14,35 @@ import (
 
 var DB *gorm.DB
 
+// migratedModels 由 AutoMigrate 管理的表
+var migratedModels = []interface{}{&models.User{}, &models.Task{}, &models.TaskExecution{}, &models.Namespace{}, &models.NamespaceMember{}, &models.APIToken{}, &models.AuditLog{}, &models.TaskRevision{}, &models.RecoveryCode{}, &models.Session{}, &models.LibraryModule{}, &models.LibraryModuleVersion{}}
+
+// LogLevel SQL 日志级别，命令行子命令使用 logger.Silent 避免输出 SQL
+var LogLevel = logger.Info
+
 func InitDatabase(dbPath string) error {
 	// 确保数据库目录存在
 	dbDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
 		return fmt.Errorf("failed to create database directory: %w", err)
 	}
-	
+
 	var err error
-	
+
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: logger.Default.LogMode(LogLevel),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// 升级前的单用户版本没有角色字段，迁移后需要把已有用户设为管理员
+	hadUserRoles := !DB.Migrator().HasTable(&models.User{}) || DB.Migrator().HasColumn(&models.User{}, "role")
+
+	hadNamespaces := DB.Migrator().HasTable(&models.Namespace{})
+
 	// 先进行 AutoMigrate
-	err = DB.AutoMigrate(&models.User{}, &models.Task{}, &models.TaskExecution{})
+	err = DB.AutoMigrate(migratedModels...)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
@@ -41,10 +52,49 @@ func InitDatabase(dbPath string) error {
 		return fmt.Errorf("failed to migrate task command: %w", err)
 	}
 
+	if !hadUserRoles {
+		if err := DB.Model(&models.User{}).Where("1 = 1").Update("role", "admin").Error; err != nil {
+			return fmt.Errorf("failed to migrate user roles: %w", err)
+		}
+		log.Printf("Existing users migrated to admin role")
+	}
+
+	if err := migrateNamespaces(hadNamespaces); err != nil {
+		return err
+	}
+
+	// 执行输出全文索引
+	if err := initExecutionFTS(); err != nil {
+		return fmt.Errorf("failed to initialize execution full-text index: %w", err)
+	}
+
 	log.Printf("Database initialized successfully at: %s", dbPath)
 	return nil
 }
 
+// OpenDatabase 打开已有的数据库但不执行迁移，供命令行工具使用；
+// 数据库不存在或还没有迁移到当前版本时返回错误
+func OpenDatabase(dbPath string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("database not found: %w", err)
+	}
+
+	var err error
+	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(LogLevel),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	for _, model := range migratedModels {
+		if !DB.Migrator().HasTable(model) {
+			return fmt.Errorf("database %s is not up to date, run \"b1cron db migrate\" first", dbPath)
+		}
+	}
+	return nil
+}
+
 func migrateTaskCommand() error {
 	// 检查 command 字段是否存在
 	if !DB.Migrator().HasColumn(&models.Task{}, "command") {
@@ -53,23 +103,37 @@ func migrateTaskCommand() error {
 			return err
 		}
 	}
-	
+
 	// 为所有没有 command 的现有任务设置默认值
 	var count int64
 	if err := DB.Model(&models.Task{}).Where("command IS NULL OR command = ''").Count(&count).Error; err != nil {
 		return err
 	}
-	
+
 	if count > 0 {
 		if err := DB.Model(&models.Task{}).Where("command IS NULL OR command = ''").Update("command", "echo 'No command specified'").Error; err != nil {
 			return err
 		}
 		log.Printf("Updated %d tasks with default command", count)
 	}
-	
+
 	return nil
 }
 
 func GetDB() *gorm.DB {
 	return DB
-}
\ No newline at end of file
+}
+
+// Backup 使用 VACUUM INTO 把数据库一致地复制到新文件，服务运行时也可以执行
+func Backup(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file already exists: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := DB.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
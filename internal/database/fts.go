@@ -0,0 +1,102 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ExecutionFTSTable 执行记录输出的 FTS5 全文索引表
+const ExecutionFTSTable = "task_executions_fts"
+
+// ftsEnabled 当前 SQLite 是否支持 FTS5（需使用 -tags sqlite_fts5 编译）
+var ftsEnabled bool
+
+// FTSEnabled 是否可以使用 FTS5 全文索引
+func FTSEnabled() bool {
+	return ftsEnabled
+}
+
+// FTSPhrase 将用户输入转换为 FTS5 短语查询，避免输入被解析为查询语法
+func FTSPhrase(query string) string {
+	return `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+}
+
+// initExecutionFTS 创建镜像 TaskExecution.Output/ErrorMsg 的外部内容 FTS5 表，
+// 并通过触发器在插入、更新和清理时保持同步
+func initExecutionFTS() error {
+	tableExists, err := sqliteObjectExists("table", ExecutionFTSTable)
+	if err != nil {
+		return err
+	}
+
+	if !tableExists {
+		// trigram 分词器支持子串匹配，对中文和错误信息片段更友好
+		err = DB.Exec(fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts5(
+			output, error_msg,
+			content='task_executions', content_rowid='id',
+			tokenize='trigram'
+		)`, ExecutionFTSTable)).Error
+	} else {
+		err = DB.Exec(fmt.Sprintf("SELECT rowid FROM %s LIMIT 0", ExecutionFTSTable)).Error
+	}
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module") {
+			return fmt.Errorf("failed to create FTS table: %w", err)
+		}
+		// 当前二进制不支持 FTS5：移除触发器，避免写入执行记录时报错
+		log.Printf("Warning: SQLite FTS5 is not available (build with -tags sqlite_fts5), execution output search falls back to LIKE")
+		return dropExecutionFTSTriggers()
+	}
+
+	// 触发器缺失说明索引可能已过期（例如曾用不支持 FTS5 的版本运行过），需要重建
+	triggerExists, err := sqliteObjectExists("trigger", "task_executions_fts_ai")
+	if err != nil {
+		return err
+	}
+
+	triggers := []string{
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS task_executions_fts_ai AFTER INSERT ON task_executions BEGIN
+			INSERT INTO %[1]s(rowid, output, error_msg) VALUES (new.id, new.output, new.error_msg);
+		END`, ExecutionFTSTable),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS task_executions_fts_ad AFTER DELETE ON task_executions BEGIN
+			INSERT INTO %[1]s(%[1]s, rowid, output, error_msg) VALUES ('delete', old.id, old.output, old.error_msg);
+		END`, ExecutionFTSTable),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS task_executions_fts_au AFTER UPDATE OF output, error_msg ON task_executions BEGIN
+			INSERT INTO %[1]s(%[1]s, rowid, output, error_msg) VALUES ('delete', old.id, old.output, old.error_msg);
+			INSERT INTO %[1]s(rowid, output, error_msg) VALUES (new.id, new.output, new.error_msg);
+		END`, ExecutionFTSTable),
+	}
+	for _, trigger := range triggers {
+		if err := DB.Exec(trigger).Error; err != nil {
+			return fmt.Errorf("failed to create FTS trigger: %w", err)
+		}
+	}
+
+	if !triggerExists {
+		if err := DB.Exec(fmt.Sprintf("INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')", ExecutionFTSTable)).Error; err != nil {
+			return fmt.Errorf("failed to build FTS index: %w", err)
+		}
+		log.Printf("Execution output full-text index built")
+	}
+
+	ftsEnabled = true
+	return nil
+}
+
+func dropExecutionFTSTriggers() error {
+	for _, name := range []string{"task_executions_fts_ai", "task_executions_fts_ad", "task_executions_fts_au"} {
+		if err := DB.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return fmt.Errorf("failed to drop FTS trigger %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func sqliteObjectExists(objectType, name string) (bool, error) {
+	var count int64
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name = ?", objectType, name).Scan(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
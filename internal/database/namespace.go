@@ -0,0 +1,51 @@
+package database
+
+import (
+	"b1cron/internal/models"
+	"fmt"
+	"log"
+)
+
+// DefaultNamespaceName 默认命名空间，升级前的任务和未指定命名空间的任务归属于它
+const DefaultNamespaceName = "default"
+
+// DefaultNamespaceID 获取默认命名空间ID
+func DefaultNamespaceID() (uint, error) {
+	var namespace models.Namespace
+	if err := DB.Where("name = ?", DefaultNamespaceName).First(&namespace).Error; err != nil {
+		return 0, fmt.Errorf("failed to get default namespace: %w", err)
+	}
+	return namespace.ID, nil
+}
+
+// migrateNamespaces 创建默认命名空间，并把没有命名空间的任务归入其中；
+// 首次引入命名空间时，已有用户全部加入默认命名空间以保持原有可见范围
+func migrateNamespaces(hadNamespaces bool) error {
+	namespace := models.Namespace{Name: DefaultNamespaceName, Description: "默认命名空间"}
+	if err := DB.Where("name = ?", DefaultNamespaceName).FirstOrCreate(&namespace).Error; err != nil {
+		return fmt.Errorf("failed to create default namespace: %w", err)
+	}
+
+	result := DB.Model(&models.Task{}).Unscoped().Where("namespace_id = 0").Update("namespace_id", namespace.ID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to migrate task namespaces: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Moved %d tasks into namespace %q", result.RowsAffected, DefaultNamespaceName)
+	}
+
+	if !hadNamespaces {
+		var users []models.User
+		if err := DB.Find(&users).Error; err != nil {
+			return fmt.Errorf("failed to get users: %w", err)
+		}
+		for _, user := range users {
+			member := models.NamespaceMember{NamespaceID: namespace.ID, UserID: user.ID}
+			if err := DB.FirstOrCreate(&member, member).Error; err != nil {
+				return fmt.Errorf("failed to add namespace member: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenHandler 个人 API 令牌处理器
+type APITokenHandler struct {
+	tokenService *service.APITokenService
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = never expires
+}
+
+func NewAPITokenHandler(tokenService *service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{
+		tokenService: tokenService,
+	}
+}
+
+func (h *APITokenHandler) GetTokens(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	tokens, err := h.tokenService.ListTokens(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken 创建令牌，响应中的 token 字段只返回这一次
+func (h *APITokenHandler) CreateToken(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days cannot be negative"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	token, raw, err := h.tokenService.CreateToken(user.ID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":     raw,
+		"api_token": token,
+	})
+}
+
+func (h *APITokenHandler) RevokeToken(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(uint(id), user.ID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
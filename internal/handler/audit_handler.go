@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 审计日志处理器
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// recordAudit 以当前请求的用户和来源IP记录审计日志
+func recordAudit(c *gin.Context, entry audit.Entry) {
+	if entry.Actor == nil {
+		entry.Actor = auth.CurrentUser(c)
+	}
+	entry.IP = c.ClientIP()
+	audit.Record(entry)
+}
+
+// parsePagination 解析分页参数，page_size 最大 100
+func parsePagination(c *gin.Context) (int, int) {
+	page := 1
+	if parsedPage, err := strconv.Atoi(c.Query("page")); err == nil && parsedPage > 0 {
+		page = parsedPage
+	}
+	pageSize := 20
+	if parsedPageSize, err := strconv.Atoi(c.Query("page_size")); err == nil && parsedPageSize > 0 && parsedPageSize <= 100 {
+		pageSize = parsedPageSize
+	}
+	return page, pageSize
+}
+
+func paginationResponse(page, pageSize int, total int64) gin.H {
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	return gin.H{
+		"current_page": page,
+		"page_size":    pageSize,
+		"total_items":  total,
+		"total_pages":  totalPages,
+		"has_next":     page < totalPages,
+		"has_prev":     page > 1,
+	}
+}
+
+// GetAuditLogs 查询审计日志，支持 action、actor、target_type、target_id、from、to 过滤
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	filter := service.AuditFilter{
+		Actions:    splitQueryValues(c.QueryArray("action")),
+		Actor:      c.Query("actor"),
+		TargetType: c.Query("target_type"),
+	}
+	if value := c.Query("target_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_id: " + value})
+			return
+		}
+		targetID := uint(id)
+		filter.TargetID = &targetID
+	}
+
+	var err error
+	if filter.From, err = parseTimeParam(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = parseTimeParam(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+	logs, total, err := h.auditService.QueryAuditLogs(filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":       logs,
+		"pagination": paginationResponse(page, pageSize, total),
+	})
+}
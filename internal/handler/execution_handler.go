@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// executionFilterParams 触发过滤分页API的查询参数
+var executionFilterParams = []string{
+	"search", "page", "page_size", "q", "status", "task_id", "from", "to",
+	"min_duration", "max_duration", "exit_code", "sort", "order",
+}
+
+// hasExecutionFilter 请求中是否包含任一过滤或分页参数
+func hasExecutionFilter(c *gin.Context) bool {
+	for _, param := range executionFilterParams {
+		if _, ok := c.GetQuery(param); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExecutionFilter 从查询参数解析执行记录过滤条件
+// 多值参数既支持重复传参（status=a&status=b），也支持逗号分隔（status=a,b）
+func parseExecutionFilter(c *gin.Context) (service.ExecutionFilter, error) {
+	filter := service.ExecutionFilter{
+		Search:    c.Query("search"),
+		Query:     c.Query("q"),
+		Statuses:  splitQueryValues(c.QueryArray("status")),
+		SortBy:    c.Query("sort"),
+		SortOrder: strings.ToLower(c.Query("order")),
+	}
+
+	for _, value := range splitQueryValues(c.QueryArray("task_id")) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid task_id: %s", value)
+		}
+		filter.TaskIDs = append(filter.TaskIDs, uint(id))
+	}
+
+	var err error
+	if filter.StartedFrom, err = parseTimeParam(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.StartedTo, err = parseTimeParam(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.MinDuration, err = parseInt64Param(c, "min_duration"); err != nil {
+		return filter, err
+	}
+	if filter.MaxDuration, err = parseInt64Param(c, "max_duration"); err != nil {
+		return filter, err
+	}
+
+	if value := c.Query("exit_code"); value != "" {
+		exitCode, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid exit_code: %s", value)
+		}
+		filter.ExitCode = &exitCode
+	}
+
+	return filter, filter.Validate()
+}
+
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// parseTimeParam 支持 RFC3339、YYYY-MM-DDTHH:MM 和 YYYY-MM-DD（后两者按本地时区）
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s format, expected RFC3339, YYYY-MM-DDTHH:MM or YYYY-MM-DD", name)
+}
+
+func parseInt64Param(c *gin.Context, name string) (*int64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &n, nil
+}
+
+// SearchExecutions 在执行输出和错误信息中全文搜索，返回高亮片段
+func (h *TaskHandler) SearchExecutions(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	var taskIDs []uint
+	for _, value := range splitQueryValues(c.QueryArray("task_id")) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid task_id: %s", value)})
+			return
+		}
+		taskIDs = append(taskIDs, uint(id))
+	}
+
+	page := 1
+	if parsedPage, err := strconv.Atoi(c.Query("page")); err == nil && parsedPage > 0 {
+		page = parsedPage
+	}
+	pageSize := 20
+	if parsedPageSize, err := strconv.Atoi(c.Query("page_size")); err == nil && parsedPageSize > 0 && parsedPageSize <= 100 {
+		pageSize = parsedPageSize
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+
+	results, total, err := h.taskService.SearchExecutionOutput(scope, query, taskIDs, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"full_text": database.FTSEnabled(),
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total_items":  total,
+			"total_pages":  totalPages,
+			"has_next":     page < totalPages,
+			"has_prev":     page > 1,
+		},
+	})
+}
+
+// ExportExecutions 按过滤条件导出执行记录，format=csv（默认）或 ndjson
+func (h *TaskHandler) ExportExecutions(c *gin.Context) {
+	filter, err := parseExecutionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ok bool
+	if filter.Scope, ok = h.namespaceScope(c); !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format, expected csv or ndjson"})
+		return
+	}
+
+	filename := fmt.Sprintf("executions_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var writeHeader, flush func() error
+	var writeRow func(execution *models.TaskExecution) error
+
+	if format == "ndjson" {
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		encoder := json.NewEncoder(c.Writer)
+		writeRow = func(execution *models.TaskExecution) error {
+			return encoder.Encode(gin.H{
+				"id":           execution.ID,
+				"task_id":      execution.TaskID,
+				"task_name":    execution.Task.Name,
+				"status":       execution.Status,
+				"exit_code":    execution.ExitCode,
+				"started_at":   execution.StartedAt,
+				"completed_at": execution.CompletedAt,
+				"duration":     execution.Duration,
+				"output":       execution.Output,
+				"error_msg":    execution.ErrorMsg,
+			})
+		}
+		writeHeader = func() error { return nil }
+		flush = func() error { return nil }
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		writer := csv.NewWriter(c.Writer)
+		writeHeader = func() error {
+			// UTF-8 BOM，方便 Excel 正确识别中文
+			if _, err := c.Writer.WriteString("\xEF\xBB\xBF"); err != nil {
+				return err
+			}
+			return writer.Write([]string{"id", "task_id", "task_name", "status", "exit_code", "started_at", "completed_at", "duration_ms", "output", "error_msg"})
+		}
+		writeRow = func(execution *models.TaskExecution) error {
+			exitCode := ""
+			if execution.ExitCode != nil {
+				exitCode = strconv.Itoa(*execution.ExitCode)
+			}
+			completedAt := ""
+			if execution.CompletedAt != nil {
+				completedAt = execution.CompletedAt.Format(time.RFC3339)
+			}
+			return writer.Write([]string{
+				strconv.FormatUint(uint64(execution.ID), 10),
+				strconv.FormatUint(uint64(execution.TaskID), 10),
+				execution.Task.Name,
+				execution.Status,
+				exitCode,
+				execution.StartedAt.Format(time.RFC3339),
+				completedAt,
+				strconv.FormatInt(execution.Duration, 10),
+				execution.Output,
+				execution.ErrorMsg,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+
+	c.Status(http.StatusOK)
+	if err := writeHeader(); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.taskService.ExportExecutions(filter, writeRow); err != nil {
+		// 响应头已发送，只能中断输出
+		c.Error(err)
+		return
+	}
+	if err := flush(); err != nil {
+		c.Error(err)
+	}
+}
+
+// GetExecution 获取单条执行记录，包括完整的输出和错误信息
+func (h *TaskHandler) GetExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid execution ID"})
+		return
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	execution, err := h.taskService.GetExecutionInScope(uint(id), scope)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
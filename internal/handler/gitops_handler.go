@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GitOpsHandler GitOps 清单同步处理器
+type GitOpsHandler struct {
+	gitopsService    *service.GitOpsService
+	namespaceService *service.NamespaceService
+}
+
+func NewGitOpsHandler(gitopsService *service.GitOpsService, namespaceService *service.NamespaceService) *GitOpsHandler {
+	return &GitOpsHandler{gitopsService: gitopsService, namespaceService: namespaceService}
+}
+
+// GetStatus 获取最近一次同步的结果，包括解析错误和与清单不一致的任务；只返回当前用户可访问的命名空间中的偏差和错误
+func (h *GitOpsHandler) GetStatus(c *gin.Context) {
+	scope, err := h.namespaceService.ScopeFor(auth.CurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.gitopsService.Status().InScope(scope))
+}
+
+// Sync 立即同步一次清单目录
+func (h *GitOpsHandler) Sync(c *gin.Context) {
+	status, err := h.gitopsService.Sync()
+	if status == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, status)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
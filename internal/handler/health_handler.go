@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/scheduler"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 健康检查处理器，供 Docker/Kubernetes 探针使用
+type HealthHandler struct {
+	schedulerService *scheduler.SchedulerService
+}
+
+func NewHealthHandler(schedulerService *scheduler.SchedulerService) *HealthHandler {
+	return &HealthHandler{
+		schedulerService: schedulerService,
+	}
+}
+
+// Healthz 存活检查：进程能响应请求即视为存活
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz 就绪检查：数据库可访问、调度器已启动且已有任务加载完成
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ready := true
+	checks := gin.H{}
+
+	// 检查数据库连接
+	dbStatus := "ok"
+	if db := database.GetDB(); db == nil {
+		dbStatus = "not initialized"
+	} else if sqlDB, err := db.DB(); err != nil {
+		dbStatus = err.Error()
+	} else if err := sqlDB.PingContext(c.Request.Context()); err != nil {
+		dbStatus = err.Error()
+	}
+	if dbStatus != "ok" {
+		ready = false
+	}
+	checks["database"] = dbStatus
+
+	// 检查调度器状态
+	schedulerStatus := "ok"
+	if !h.schedulerService.IsStarted() {
+		schedulerStatus = "not started"
+	} else if !h.schedulerService.TasksLoaded() {
+		schedulerStatus = "loading tasks"
+	}
+	if schedulerStatus != "ok" {
+		ready = false
+	}
+	checks["scheduler"] = schedulerStatus
+
+	// 对比已调度任务与已启用任务数量，便于发现不一致
+	scheduledJobs := h.schedulerService.JobCount()
+	var enabledTasks int64 = -1
+	if dbStatus == "ok" {
+		if err := database.GetDB().Model(&models.Task{}).Where("is_enabled = ?", true).Count(&enabledTasks).Error; err != nil {
+			checks["database"] = err.Error()
+			ready = false
+		}
+	}
+	checks["jobs"] = gin.H{
+		"scheduled": scheduledJobs,
+		"enabled":   enabledTasks,
+		"mismatch":  enabledTasks >= 0 && int64(scheduledJobs) != enabledTasks,
+	}
+
+	status := http.StatusOK
+	statusText := "ready"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		statusText = "not ready"
+	}
+
+	c.JSON(status, gin.H{
+		"status": statusText,
+		"checks": checks,
+	})
+}
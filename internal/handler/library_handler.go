@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LibraryHandler 共享脚本库处理器
+type LibraryHandler struct {
+	libraryService   *service.LibraryService
+	namespaceService *service.NamespaceService
+}
+
+type CreateLibraryModuleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Language    string `json:"language" binding:"required"` // shell, python
+	Description string `json:"description"`
+	Content     string `json:"content" binding:"required"`
+}
+
+type UpdateLibraryModuleRequest struct {
+	Description *string `json:"description"` // nil = unchanged
+	Content     string  `json:"content" binding:"required"`
+	Note        string  `json:"note"` // 新版本的说明
+}
+
+func NewLibraryHandler(libraryService *service.LibraryService, namespaceService *service.NamespaceService) *LibraryHandler {
+	return &LibraryHandler{
+		libraryService:   libraryService,
+		namespaceService: namespaceService,
+	}
+}
+
+// librarySnapshot 模块的审计快照
+func librarySnapshot(module *models.LibraryModule) audit.Snapshot {
+	return audit.Snapshot{
+		"name":        module.Name,
+		"language":    module.Language,
+		"description": module.Description,
+		"version":     module.Version,
+	}
+}
+
+// parseModuleID 解析模块ID参数
+func parseModuleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid module ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetModules 获取全部模块及使用它们的任务数
+func (h *LibraryHandler) GetModules(c *gin.Context) {
+	modules, err := h.libraryService.ListModules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, modules)
+}
+
+// GetModule 获取模块、历史版本和使用它的任务，其他命名空间中的任务只返回数量
+func (h *LibraryHandler) GetModule(c *gin.Context) {
+	id, ok := parseModuleID(c)
+	if !ok {
+		return
+	}
+
+	scope, err := h.namespaceService.ScopeFor(auth.CurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	module, err := h.libraryService.GetModule(id, scope)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+		return
+	}
+	c.JSON(http.StatusOK, module)
+}
+
+// GetModuleVersion 获取模块的某个版本
+func (h *LibraryHandler) GetModuleVersion(c *gin.Context) {
+	id, ok := parseModuleID(c)
+	if !ok {
+		return
+	}
+	version, ok := parseRevision(c.Param("version"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	moduleVersion, err := h.libraryService.GetModuleVersion(id, version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	c.JSON(http.StatusOK, moduleVersion)
+}
+
+func (h *LibraryHandler) CreateModule(c *gin.Context) {
+	var req CreateLibraryModuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	module, err := h.libraryService.CreateModule(req.Name, req.Language, req.Description, req.Content, auth.CurrentUser(c))
+	if err != nil {
+		if respondScriptCheckError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, audit.Entry{
+		Action:     audit.ActionLibraryCreate,
+		TargetType: audit.TargetLibrary,
+		TargetID:   module.ID,
+		TargetName: module.Name,
+		After:      librarySnapshot(module),
+	})
+	c.JSON(http.StatusCreated, module)
+}
+
+// UpdateModule 修改模块，内容变化时生成新版本，未固定版本的任务下一次执行时使用新内容
+func (h *LibraryHandler) UpdateModule(c *gin.Context) {
+	id, ok := parseModuleID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateLibraryModuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, err := h.libraryService.GetModule(id, service.AllNamespaces())
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+		return
+	}
+	description := before.Description
+	if req.Description != nil {
+		description = *req.Description
+	}
+	module, err := h.libraryService.UpdateModule(id, description, req.Content, req.Note, auth.CurrentUser(c))
+	if err != nil {
+		if respondScriptCheckError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, audit.Entry{
+		Action:     audit.ActionLibraryUpdate,
+		TargetType: audit.TargetLibrary,
+		TargetID:   module.ID,
+		TargetName: module.Name,
+		Before:     librarySnapshot(&before.LibraryModule),
+		After:      librarySnapshot(module),
+	})
+	c.JSON(http.StatusOK, module)
+}
+
+// DeleteModule 删除模块，仍被任务使用时返回 409
+func (h *LibraryHandler) DeleteModule(c *gin.Context) {
+	id, ok := parseModuleID(c)
+	if !ok {
+		return
+	}
+
+	module, err := h.libraryService.DeleteModule(id)
+	if err != nil {
+		if errors.Is(err, service.ErrLibraryModuleInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, audit.Entry{
+		Action:     audit.ActionLibraryDelete,
+		TargetType: audit.TargetLibrary,
+		TargetID:   module.ID,
+		TargetName: module.Name,
+		Before:     librarySnapshot(module),
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Module deleted successfully"})
+}
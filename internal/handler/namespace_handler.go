@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NamespaceHandler 命名空间管理处理器
+type NamespaceHandler struct {
+	namespaceService *service.NamespaceService
+}
+
+type CreateNamespaceRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type AddNamespaceMemberRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+func NewNamespaceHandler(namespaceService *service.NamespaceService) *NamespaceHandler {
+	return &NamespaceHandler{
+		namespaceService: namespaceService,
+	}
+}
+
+// GetNamespaces 获取当前用户可访问的命名空间，管理员同时返回成员和任务数
+func (h *NamespaceHandler) GetNamespaces(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user != nil && user.Role == auth.RoleAdmin {
+		namespaces, err := h.namespaceService.ListNamespacesWithMembers()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, namespaces)
+		return
+	}
+
+	scope, err := h.namespaceService.ScopeFor(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	namespaces, err := h.namespaceService.ListNamespaces(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, namespaces)
+}
+
+func (h *NamespaceHandler) CreateNamespace(c *gin.Context) {
+	var req CreateNamespaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace, err := h.namespaceService.CreateNamespace(req.Name, req.Description)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, namespace)
+}
+
+func (h *NamespaceHandler) DeleteNamespace(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid namespace ID"})
+		return
+	}
+
+	if err := h.namespaceService.DeleteNamespace(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Namespace deleted successfully"})
+}
+
+func (h *NamespaceHandler) AddMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid namespace ID"})
+		return
+	}
+
+	var req AddNamespaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.namespaceService.AddMember(uint(id), req.UserID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member added successfully"})
+}
+
+func (h *NamespaceHandler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid namespace ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.namespaceService.RemoveMember(uint(id), uint(userID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"errors"
+	"log"
+	"net/http"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler 单点登录处理器：跳转到身份提供方，回调时创建或同步用户并签发 JWT Cookie
+type OIDCHandler struct {
+	provider         *auth.OIDCProvider
+	jwtMiddleware    *jwt.GinJWTMiddleware
+	userService      *service.UserService
+	namespaceService *service.NamespaceService
+}
+
+func NewOIDCHandler(provider *auth.OIDCProvider, jwtMiddleware *jwt.GinJWTMiddleware, userService *service.UserService, namespaceService *service.NamespaceService) *OIDCHandler {
+	return &OIDCHandler{
+		provider:         provider,
+		jwtMiddleware:    jwtMiddleware,
+		userService:      userService,
+		namespaceService: namespaceService,
+	}
+}
+
+// recordSSOLogin 记录单点登录的审计日志
+func recordSSOLogin(c *gin.Context, action string, user *models.User, username, reason string) {
+	entry := audit.Entry{
+		Action:     action,
+		ActorName:  username,
+		IP:         c.ClientIP(),
+		TargetType: audit.TargetUser,
+		TargetName: username,
+		After:      audit.Snapshot{"provider": auth.ProviderOIDC},
+	}
+	if user != nil {
+		entry.TargetID = user.ID
+		if action == audit.ActionLoginSuccess {
+			entry.Actor = user
+		}
+	}
+	if reason != "" {
+		entry.After["reason"] = reason
+	}
+	audit.Record(entry)
+}
+
+// loginError 回到登录页并显示错误，具体原因只写入日志
+func (h *OIDCHandler) loginError(c *gin.Context, reason string) {
+	c.Redirect(http.StatusFound, "/login?sso_error="+reason)
+}
+
+// Login 跳转到身份提供方的授权页面
+func (h *OIDCHandler) Login(c *gin.Context) {
+	url, err := h.provider.AuthCodeURL(c)
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		h.loginError(c, "unavailable")
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// Callback 处理身份提供方的回调
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	identity, err := h.provider.Exchange(c)
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCNoRole) {
+			log.Printf("OIDC login denied for %s: groups %v are not mapped to a role", identity.Username, identity.Groups)
+			recordSSOLogin(c, audit.ActionLoginFailure, nil, identity.Username, audit.LoginSSONoRole)
+			h.loginError(c, audit.LoginSSONoRole)
+			return
+		}
+		log.Printf("OIDC login failed: %v", err)
+		recordSSOLogin(c, audit.ActionLoginFailure, nil, "", audit.LoginSSOError)
+		h.loginError(c, audit.LoginSSOError)
+		return
+	}
+
+	user, created, previousRole, err := h.userService.ProvisionExternalUser(auth.ProviderOIDC, identity.Subject, identity.Username, identity.Role)
+	if err != nil {
+		if errors.Is(err, service.ErrUsernameTaken) {
+			log.Printf("OIDC login denied for %s: username is used by another account", identity.Username)
+			recordSSOLogin(c, audit.ActionLoginFailure, nil, identity.Username, audit.LoginSSOConflict)
+			h.loginError(c, audit.LoginSSOConflict)
+			return
+		}
+		log.Printf("Failed to provision OIDC user %s: %v", identity.Username, err)
+		h.loginError(c, audit.LoginSSOError)
+		return
+	}
+
+	if created {
+		// 新用户和管理员添加的用户一样加入默认命名空间；失败时审计日志记录空的命名空间
+		namespaceIDs := []uint{}
+		defaultID, err := database.DefaultNamespaceID()
+		if err == nil {
+			err = h.namespaceService.SetUserNamespaces(user.ID, []uint{defaultID})
+		}
+		if err != nil {
+			log.Printf("Failed to add OIDC user %s to the default namespace: %v", user.Username, err)
+		} else {
+			namespaceIDs = []uint{defaultID}
+		}
+		audit.Record(audit.Entry{
+			Action:     audit.ActionUserCreate,
+			ActorName:  "system",
+			IP:         c.ClientIP(),
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			TargetName: user.Username,
+			After:      audit.UserSnapshot(user, namespaceIDs),
+		})
+	} else if previousRole != user.Role {
+		audit.Record(audit.Entry{
+			Action:     audit.ActionUserUpdate,
+			ActorName:  "system",
+			IP:         c.ClientIP(),
+			TargetType: audit.TargetUser,
+			TargetID:   user.ID,
+			TargetName: user.Username,
+			Before:     audit.Snapshot{"role": previousRole},
+			After:      audit.Snapshot{"role": user.Role},
+		})
+	}
+
+	if auth.IsLocked(user) {
+		recordSSOLogin(c, audit.ActionLoginFailure, user, user.Username, audit.LoginAccountLocked)
+		h.loginError(c, audit.LoginAccountLocked)
+		return
+	}
+
+	if err := auth.IssueSessionCookie(h.jwtMiddleware, c, user, auth.SessionMethodOIDC); err != nil {
+		log.Printf("Failed to issue session for OIDC user %s: %v", user.Username, err)
+		h.loginError(c, audit.LoginSSOError)
+		return
+	}
+	recordSSOLogin(c, audit.ActionLoginSuccess, user, user.Username, "")
+
+	c.Redirect(http.StatusFound, "/dashboard")
+}
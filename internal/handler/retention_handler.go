@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler 执行记录保留策略处理器
+type RetentionHandler struct {
+	retentionService *service.RetentionService
+	config           *config.Config
+}
+
+func NewRetentionHandler(retentionService *service.RetentionService, cfg *config.Config) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		config:           cfg,
+	}
+}
+
+// GetRetention 获取全局保留策略和最近一次清理报告
+func (h *RetentionHandler) GetRetention(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"policy":      h.config.Retention,
+		"last_report": h.retentionService.LastReport(),
+	})
+}
+
+// Prune 立即执行一次清理，dry_run=true 时只返回将被清理的记录统计；已有清理正在进行时返回 409
+func (h *RetentionHandler) Prune(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := h.retentionService.Prune(dryRun)
+	if errors.Is(err, service.ErrPruneInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
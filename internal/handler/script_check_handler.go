@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"b1cron/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateScriptRequest 脚本检查请求
+type ValidateScriptRequest struct {
+	ScriptType string `json:"script_type" binding:"required"`
+	Command    string `json:"command"`
+}
+
+// ValidateScript 检查脚本内容但不保存，返回语法检查和静态检查发现的问题，供编辑器显示
+func (h *TaskHandler) ValidateScript(c *gin.Context) {
+	var req ValidateScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diagnostics, err := h.taskService.ValidateScript(req.ScriptType, req.Command)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	valid := true
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == "error" {
+			valid = false
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": valid, "diagnostics": diagnostics})
+}
+
+// respondScriptCheckError 脚本没有通过语法检查时返回 400 和带行号的问题列表
+func respondScriptCheckError(c *gin.Context, err error) bool {
+	var checkErr *service.ScriptCheckError
+	if !errors.As(err, &checkErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": checkErr.Error(), "diagnostics": checkErr.Diagnostics})
+	return true
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 登录会话处理器：查看活动会话、下线单个会话、退出全部会话
+type SessionHandler struct {
+	sessionService *service.SessionService
+	userService    *service.UserService
+}
+
+// sessionResponse 会话及是否为当前请求所用的会话
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+func NewSessionHandler(sessionService *service.SessionService, userService *service.UserService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		userService:    userService,
+	}
+}
+
+// sessionList 获取用户的活动会话并标记当前会话
+func (h *SessionHandler) sessionList(c *gin.Context, userID uint) ([]sessionResponse, error) {
+	sessions, err := h.sessionService.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentID := ""
+	if current := auth.CurrentSession(c); current != nil {
+		currentID = current.ID
+	}
+	result := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, sessionResponse{Session: session, Current: session.ID == currentID})
+	}
+	return result, nil
+}
+
+// GetSessions 获取当前用户的活动会话
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	sessions, err := h.sessionList(c, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession 让当前用户的另一个会话下线，当前会话请使用退出登录
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	sessionID := c.Param("id")
+	if current := auth.CurrentSession(c); current != nil && current.ID == sessionID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot revoke the current session, log out instead"})
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(user.ID, sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeOtherSessions 退出当前用户在其他设备上的全部会话
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	current := auth.CurrentSession(c)
+	if user == nil || current == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	count, err := h.sessionService.RevokeUserSessions(user.ID, current.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revoked": count})
+}
+
+// GetUserSessions 管理员查看用户的活动会话
+func (h *SessionHandler) GetUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if _, err := h.userService.GetUserByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	sessions, err := h.sessionList(c, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSessions 管理员让用户在所有设备上退出登录
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	user, err := h.userService.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	count, err := h.sessionService.RevokeUserSessions(user.ID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAudit(c, audit.Entry{
+		Action:     audit.ActionSessionRevoke,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		After:      audit.Snapshot{"revoked_sessions": count},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"revoked": count})
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBundleSize 导入的任务定义包大小上限
+const maxBundleSize = 10 << 20
+
+// checkSlugAndEnv 在修改任务前检查请求中的 slug、环境变量、Python 依赖和共享库模块
+func (h *TaskHandler) checkSlugAndEnv(req *CreateTaskRequest, taskID uint) error {
+	if req.Slug != "" {
+		if err := h.taskService.CheckSlug(req.Slug, taskID); err != nil {
+			return err
+		}
+	}
+	if req.Requirements != nil {
+		if _, err := h.taskService.CheckRequirements(req.ScriptType, *req.Requirements); err != nil {
+			return err
+		}
+	}
+	if req.Libraries != nil {
+		if _, err := h.taskService.CheckLibraries(*req.Libraries); err != nil {
+			return err
+		}
+	}
+	return service.ValidateEnv(req.Env)
+}
+
+// applySlugAndEnv 写入请求中的 slug、环境变量、Python 依赖和共享库模块，未填写的字段保持不变
+func (h *TaskHandler) applySlugAndEnv(task *models.Task, req *CreateTaskRequest) error {
+	if req.Slug != "" {
+		if err := h.taskService.SetTaskSlug(task, req.Slug); err != nil {
+			return err
+		}
+	}
+	if req.Requirements != nil {
+		if err := h.taskService.SetTaskRequirements(task, *req.Requirements); err != nil {
+			return err
+		}
+	}
+	if req.Libraries != nil {
+		if err := h.taskService.SetTaskLibraries(task, *req.Libraries); err != nil {
+			return err
+		}
+	}
+	if req.Env != nil {
+		return h.taskService.SetTaskEnv(task, req.Env)
+	}
+	return nil
+}
+
+// ExportTasks 导出当前用户可访问的全部任务定义，format=yaml（默认）或 json
+func (h *TaskHandler) ExportTasks(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+		return
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	bundle, err := h.taskService.ExportTasks(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var data []byte
+	contentType := "application/json; charset=utf-8"
+	if format == "json" {
+		data, err = json.MarshalIndent(bundle, "", "  ")
+	} else {
+		contentType = "application/yaml; charset=utf-8"
+		data, err = yaml.Marshal(bundle)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("tasks_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ImportTasks 导入 YAML 或 JSON 格式的任务定义包，按 slug 匹配现有任务；
+// mode=merge（默认）只新增和更新，mode=replace 还会删除包中没有的任务，dry_run=true 时只返回变更计划
+func (h *TaskHandler) ImportTasks(c *gin.Context) {
+	mode := c.DefaultQuery("mode", service.ImportModeMerge)
+	dryRun := c.Query("dry_run") == "true" || c.Query("dry_run") == "1"
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read bundle: " + err.Error()})
+		return
+	}
+	bundle, err := service.ParseTaskBundle(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	plan, err := h.taskService.PlanImport(bundle, mode, scope)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plan.DryRun = dryRun
+	if dryRun {
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
+	failed := 0
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		if item.Action == service.ImportUnchanged {
+			continue
+		}
+
+		var before audit.Snapshot
+		if item.TaskID != 0 {
+			original, _ := h.taskService.GetTaskByID(item.TaskID)
+			before = h.taskSnapshot(original)
+		}
+
+		task, err := h.taskService.ApplyImportItem(item)
+		if err != nil {
+			item.Error = err.Error()
+			failed++
+		}
+		if task == nil || (item.Action == service.ImportDelete && err != nil) {
+			continue
+		}
+
+		switch item.Action {
+		case service.ImportCreate:
+			h.recordTaskRevision(c, task, "imported")
+			h.recordTaskAudit(c, audit.ActionTaskCreate, task, nil, h.taskSnapshot(task))
+		case service.ImportUpdate:
+			h.recordTaskRevision(c, task, "imported")
+			h.recordTaskAudit(c, audit.ActionTaskUpdate, task, before, h.taskSnapshot(task))
+		case service.ImportDelete:
+			h.recordTaskAudit(c, audit.ActionTaskDelete, task, before, nil)
+		}
+	}
+
+	if failed > 0 {
+		plan.Summary["failed"] = failed
+		plan.Error = fmt.Sprintf("%d tasks failed to import", failed)
+		c.JSON(http.StatusInternalServerError, plan)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// ImportCrontab 把 crontab 文本导入为任务；dry_run=true 时只返回可导入的任务和无法映射的行，
+// namespace_id 指定目标命名空间（默认 default），enabled=false 时导入后先不启用
+func (h *TaskHandler) ImportCrontab(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true" || c.Query("dry_run") == "1"
+	enabled := c.Query("enabled") != "false" && c.Query("enabled") != "0"
+
+	namespaceID, err := strconv.ParseUint(c.DefaultQuery("namespace_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid namespace ID"})
+		return
+	}
+	if namespaceID == 0 {
+		defaultID, err := database.DefaultNamespaceID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		namespaceID = uint64(defaultID)
+	}
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	if !scope.Contains(uint(namespaceID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "namespace not accessible"})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read crontab: " + err.Error()})
+		return
+	}
+
+	result := service.ParseCrontab(string(data))
+	result.DryRun = dryRun
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	failed := 0
+	for i := range result.Entries {
+		entry := &result.Entries[i]
+		task, err := h.taskService.ImportCrontabEntry(entry, uint(namespaceID), enabled)
+		if err != nil {
+			entry.Error = err.Error()
+			failed++
+		}
+		if task == nil {
+			continue
+		}
+		h.recordTaskRevision(c, task, fmt.Sprintf("imported from crontab line %d", entry.Line))
+		h.recordTaskAudit(c, audit.ActionTaskCreate, task, nil, h.taskSnapshot(task))
+	}
+
+	if failed > 0 {
+		result.Error = fmt.Sprintf("%d tasks failed to import", failed)
+		c.JSON(http.StatusInternalServerError, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
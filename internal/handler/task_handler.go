@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
 	"b1cron/internal/database"
 	"b1cron/internal/models"
 	"b1cron/internal/service"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	jwt "github.com/appleboy/gin-jwt/v2"
@@ -14,17 +17,33 @@ import (
 )
 
 type TaskHandler struct {
-	taskService *service.TaskService
+	taskService      *service.TaskService
+	namespaceService *service.NamespaceService
+	auditService     *service.AuditService
 }
 
 type CreateTaskRequest struct {
-	Name         string `json:"name" binding:"required"`
-	Command      string `json:"command" binding:"required"`
-	ScriptType   string `json:"script_type"`
-	ScheduleSpec string `json:"schedule_spec"`
-	ScheduleType string `json:"schedule_type"`
-	ExecuteAt    string `json:"execute_at"`    // RFC3339 format datetime string
-	IsEnabled    bool   `json:"is_enabled"`
+	Name         string         `json:"name" binding:"required"`
+	Command      string         `json:"command" binding:"required"`
+	ScriptType   string         `json:"script_type"`
+	ScheduleSpec string         `json:"schedule_spec"`
+	ScheduleType string         `json:"schedule_type"`
+	ExecuteAt    string         `json:"execute_at"` // RFC3339 format datetime string
+	IsEnabled    bool           `json:"is_enabled"`
+	NamespaceID  uint           `json:"namespace_id"` // 0 = default namespace, only used when creating
+	Slug         string         `json:"slug"`         // empty = generated from the name, or unchanged when updating
+	Env          models.EnvVars `json:"env"`          // nil = unchanged, {} clears all variables
+	Requirements *string        `json:"requirements"` // pip requirements of python tasks; nil = unchanged
+	Libraries    *[]string      `json:"libraries"`    // shared library modules, "name" or "name@version"; nil = unchanged
+}
+
+type MoveTaskRequest struct {
+	NamespaceID uint `json:"namespace_id" binding:"required"`
+}
+
+type UpdateRetentionRequest struct {
+	RetentionDays    int `json:"retention_days"`
+	RetentionMaxRows int `json:"retention_max_rows"`
 }
 
 type ChangePasswordRequest struct {
@@ -32,10 +51,79 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
 
-func NewTaskHandler(taskService *service.TaskService) *TaskHandler {
+func NewTaskHandler(taskService *service.TaskService, namespaceService *service.NamespaceService, auditService *service.AuditService) *TaskHandler {
 	return &TaskHandler{
-		taskService: taskService,
+		taskService:      taskService,
+		namespaceService: namespaceService,
+		auditService:     auditService,
+	}
+}
+
+// taskSnapshot 获取任务当前的审计快照，任务不存在时返回 nil
+func (h *TaskHandler) taskSnapshot(task *models.Task) audit.Snapshot {
+	if task == nil {
+		return nil
+	}
+	return audit.TaskSnapshot(task, h.taskService.GetTaskScriptContent(task))
+}
+
+// recordTaskAudit 记录任务操作的审计日志
+func (h *TaskHandler) recordTaskAudit(c *gin.Context, action string, task *models.Task, before, after audit.Snapshot) {
+	recordAudit(c, audit.Entry{
+		Action:     action,
+		TargetType: audit.TargetTask,
+		TargetID:   task.ID,
+		TargetName: task.Name,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// namespaceScope 获取当前用户可访问的命名空间范围
+func (h *TaskHandler) namespaceScope(c *gin.Context) (service.NamespaceScope, bool) {
+	scope, err := h.namespaceService.ScopeFor(auth.CurrentUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return scope, false
+	}
+	return scope, true
+}
+
+// scopedTaskID 解析路由中的任务ID，并确认任务在当前用户的命名空间内
+func (h *TaskHandler) scopedTaskID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return 0, false
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return 0, false
+	}
+	if _, err := h.taskService.GetTaskInScope(uint(id), scope); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writableTaskID 与 scopedTaskID 相同，但由 GitOps 清单管理的任务只读，返回 409
+func (h *TaskHandler) writableTaskID(c *gin.Context) (uint, bool) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return 0, false
 	}
+	task, err := h.taskService.GetTaskByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return 0, false
+	}
+	if task.Manifest != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "task is managed by GitOps manifest " + task.Manifest + " and is read-only"})
+		return 0, false
+	}
+	return id, true
 }
 
 func (h *TaskHandler) ShowDashboard(c *gin.Context) {
@@ -46,20 +134,32 @@ func (h *TaskHandler) ShowDashboard(c *gin.Context) {
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
-	
+
 	var user models.User
 	if err := database.GetDB().Where("username = ?", username).First(&user).Error; err != nil {
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
-	
+
 	if user.ForcePasswordChange {
 		// 如果需要强制修改密码，重定向到登录页面
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
-	
-	tasks, err := h.taskService.GetAllTasks()
+
+	scope, err := h.namespaceService.ScopeFor(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tasks, err := h.taskService.GetAllTasks(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespaces, err := h.namespaceService.ListNamespaces(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -76,14 +176,14 @@ func (h *TaskHandler) ShowDashboard(c *gin.Context) {
 	}
 
 	// 获取执行统计
-	executionStats, err := h.taskService.GetExecutionStats()
+	executionStats, err := h.taskService.GetExecutionStats(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// 获取最近执行记录
-	recentExecutions, err := h.taskService.GetRecentExecutions(20)
+	recentExecutions, err := h.taskService.GetRecentExecutions(scope, 20)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -91,12 +191,15 @@ func (h *TaskHandler) ShowDashboard(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "dashboard.html", gin.H{
 		"title":            "Dashboard",
+		"currentUser":      &user,
+		"namespaces":       namespaces,
 		"tasks":            tasks,
 		"totalTasks":       len(tasks),
 		"enabledTasks":     enabledCount,
 		"disabledTasks":    disabledCount,
 		"executionStats":   executionStats,
 		"recentExecutions": recentExecutions,
+		"runtimes":         h.taskService.ScriptRuntimes(),
 	})
 }
 
@@ -114,6 +217,10 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 	if req.ScheduleType == "" {
 		req.ScheduleType = "cron"
 	}
+	if err := h.taskService.ValidateScriptType(req.ScriptType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 解析执行时间（如果是一次性任务）
 	var executeAt *time.Time
@@ -122,7 +229,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "execute_at is required for one-time tasks"})
 			return
 		}
-		
+
 		parsedTime, err := time.ParseInLocation("2006-01-02T15:04", req.ExecuteAt, time.Local)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid execute_at format, expected YYYY-MM-DDTHH:MM"})
@@ -137,11 +244,44 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		}
 	}
 
-	task, err := h.taskService.CreateTaskFull(req.Name, req.Command, req.ScriptType, req.ScheduleSpec, req.ScheduleType, executeAt, req.IsEnabled)
+	// 未指定命名空间时使用默认命名空间
+	if req.NamespaceID == 0 {
+		namespaceID, err := database.DefaultNamespaceID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		req.NamespaceID = namespaceID
+	}
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	if !scope.Contains(req.NamespaceID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "namespace not accessible"})
+		return
+	}
+
+	if err := h.checkSlugAndEnv(&req, 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.taskService.CreateTaskFull(req.Name, req.Command, req.ScriptType, req.ScheduleSpec, req.ScheduleType, executeAt, req.IsEnabled, req.NamespaceID)
+	if respondScriptCheckError(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.applySlugAndEnv(task, &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	task.Namespace, _ = h.namespaceService.GetNamespaceByID(task.NamespaceID)
+	h.recordTaskRevision(c, task, "created")
+	h.recordTaskAudit(c, audit.ActionTaskCreate, task, nil, h.taskSnapshot(task))
 
 	if c.GetHeader("HX-Request") == "true" {
 		c.HTML(http.StatusOK, "_task_row.html", task)
@@ -152,7 +292,12 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 }
 
 func (h *TaskHandler) GetTasks(c *gin.Context) {
-	tasks, err := h.taskService.GetAllTasks()
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+
+	tasks, err := h.taskService.GetAllTasks(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -162,13 +307,12 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 }
 
 func (h *TaskHandler) GetTask(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+	id, ok := h.scopedTaskID(c)
+	if !ok {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(uint(id))
+	task, err := h.taskService.GetTaskByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
@@ -176,24 +320,33 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 
 	// 如果是脚本类型，获取脚本内容
 	taskData := map[string]interface{}{
-		"id":            task.ID,
-		"name":          task.Name,
-		"command":       h.taskService.GetTaskScriptContent(task),
-		"script_type":   task.ScriptType,
-		"script_path":   task.ScriptPath,
-		"schedule_spec": task.ScheduleSpec,
-		"is_enabled":    task.IsEnabled,
-		"created_at":    task.CreatedAt,
-		"updated_at":    task.UpdatedAt,
+		"id":                 task.ID,
+		"name":               task.Name,
+		"slug":               task.Slug,
+		"command":            h.taskService.GetTaskScriptContent(task),
+		"script_type":        task.ScriptType,
+		"script_path":        task.ScriptPath,
+		"script_checksum":    task.ScriptChecksum,
+		"schedule_spec":      task.ScheduleSpec,
+		"is_enabled":         task.IsEnabled,
+		"namespace_id":       task.NamespaceID,
+		"current_revision":   task.CurrentRevision,
+		"retention_days":     task.RetentionDays,
+		"retention_max_rows": task.RetentionMaxRows,
+		"env":                task.Env,
+		"requirements":       task.Requirements,
+		"libraries":          task.Libraries,
+		"manifest":           task.Manifest,
+		"created_at":         task.CreatedAt,
+		"updated_at":         task.UpdatedAt,
 	}
 
 	c.JSON(http.StatusOK, taskData)
 }
 
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+	id, ok := h.writableTaskID(c)
+	if !ok {
 		return
 	}
 
@@ -210,6 +363,10 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	if req.ScheduleType == "" {
 		req.ScheduleType = "cron"
 	}
+	if err := h.taskService.ValidateScriptType(req.ScriptType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 解析执行时间（如果是一次性任务）
 	var executeAt *time.Time
@@ -218,7 +375,7 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "execute_at is required for one-time tasks"})
 			return
 		}
-		
+
 		parsedTime, err := time.ParseInLocation("2006-01-02T15:04", req.ExecuteAt, time.Local)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid execute_at format, expected YYYY-MM-DDTHH:MM"})
@@ -233,26 +390,50 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		}
 	}
 
-	task, err := h.taskService.UpdateTaskFull(uint(id), req.Name, req.Command, req.ScriptType, req.ScheduleSpec, req.ScheduleType, executeAt, req.IsEnabled)
+	if err := h.checkSlugAndEnv(&req, id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	original, _ := h.taskService.GetTaskByID(id)
+	before := h.taskSnapshot(original)
+
+	task, err := h.taskService.UpdateTaskFull(id, req.Name, req.Command, req.ScriptType, req.ScheduleSpec, req.ScheduleType, executeAt, req.IsEnabled)
+	if respondScriptCheckError(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.applySlugAndEnv(task, &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTaskRevision(c, task, "")
+	h.recordTaskAudit(c, audit.ActionTaskUpdate, task, before, h.taskSnapshot(task))
 
 	c.JSON(http.StatusOK, task)
 }
 
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	id, ok := h.writableTaskID(c)
+	if !ok {
+		return
+	}
+
+	original, err := h.taskService.GetTaskByID(id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
+	before := h.taskSnapshot(original)
 
-	if err := h.taskService.DeleteTask(uint(id)); err != nil {
+	if err := h.taskService.DeleteTask(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.recordTaskAudit(c, audit.ActionTaskDelete, original, before, nil)
 
 	if c.GetHeader("HX-Request") == "true" {
 		c.Status(http.StatusOK)
@@ -263,17 +444,21 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 }
 
 func (h *TaskHandler) ToggleTask(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+	id, ok := h.writableTaskID(c)
+	if !ok {
 		return
 	}
 
-	task, err := h.taskService.ToggleTask(uint(id))
+	original, _ := h.taskService.GetTaskByID(id)
+	before := h.taskSnapshot(original)
+
+	task, err := h.taskService.ToggleTask(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.recordTaskAudit(c, audit.ActionTaskToggle, task, before, h.taskSnapshot(task))
+	task.Namespace, _ = h.namespaceService.GetNamespaceByID(task.NamespaceID)
 
 	if c.GetHeader("HX-Request") == "true" {
 		c.HTML(http.StatusOK, "_task_row.html", task)
@@ -283,6 +468,117 @@ func (h *TaskHandler) ToggleTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
+func (h *TaskHandler) GetTaskStats(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.taskService.GetTaskStats(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetTasksStats 批量获取任务统计，ids 为逗号分隔的任务ID，供任务列表一次加载全部趋势图
+func (h *TaskHandler) GetTasksStats(c *gin.Context) {
+	var ids []uint
+	for _, part := range strings.Split(c.Query("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID: " + part})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+	stats, err := h.taskService.GetTasksStats(ids, scope)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *TaskHandler) UpdateTaskRetention(c *gin.Context) {
+	id, ok := h.writableTaskID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	original, _ := h.taskService.GetTaskByID(id)
+	before := h.taskSnapshot(original)
+
+	task, err := h.taskService.UpdateTaskRetention(id, req.RetentionDays, req.RetentionMaxRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTaskAudit(c, audit.ActionTaskUpdate, task, before, h.taskSnapshot(task))
+
+	c.JSON(http.StatusOK, task)
+}
+
+// MoveTask 把任务移动到另一个命名空间
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	id, ok := h.writableTaskID(c)
+	if !ok {
+		return
+	}
+
+	var req MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	original, _ := h.taskService.GetTaskByID(id)
+	before := h.taskSnapshot(original)
+
+	task, err := h.taskService.MoveTask(id, req.NamespaceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTaskAudit(c, audit.ActionTaskUpdate, task, before, h.taskSnapshot(task))
+
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) RunTask(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.RunTaskNow(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	h.recordTaskAudit(c, audit.ActionTaskRun, task, nil, nil)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Task started", "task_id": task.ID})
+}
+
 func (h *TaskHandler) ChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -328,13 +624,51 @@ func (h *TaskHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 其他设备上的会话立即失效，当前会话保持登录
+	keepSessionID := ""
+	if session := auth.CurrentSession(c); session != nil {
+		keepSessionID = session.ID
+	}
+	if err := database.GetDB().Where("user_id = ? AND id <> ?", user.ID, keepSessionID).Delete(&models.Session{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke other sessions"})
+		return
+	}
+
+	recordAudit(c, audit.Entry{
+		Action:     audit.ActionPasswordChange,
+		Actor:      &user,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
-func (h *TaskHandler) GetTaskExecutions(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+// GetTaskAudit 获取任务的审计日志
+func (h *TaskHandler) GetTaskAudit(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	filter := service.AuditFilter{TargetType: audit.TargetTask, TargetID: &id}
+	page, pageSize := parsePagination(c)
+	logs, total, err := h.auditService.QueryAuditLogs(filter, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":       logs,
+		"pagination": paginationResponse(page, pageSize, total),
+	})
+}
+
+func (h *TaskHandler) GetTaskExecutions(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
 		return
 	}
 
@@ -345,7 +679,7 @@ func (h *TaskHandler) GetTaskExecutions(c *gin.Context) {
 		}
 	}
 
-	executions, err := h.taskService.GetTaskExecutions(uint(id), limit)
+	executions, err := h.taskService.GetTaskExecutions(id, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -356,11 +690,20 @@ func (h *TaskHandler) GetTaskExecutions(c *gin.Context) {
 
 func (h *TaskHandler) GetRecentExecutions(c *gin.Context) {
 	// 检查是否使用新的分页API
-	search := c.Query("search")
 	pageStr := c.Query("page")
 	pageSizeStr := c.Query("page_size")
-	
-	if search != "" || pageStr != "" || pageSizeStr != "" {
+
+	if hasExecutionFilter(c) {
+		filter, err := parseExecutionFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var ok bool
+		if filter.Scope, ok = h.namespaceScope(c); !ok {
+			return
+		}
+
 		// 使用新的分页API
 		page := 1
 		if pageStr != "" {
@@ -368,23 +711,23 @@ func (h *TaskHandler) GetRecentExecutions(c *gin.Context) {
 				page = parsedPage
 			}
 		}
-		
+
 		pageSize := 20
 		if pageSizeStr != "" {
 			if parsedPageSize, err := strconv.Atoi(pageSizeStr); err == nil && parsedPageSize > 0 && parsedPageSize <= 100 {
 				pageSize = parsedPageSize
 			}
 		}
-		
-		executions, total, err := h.taskService.GetRecentExecutionsWithPagination(search, page, pageSize)
+
+		executions, total, err := h.taskService.QueryExecutions(filter, page, pageSize)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		
+
 		// 计算分页信息
 		totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
-		
+
 		c.JSON(http.StatusOK, gin.H{
 			"executions": executions,
 			"pagination": gin.H{
@@ -398,7 +741,7 @@ func (h *TaskHandler) GetRecentExecutions(c *gin.Context) {
 		})
 		return
 	}
-	
+
 	// 保持向后兼容的旧API
 	limit := 20 // 默认限制20条记录
 	if limitParam := c.Query("limit"); limitParam != "" {
@@ -407,11 +750,16 @@ func (h *TaskHandler) GetRecentExecutions(c *gin.Context) {
 		}
 	}
 
-	executions, err := h.taskService.GetRecentExecutions(limit)
+	scope, ok := h.namespaceScope(c)
+	if !ok {
+		return
+	}
+
+	executions, err := h.taskService.GetRecentExecutions(scope, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, executions)
-}
\ No newline at end of file
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordTaskRevision 记录任务的新版本，失败只记日志，不影响已完成的修改
+func (h *TaskHandler) recordTaskRevision(c *gin.Context, task *models.Task, note string) {
+	if _, err := h.taskService.RecordRevision(task, h.taskService.GetTaskScriptContent(task), auth.CurrentUser(c), note); err != nil {
+		log.Printf("Failed to record revision for task %d: %v", task.ID, err)
+	}
+}
+
+// parseRevision 解析版本号参数
+func parseRevision(value string) (int, bool) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, false
+	}
+	return revision, true
+}
+
+// GetTaskRevisions 获取任务的版本列表
+func (h *TaskHandler) GetTaskRevisions(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.taskService.ListRevisions(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetTaskRevision 获取任务的某个版本
+func (h *TaskHandler) GetTaskRevision(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	revision, ok := parseRevision(c.Param("rev"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	rev, err := h.taskService.GetRevision(id, revision)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffTaskRevisions 比较任务的两个版本
+func (h *TaskHandler) DiffTaskRevisions(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+
+	from, okFrom := parseRevision(c.Query("from"))
+	to, okTo := parseRevision(c.Query("to"))
+	if !okFrom || !okTo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be revision numbers"})
+		return
+	}
+
+	diff, err := h.taskService.DiffRevisions(id, from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestoreTaskRevision 将任务恢复到指定版本
+func (h *TaskHandler) RestoreTaskRevision(c *gin.Context) {
+	id, ok := h.writableTaskID(c)
+	if !ok {
+		return
+	}
+
+	revision, ok := parseRevision(c.Param("rev"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+	if _, err := h.taskService.GetRevision(id, revision); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	original, _ := h.taskService.GetTaskByID(id)
+	before := h.taskSnapshot(original)
+
+	task, err := h.taskService.RestoreRevision(id, revision, auth.CurrentUser(c))
+	if respondScriptCheckError(c, err) {
+		return
+	}
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		// 例如一次性任务的执行时间已经过去，或者运行时已从配置中移除
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTaskAudit(c, audit.ActionTaskUpdate, task, before, h.taskSnapshot(task))
+
+	c.JSON(http.StatusOK, task)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler 两步验证处理器
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+	userService      *service.UserService
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService, userService *service.UserService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+		userService:      userService,
+	}
+}
+
+// recordTwoFactorAudit 记录两步验证开启或关闭的审计日志
+func (h *TwoFactorHandler) recordTwoFactorAudit(c *gin.Context, action string, user *models.User) {
+	recordAudit(c, audit.Entry{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+	})
+}
+
+// GetStatus 获取当前用户的两步验证状态
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	remaining, err := h.twoFactorService.RemainingRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// BeginSetup 生成密钥和二维码，开始开通两步验证
+func (h *TwoFactorHandler) BeginSetup(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	setup, err := h.twoFactorService.BeginSetup(user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// Enable 校验动态码后开启两步验证，返回恢复码
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTwoFactorAudit(c, audit.ActionTOTPEnable, user)
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable 关闭当前用户的两步验证
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(user, req.Password, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTwoFactorAudit(c, audit.ActionTOTPDisable, user)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// ResetUser 管理员为丢失认证器的用户关闭两步验证
+func (h *TwoFactorHandler) ResetUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userService.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.twoFactorService.Reset(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordTwoFactorAudit(c, audit.ActionTOTPDisable, user)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"b1cron/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler 用户管理处理器
+type UserHandler struct {
+	userService      *service.UserService
+	namespaceService *service.NamespaceService
+	loginGuard       *auth.LoginGuard
+}
+
+type CreateUserRequest struct {
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required,min=6"`
+	Role         string `json:"role" binding:"required"`
+	NamespaceIDs []uint `json:"namespace_ids"` // 为空时加入默认命名空间
+}
+
+type UpdateUserRequest struct {
+	Role         string  `json:"role"`
+	Password     string  `json:"password" binding:"omitempty,min=6"`
+	NamespaceIDs *[]uint `json:"namespace_ids"` // 非空时覆盖用户所属的命名空间
+}
+
+func NewUserHandler(userService *service.UserService, namespaceService *service.NamespaceService, loginGuard *auth.LoginGuard) *UserHandler {
+	return &UserHandler{
+		userService:      userService,
+		namespaceService: namespaceService,
+		loginGuard:       loginGuard,
+	}
+}
+
+// userSnapshot 获取用户账户的审计快照
+func (h *UserHandler) userSnapshot(user *models.User) audit.Snapshot {
+	namespaceIDs, err := h.namespaceService.UserNamespaceIDs(user.ID)
+	if err != nil {
+		namespaceIDs = nil
+	}
+	return audit.UserSnapshot(user, namespaceIDs)
+}
+
+// recordUserAudit 记录用户管理操作的审计日志
+func (h *UserHandler) recordUserAudit(c *gin.Context, action string, user *models.User, before, after audit.Snapshot) {
+	recordAudit(c, audit.Entry{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		TargetName: user.Username,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// GetCurrentUser 获取当前登录用户信息（含角色），供前端显示对应的操作
+func (h *UserHandler) GetCurrentUser(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	users, err := h.userService.ListUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	namespaceIDs := req.NamespaceIDs
+	if len(namespaceIDs) == 0 {
+		defaultID, err := database.DefaultNamespaceID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		namespaceIDs = []uint{defaultID}
+	}
+
+	user, err := h.userService.CreateUser(req.Username, req.Password, req.Role, namespaceIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.recordUserAudit(c, audit.ActionUserCreate, user, nil, h.userSnapshot(user))
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser 修改用户角色和/或重置密码
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	original, err := h.userService.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	before := h.userSnapshot(original)
+
+	// 全部修改在同一个事务中写入，失败时不会留下未审计的部分修改
+	user, err := h.userService.UpdateUser(uint(id), service.UserUpdate{
+		Role:         req.Role,
+		Password:     req.Password,
+		NamespaceIDs: req.NamespaceIDs,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	after := h.userSnapshot(user)
+	if req.Password != "" {
+		// 只记录密码被重置，不记录密码本身
+		after["password_reset"] = true
+	}
+	h.recordUserAudit(c, audit.ActionUserUpdate, user, before, after)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UnlockUser 解除账户因多次登录失败造成的锁定
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.userService.UnlockUser(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.loginGuard.Reset(user.Username)
+	h.recordUserAudit(c, audit.ActionUserUnlock, user, nil, nil)
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	currentUser := auth.CurrentUser(c)
+	if currentUser == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return
+	}
+
+	original, err := h.userService.GetUserByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	before := h.userSnapshot(original)
+
+	if err := h.userService.DeleteUser(uint(id), currentUser.ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.recordUserAudit(c, audit.ActionUserDelete, original, before, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"b1cron/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskVenv 获取 Python 任务的虚拟环境状态和构建日志
+func (h *TaskHandler) GetTaskVenv(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+	task, err := h.taskService.GetTaskByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	status := h.taskService.GetTaskVenv(task)
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task has no requirements"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RebuildTaskVenv 在后台重新构建任务的虚拟环境
+func (h *TaskHandler) RebuildTaskVenv(c *gin.Context) {
+	id, ok := h.scopedTaskID(c)
+	if !ok {
+		return
+	}
+	task, err := h.taskService.GetTaskByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	if err := h.taskService.RebuildTaskVenv(task); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrVenvBuilding) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, h.taskService.GetTaskVenv(task))
+}
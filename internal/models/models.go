@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,40 +11,244 @@ import (
 )
 
 type User struct {
-	ID                  uint   `gorm:"primaryKey" json:"id"`
-	Username            string `gorm:"uniqueIndex;not null" json:"username"`
-	PasswordHash        string `gorm:"not null" json:"-"`
-	ForcePasswordChange bool   `gorm:"default:false" json:"force_password_change"`
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	Username            string     `gorm:"uniqueIndex;not null" json:"username"`
+	PasswordHash        string     `gorm:"not null" json:"-"`
+	ForcePasswordChange bool       `gorm:"default:false" json:"force_password_change"`
+	Role                string     `gorm:"default:'viewer';not null" json:"role"` // admin, operator, viewer
+	FailedLogins        int        `gorm:"default:0" json:"failed_logins"`        // consecutive failed logins since the last success
+	LockedUntil         *time.Time `json:"locked_until"`                          // login is refused until this time
+	TOTPSecret          string     `json:"-"`                                     // base32 TOTP secret, set during enrollment
+	TOTPEnabled         bool       `gorm:"default:false" json:"totp_enabled"`     // login requires a TOTP or recovery code
+	TOTPLastStep        int64      `json:"-"`                                     // last accepted TOTP time step, prevents code replay
+	AuthProvider        string     `gorm:"default:'local'" json:"auth_provider"`  // local, oidc
+	ExternalID          string     `gorm:"index" json:"-"`                        // subject at the identity provider for SSO users
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
 	DeletedAt           gorm.DeletedAt `gorm:"index"`
 }
 
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost;
+// only the SHA-256 hash is stored
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// APIToken is a personal access token for the API; only the SHA-256 hash of the token is stored
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"` // leading characters of the token, for display
+	TokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     string     `gorm:"not null" json:"scopes"` // comma separated: read, run, write
+	ExpiresAt  *time.Time `json:"expires_at"`             // nil = never expires
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Session is a login session; every JWT carries the session ID and is rejected once the
+// session is deleted (logout, password change, admin revocation or user deletion)
+type Session struct {
+	ID         string    `gorm:"primaryKey;type:char(36)" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	AuthMethod string    `json:"auth_method"` // password, oidc
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `gorm:"index" json:"expires_at"`
+}
+
 type Task struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Name         string    `gorm:"not null" json:"name"`
-	Command      string    `json:"command"`
-	ScriptType   string    `gorm:"default:'command'" json:"script_type"` // command, shell, python
-	ScriptPath   string    `json:"script_path"`                          // relative path to script file
-	ScheduleSpec string    `gorm:"not null" json:"schedule_spec"`
-	ScheduleType string    `gorm:"default:'cron'" json:"schedule_type"`  // cron, once, range, dynamic
-	ExecuteAt    *time.Time `json:"execute_at"`                         // for one-time execution
-	IsEnabled    bool      `gorm:"default:true" json:"is_enabled"`
-	GocronJobID  uuid.UUID `gorm:"type:char(36)" json:"gocron_job_id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"not null" json:"name"`
+	Slug             string         `gorm:"index" json:"slug"`                    // stable bundle identifier
+	Command          string         `json:"command"`                              // shell command, or runtime command for display
+	ScriptType       string         `gorm:"default:'command'" json:"script_type"` // command or a runtime name
+	ScriptPath       string         `json:"script_path"`                          // legacy script file, empty once migrated
+	ScriptContent    string         `gorm:"type:text" json:"-"`                   // full script including header
+	ScriptChecksum   string         `json:"script_checksum"`                      // SHA-256 of ScriptContent
+	ScheduleSpec     string         `gorm:"not null" json:"schedule_spec"`
+	ScheduleType     string         `gorm:"default:'cron'" json:"schedule_type"` // cron, once, range, dynamic
+	ExecuteAt        *time.Time     `json:"execute_at"`                          // for one-time execution
+	IsEnabled        bool           `gorm:"default:true" json:"is_enabled"`
+	GocronJobID      uuid.UUID      `gorm:"type:char(36)" json:"gocron_job_id"`
+	NamespaceID      uint           `gorm:"not null;default:0;index" json:"namespace_id"`
+	CurrentRevision  int            `gorm:"default:0" json:"current_revision"` // latest TaskRevision.Revision
+	Namespace        *Namespace     `gorm:"foreignKey:NamespaceID;-:migration" json:"namespace,omitempty"`
+	RetentionDays    int            `gorm:"default:0" json:"retention_days"`     // 0 = global policy
+	RetentionMaxRows int            `gorm:"default:0" json:"retention_max_rows"` // 0 = global policy
+	Env              EnvVars        `gorm:"type:text" json:"env"`                // extra environment variables
+	Requirements     string         `gorm:"type:text" json:"requirements"`       // pip requirements, one per line
+	Libraries        StringList     `gorm:"type:text" json:"libraries"`          // "name" or "name@version"
+	Manifest         string         `json:"manifest"`                            // managing GitOps manifest, empty = unmanaged
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-type TaskExecution struct {
+// EnvVars holds a task's environment variables, stored as a JSON object.
+type EnvVars map[string]string
+
+// Value implements driver.Valuer.
+func (e EnvVars) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]string(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (e *EnvVars) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported env value type %T", value)
+	}
+	if len(data) == 0 {
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*map[string]string)(e))
+}
+
+// StringList holds a list of strings, stored as a JSON array.
+type StringList []string
+
+// Value implements driver.Valuer.
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported string list value type %T", value)
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// TaskRevision is an immutable version of a task's script and schedule.
+// Revision numbers are assigned inside a transaction; the index is not declared
+// unique because the sqlite migrator would then rebuild the table on every start.
+type TaskRevision struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	TaskID       uint       `gorm:"not null;index:idx_task_revision" json:"task_id"`
+	Revision     int        `gorm:"not null;index:idx_task_revision" json:"revision"` // 1-based, per task
+	Name         string     `json:"name"`
+	ScriptType   string     `json:"script_type"`
+	Content      string     `gorm:"type:text" json:"content"` // script content, or the command for command tasks
+	ScheduleSpec string     `json:"schedule_spec"`
+	ScheduleType string     `json:"schedule_type"`
+	ExecuteAt    *time.Time `json:"execute_at"`
+	AuthorID     *uint      `json:"author_id"` // nil for revisions created by the system
+	AuthorName   string     `json:"author_name"`
+	Note         string     `json:"note"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// LibraryModule is a shared shell or python module that tasks include by name;
+// Content is the latest version, earlier versions are kept in LibraryModuleVersion
+type LibraryModule struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	TaskID      uint      `gorm:"not null;index:idx_task_started" json:"task_id"`
-	Task        Task      `gorm:"foreignKey:TaskID" json:"task,omitempty"`
-	Status      string    `gorm:"not null;index" json:"status"` // success, failed, running
-	StartedAt   time.Time `gorm:"not null;index:idx_task_started" json:"started_at"`
-	CompletedAt *time.Time `json:"completed_at"`
-	Duration    int64     `json:"duration"` // milliseconds
-	Output      string    `gorm:"type:text" json:"output"`
-	ErrorMsg    string    `gorm:"type:text" json:"error_msg"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"` // file name without extension: <name>.sh or <name>.py
+	Language    string    `gorm:"not null" json:"language"`         // shell, python
+	Description string    `json:"description"`
+	Content     string    `gorm:"type:text" json:"content"`
+	Version     int       `gorm:"default:0" json:"version"` // latest LibraryModuleVersion.Version
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LibraryModuleVersion is an immutable version of a library module
+type LibraryModuleVersion struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ModuleID   uint      `gorm:"not null;index:idx_module_version" json:"module_id"`
+	Version    int       `gorm:"not null;index:idx_module_version" json:"version"` // 1-based, per module
+	Content    string    `gorm:"type:text" json:"content"`
+	AuthorID   *uint     `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Namespace groups tasks; non-admin users only see tasks in namespaces they belong to
+type Namespace struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type NamespaceMember struct {
+	NamespaceID uint      `gorm:"primaryKey" json:"namespace_id"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
 	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+}
+
+type TaskExecution struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TaskID      uint       `gorm:"not null;index:idx_task_started" json:"task_id"`
+	Task        Task       `gorm:"foreignKey:TaskID" json:"task,omitempty"`
+	Status      string     `gorm:"not null;index" json:"status"` // success, failed, running
+	StartedAt   time.Time  `gorm:"not null;index:idx_task_started" json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Duration    int64      `json:"duration"`                  // milliseconds
+	ExitCode    *int       `gorm:"index" json:"exit_code"`    // nil if the process never exited normally
+	Revision    int        `gorm:"default:0" json:"revision"` // task revision that was executed, 0 = unknown
+	Output      string     `gorm:"type:text" json:"output"`
+	ErrorMsg    string     `gorm:"type:text" json:"error_msg"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AuditLog records who did what; Changes is a JSON object of field -> {"before": ..., "after": ...}
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Action     string    `gorm:"not null;index" json:"action"` // e.g. task.update, login.failure
+	ActorID    *uint     `gorm:"index" json:"actor_id"`        // nil when the actor is unknown (failed login)
+	ActorName  string    `gorm:"index" json:"actor_name"`
+	IP         string    `json:"ip"`
+	TargetType string    `gorm:"index:idx_audit_target" json:"target_type"` // task, user
+	TargetID   uint      `gorm:"index:idx_audit_target" json:"target_id"`
+	TargetName string    `json:"target_name"`
+	Changes    string    `gorm:"type:text" json:"changes"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
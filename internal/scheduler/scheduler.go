@@ -5,17 +5,35 @@ import (
 	"b1cron/internal/models"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
 )
 
+// TaskEnvironment 在执行任务前准备运行环境（例如 Python 虚拟环境），返回执行命令时使用的环境变量
+type TaskEnvironment interface {
+	Prepare(taskID uint, environ []string) ([]string, error)
+}
+
+// ScriptMaterializer 在执行任务前把数据库中的脚本和引用的共享库模块写入本次执行的临时目录，
+// 返回执行命令和加入共享库路径后的环境变量
+type ScriptMaterializer interface {
+	MaterializeScript(taskID uint, dir string, environ []string) (string, []string, error)
+}
+
 type SchedulerService struct {
-	scheduler gocron.Scheduler
+	scheduler   gocron.Scheduler
+	started     atomic.Bool
+	tasksLoaded atomic.Bool
+	environment TaskEnvironment
+	scripts     ScriptMaterializer
 }
 
 func NewSchedulerService() (*SchedulerService, error) {
@@ -29,21 +47,49 @@ func NewSchedulerService() (*SchedulerService, error) {
 	}, nil
 }
 
+// SetTaskEnvironment 设置执行任务前准备运行环境的服务，需要在 Start 之前调用
+func (s *SchedulerService) SetTaskEnvironment(environment TaskEnvironment) {
+	s.environment = environment
+}
+
+// SetScriptMaterializer 设置执行任务前生成脚本文件的服务，需要在 Start 之前调用
+func (s *SchedulerService) SetScriptMaterializer(scripts ScriptMaterializer) {
+	s.scripts = scripts
+}
+
 func (s *SchedulerService) Start() error {
 	s.scheduler.Start()
-	
+	s.started.Store(true)
+
 	if err := s.loadExistingTasks(); err != nil {
 		return fmt.Errorf("failed to load existing tasks: %w", err)
 	}
-	
+	s.tasksLoaded.Store(true)
+
 	log.Println("Scheduler service started and existing tasks loaded")
 	return nil
 }
 
 func (s *SchedulerService) Stop() error {
+	s.started.Store(false)
 	return s.scheduler.Shutdown()
 }
 
+// IsStarted 调度器是否已启动
+func (s *SchedulerService) IsStarted() bool {
+	return s.started.Load()
+}
+
+// TasksLoaded 启动时的已有任务是否已加载完成
+func (s *SchedulerService) TasksLoaded() bool {
+	return s.tasksLoaded.Load()
+}
+
+// JobCount 当前在gocron中注册的任务数量
+func (s *SchedulerService) JobCount() int {
+	return len(s.scheduler.Jobs())
+}
+
 func (s *SchedulerService) ScheduleTask(task *models.Task) (uuid.UUID, error) {
 	job, err := s.createJob(task)
 	if err != nil {
@@ -60,7 +106,7 @@ func (s *SchedulerService) UnscheduleTask(jobID uuid.UUID) error {
 	if err != nil {
 		return fmt.Errorf("failed to remove job %s: %w", jobID, err)
 	}
-	
+
 	log.Printf("Task with ID %s unscheduled", jobID)
 	return nil
 }
@@ -71,7 +117,7 @@ func (s *SchedulerService) UpdateTask(task *models.Task) (uuid.UUID, error) {
 			log.Printf("Warning: failed to unschedule old task: %v", err)
 		}
 	}
-	
+
 	return s.ScheduleTask(task)
 }
 
@@ -85,7 +131,7 @@ func (s *SchedulerService) createJob(task *models.Task) (gocron.Job, error) {
 		if task.ExecuteAt == nil {
 			return nil, fmt.Errorf("execute_at is required for one-time tasks")
 		}
-		
+
 		// 检查执行时间是否已过，如果已过则不调度
 		if task.ExecuteAt.Before(time.Now()) {
 			log.Printf("One-time task '%s' execution time has passed, skipping scheduling", task.Name)
@@ -95,7 +141,7 @@ func (s *SchedulerService) createJob(task *models.Task) (gocron.Job, error) {
 			}
 			return nil, fmt.Errorf("execution time has passed")
 		}
-		
+
 		return s.scheduler.NewJob(
 			gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(*task.ExecuteAt)),
 			gocron.NewTask(taskFunc),
@@ -121,98 +167,167 @@ func (s *SchedulerService) createJob(task *models.Task) (gocron.Job, error) {
 	)
 }
 
+// RunNow 立即在后台执行一次任务，不影响已有调度
+func (s *SchedulerService) RunNow(task *models.Task) {
+	go s.runTask(task)
+}
+
 func (s *SchedulerService) executeTask(task *models.Task) {
+	s.runTask(task)
+
+	// 如果是一次性任务，执行完成后自动禁用并从调度器中移除
+	if task.ScheduleType == "once" {
+		if err := database.GetDB().Model(task).Update("is_enabled", false).Error; err != nil {
+			log.Printf("Failed to disable one-time task %s: %v", task.Name, err)
+		} else {
+			log.Printf("One-time task '%s' completed and disabled", task.Name)
+		}
+
+		// 从调度器中移除任务
+		if task.GocronJobID != uuid.Nil {
+			if err := s.UnscheduleTask(task.GocronJobID); err != nil {
+				log.Printf("Failed to unschedule completed one-time task %s: %v", task.Name, err)
+			} else {
+				log.Printf("One-time task '%s' removed from scheduler", task.Name)
+			}
+		}
+	}
+}
+
+func (s *SchedulerService) runTask(task *models.Task) {
 	log.Printf("Executing task: %s (ID: %d)", task.Name, task.ID)
-	
+
 	// 检查命令是否为空
 	if task.Command == "" {
 		log.Printf("Task '%s' has no command to execute", task.Name)
 		return
 	}
-	
+
 	startTime := time.Now()
-	
+
+	// 记录本次执行的任务版本并读取环境变量（从数据库读取，调度时持有的任务副本可能较旧）
+	var current struct {
+		CurrentRevision int
+		Env             models.EnvVars
+	}
+	if err := database.GetDB().Model(&models.Task{}).Select("current_revision", "env").Where("id = ?", task.ID).Scan(&current).Error; err != nil {
+		log.Printf("Failed to get task revision: %v", err)
+	}
+	revision := current.CurrentRevision
+
 	// 创建执行记录
 	execution := &models.TaskExecution{
 		TaskID:    task.ID,
 		Status:    "running",
 		StartedAt: startTime,
+		Revision:  revision,
 	}
-	
+
 	// 保存执行记录到数据库
 	if err := database.GetDB().Create(execution).Error; err != nil {
 		log.Printf("Failed to create execution record: %v", err)
 	}
-	
+
+	// 脚本只保存在数据库中，每次执行前写入单独的临时目录，执行结束后删除
+	command := task.Command
+	environ := taskEnviron(current.Env)
+	if s.scripts != nil {
+		dir, err := os.MkdirTemp("", fmt.Sprintf("b1cron-task-%d-", task.ID))
+		if err != nil {
+			s.failExecution(task, execution, startTime, fmt.Errorf("failed to create script directory: %w", err))
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		if command, environ, err = s.scripts.MaterializeScript(task.ID, dir, environ); err != nil {
+			s.failExecution(task, execution, startTime, err)
+			return
+		}
+	}
+
 	// 执行命令 - 统一通过shell执行以支持重定向、管道等操作
 	var cmd *exec.Cmd
-	
+
 	// 检测操作系统并使用相应的shell
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", task.Command)
+		cmd = exec.Command("cmd", "/C", command)
 	} else {
-		cmd = exec.Command("/bin/sh", "-c", task.Command)
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+	cmd.Env = environ
+
+	// 准备运行环境，失败时不执行命令
+	if s.environment != nil {
+		environ, err := s.environment.Prepare(task.ID, cmd.Env)
+		if err != nil {
+			s.failExecution(task, execution, startTime, err)
+			return
+		}
+		cmd.Env = environ
 	}
-	
+
 	output, err := cmd.CombinedOutput()
 	completedAt := time.Now()
 	duration := completedAt.Sub(startTime)
-	
+
 	// 更新执行记录
 	execution.CompletedAt = &completedAt
 	execution.Duration = duration.Milliseconds()
 	execution.Output = string(output)
-	
+
+	// 记录退出码
+	if cmd.ProcessState != nil {
+		exitCode := cmd.ProcessState.ExitCode()
+		if exitCode >= 0 {
+			execution.ExitCode = &exitCode
+		}
+	}
+
 	if err != nil {
 		execution.Status = "failed"
 		execution.ErrorMsg = err.Error()
-		log.Printf("Task '%s' failed after %v: %v\nOutput: %s", 
+		log.Printf("Task '%s' failed after %v: %v\nOutput: %s",
 			task.Name, duration, err, string(output))
 	} else {
 		execution.Status = "success"
-		log.Printf("Task '%s' completed successfully in %v\nOutput: %s", 
+		log.Printf("Task '%s' completed successfully in %v\nOutput: %s",
 			task.Name, duration, string(output))
 	}
-	
+
 	// 保存更新后的执行记录
 	if err := database.GetDB().Save(execution).Error; err != nil {
 		log.Printf("Failed to update execution record: %v", err)
 	}
+}
 
-	// 如果是一次性任务，执行完成后自动禁用并从调度器中移除
-	if task.ScheduleType == "once" {
-		if err := database.GetDB().Model(task).Update("is_enabled", false).Error; err != nil {
-			log.Printf("Failed to disable one-time task %s: %v", task.Name, err)
-		} else {
-			log.Printf("One-time task '%s' completed and disabled", task.Name)
-		}
-		
-		// 从调度器中移除任务
-		if task.GocronJobID != uuid.Nil {
-			if err := s.UnscheduleTask(task.GocronJobID); err != nil {
-				log.Printf("Failed to unschedule completed one-time task %s: %v", task.Name, err)
-			} else {
-				log.Printf("One-time task '%s' removed from scheduler", task.Name)
-			}
-		}
+// failExecution 命令执行之前出错时把执行记录标记为失败
+func (s *SchedulerService) failExecution(task *models.Task, execution *models.TaskExecution, startTime time.Time, err error) {
+	completedAt := time.Now()
+	execution.CompletedAt = &completedAt
+	execution.Duration = completedAt.Sub(startTime).Milliseconds()
+	execution.Status = "failed"
+	execution.ErrorMsg = err.Error()
+	log.Printf("Task '%s' failed: %v", task.Name, err)
+	if err := database.GetDB().Save(execution).Error; err != nil {
+		log.Printf("Failed to update execution record: %v", err)
 	}
 }
 
 // normalizeCronSpec 确保Cron表达式是5字段格式
 func (s *SchedulerService) normalizeCronSpec(spec string) string {
 	parts := strings.Fields(spec)
-	
+
 	// 如果是6字段格式 (秒 分 时 日 月 周)，转换为5字段格式 (分 时 日 月 周)
 	if len(parts) == 6 {
 		// 移除秒字段，保留后5个字段
 		return strings.Join(parts[1:], " ")
 	}
-	
+
 	// 如果已经是5字段格式，直接返回
 	if len(parts) == 5 {
 		return spec
 	}
-	
+
 	// 格式错误的情况
 	return spec
 }
@@ -222,7 +337,7 @@ func (s *SchedulerService) parseDuration(spec string) (time.Duration, error) {
 	if len(parts) != 2 || parts[0] != "@every" {
 		return 0, fmt.Errorf("invalid duration format: %s", spec)
 	}
-	
+
 	return time.ParseDuration(parts[1])
 }
 
@@ -246,4 +361,18 @@ func (s *SchedulerService) loadExistingTasks() error {
 
 	log.Printf("Loaded %d existing tasks", len(tasks))
 	return nil
-}
\ No newline at end of file
+}
+
+// taskEnviron 在进程环境变量的基础上追加任务的环境变量，按名称排序以保证结果稳定
+func taskEnviron(env models.EnvVars) []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		environ = append(environ, key+"="+env[key])
+	}
+	return environ
+}
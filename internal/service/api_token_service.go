@@ -0,0 +1,95 @@
+package service
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// apiTokenBytes 令牌随机部分的字节数
+const apiTokenBytes = 32
+
+// apiTokenDisplayLen 列表中显示的令牌前缀长度
+const apiTokenDisplayLen = 12
+
+// APITokenService 个人 API 令牌管理服务
+type APITokenService struct{}
+
+func NewAPITokenService() *APITokenService {
+	return &APITokenService{}
+}
+
+// ListTokens 获取用户的全部令牌（包括已撤销和已过期的）
+func (s *APITokenService) ListTokens(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := database.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// CreateToken 创建令牌，明文令牌只在创建时返回一次
+func (s *APITokenService) CreateToken(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("token name cannot be empty")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !auth.ValidScope(scope) {
+			return nil, "", fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", fmt.Errorf("expires_at must be in the future")
+	}
+
+	buf := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	raw := auth.APITokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:apiTokenDisplayLen],
+		TokenHash: auth.HashAPIToken(raw),
+		Scopes:    strings.Join(normalized, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.GetDB().Create(token).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create api token: %w", err)
+	}
+	return token, raw, nil
+}
+
+// RevokeToken 撤销用户自己的令牌
+func (s *APITokenService) RevokeToken(id, userID uint) error {
+	var token models.APIToken
+	if err := database.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&token).Error; err != nil {
+		return fmt.Errorf("failed to get api token: %w", err)
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+
+	if err := database.GetDB().Model(&token).Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+	return nil
+}
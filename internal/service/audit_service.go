@@ -0,0 +1,72 @@
+package service
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditFilter 审计日志查询条件，零值字段表示不过滤
+type AuditFilter struct {
+	Actions    []string   // 动作，如 task.update
+	Actor      string     // 操作者用户名
+	TargetType string     // task, user
+	TargetID   *uint      // 对象ID
+	From       *time.Time // 时间下限（含）
+	To         *time.Time // 时间上限（不含）
+}
+
+func (f *AuditFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.Actions) > 0 {
+		query = query.Where("action IN ?", f.Actions)
+	}
+	if f.Actor != "" {
+		query = query.Where("actor_name = ?", f.Actor)
+	}
+	if f.TargetType != "" {
+		query = query.Where("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != nil {
+		query = query.Where("target_id = ?", *f.TargetID)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at < ?", *f.To)
+	}
+	return query
+}
+
+// AuditService 审计日志查询服务，写入见 audit 包
+type AuditService struct{}
+
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// QueryAuditLogs 按条件分页查询审计日志，按时间倒序
+func (s *AuditService) QueryAuditLogs(filter AuditFilter, page, pageSize int) ([]models.AuditLog, int64, error) {
+	var logs []models.AuditLog
+	var total int64
+
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	if err := filter.apply(database.GetDB().Model(&models.AuditLog{})).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+	if err := filter.apply(database.GetDB()).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+	return logs, total, nil
+}
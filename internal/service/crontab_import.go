@@ -0,0 +1,240 @@
+package service
+
+import (
+	"b1cron/internal/models"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxCrontabNameLength 由命令生成的任务名称的最大长度
+const maxCrontabNameLength = 60
+
+// crontabMacros 可以直接作为调度规则使用的 @ 宏
+var crontabMacros = map[string]bool{
+	"@yearly":   true,
+	"@annually": true,
+	"@monthly":  true,
+	"@weekly":   true,
+	"@daily":    true,
+	"@midnight": true,
+	"@hourly":   true,
+}
+
+// crontabAssignment 环境变量赋值行：NAME=value，名称和值都可以加引号
+var crontabAssignment = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s=]+)\s*=\s*(.*)$`)
+
+// CrontabEntry crontab 中可以导入为任务的一行
+type CrontabEntry struct {
+	Line     int               `json:"line"`
+	Text     string            `json:"text"`
+	Name     string            `json:"name"`
+	Schedule string            `json:"schedule"`
+	Command  string            `json:"command"`
+	Env      map[string]string `json:"env,omitempty"`
+	TaskID   uint              `json:"task_id,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// CrontabSkip crontab 中无法映射为任务的行及原因
+type CrontabSkip struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// CrontabImport crontab 的解析结果
+type CrontabImport struct {
+	DryRun  bool           `json:"dry_run"`
+	Entries []CrontabEntry `json:"entries"`
+	Skipped []CrontabSkip  `json:"skipped"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// ParseCrontab 解析标准 crontab 格式（crontab -l 的输出）：
+// 环境变量赋值对之后的任务生效，任务上方紧邻的注释作为任务名称，
+// 命令中未转义的 % 按 cron 的规则转为换行，第一个 % 之后的内容作为标准输入
+func ParseCrontab(data string) *CrontabImport {
+	result := &CrontabImport{Entries: []CrontabEntry{}, Skipped: []CrontabSkip{}}
+	env := map[string]string{}
+	comment := ""
+
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		number := i + 1
+		line := strings.TrimSpace(raw)
+		skip := func(reason string) {
+			result.Skipped = append(result.Skipped, CrontabSkip{Line: number, Text: line, Reason: reason})
+		}
+
+		switch {
+		case line == "":
+			comment = ""
+			continue
+		case strings.HasPrefix(line, "#"):
+			text := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if _, err := parseCrontabEntry(text); err == nil {
+				skip("commented-out entry")
+				comment = ""
+			} else {
+				comment = text
+			}
+			continue
+		}
+
+		if match := crontabAssignment.FindStringSubmatch(line); match != nil && !strings.HasPrefix(line, "@") {
+			name, value := unquoteCrontab(match[1]), unquoteCrontab(match[2])
+			switch name {
+			case "MAILTO", "MAILFROM":
+				if value == "" {
+					break
+				}
+				skip("mail is not supported, output is stored in the execution history")
+			case "SHELL":
+				if value != "/bin/sh" {
+					skip("commands always run with /bin/sh")
+				}
+			case "CRON_TZ":
+				skip("CRON_TZ is not supported, schedules use the server time zone")
+			default:
+				if !envKeyPattern.MatchString(name) {
+					skip(fmt.Sprintf("invalid environment variable name %q", name))
+					break
+				}
+				env[name] = value
+			}
+			continue
+		}
+
+		entry, err := parseCrontabEntry(line)
+		if err != nil {
+			skip(err.Error())
+			comment = ""
+			continue
+		}
+		entry.Line = number
+		entry.Text = line
+		if comment != "" {
+			entry.Name = comment
+		}
+		if len(env) > 0 {
+			entry.Env = make(map[string]string, len(env))
+			for key, value := range env {
+				entry.Env[key] = value
+			}
+		}
+		result.Entries = append(result.Entries, *entry)
+		comment = ""
+	}
+	return result
+}
+
+// parseCrontabEntry 解析一行任务：5 个时间字段或 @ 宏，后面是命令
+func parseCrontabEntry(line string) (*CrontabEntry, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty line")
+	}
+
+	count := 5
+	if strings.HasPrefix(fields[0], "@") {
+		if fields[0] == "@reboot" {
+			return nil, fmt.Errorf("@reboot has no equivalent, run it when the service starts instead")
+		}
+		if !crontabMacros[fields[0]] {
+			return nil, fmt.Errorf("unknown macro %s", fields[0])
+		}
+		count = 1
+	}
+	if len(fields) <= count {
+		return nil, fmt.Errorf("missing command")
+	}
+	schedule := strings.Join(fields[:count], " ")
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	// 保留命令中原有的空白
+	rest := line
+	for _, field := range fields[:count] {
+		rest = strings.TrimLeft(rest, " \t")
+		rest = rest[len(field):]
+	}
+	command, name, err := crontabCommand(strings.TrimSpace(rest))
+	if err != nil {
+		return nil, err
+	}
+	return &CrontabEntry{Name: crontabTaskName(name), Schedule: schedule, Command: command}, nil
+}
+
+// crontabCommand 处理 % 的语义：\% 是普通的 %，第一个未转义的 % 之后是标准输入，其余的 % 表示换行。
+// 返回要执行的脚本和不含标准输入的命令
+func crontabCommand(text string) (string, string, error) {
+	var command, stdin strings.Builder
+	current := &command
+	hasStdin := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && text[i+1] == '%':
+			current.WriteByte('%')
+			i++
+		case text[i] == '%' && !hasStdin:
+			hasStdin = true
+			current = &stdin
+		case text[i] == '%':
+			current.WriteByte('\n')
+		default:
+			current.WriteByte(text[i])
+		}
+	}
+
+	cmd := strings.TrimSpace(command.String())
+	if cmd == "" {
+		return "", "", fmt.Errorf("missing command")
+	}
+	if !hasStdin {
+		return cmd, cmd, nil
+	}
+
+	// 用带引号的 here-document 把标准输入原样传给命令，不做变量展开
+	// 与 cron 一样，标准输入不以换行结尾时补一个换行。
+	// 命令放在 { } 中单独成行：管道和命令列表整体读取标准输入，命令末尾的注释也不会吞掉 here-document
+	input := strings.TrimSuffix(stdin.String(), "\n")
+	delimiter := "B1CRON_STDIN"
+	for strings.Contains(input, delimiter) {
+		delimiter += "_"
+	}
+	return fmt.Sprintf("{\n%s\n} <<'%s'\n%s\n%s", cmd, delimiter, input, delimiter), cmd, nil
+}
+
+// crontabTaskName 没有注释时用命令作为任务名称
+func crontabTaskName(command string) string {
+	name := command
+	if len([]rune(name)) > maxCrontabNameLength {
+		name = string([]rune(name)[:maxCrontabNameLength]) + "…"
+	}
+	return name
+}
+
+func unquoteCrontab(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// ImportCrontabEntry 把 crontab 中的一行创建为 command 类型的任务，与 cron 一样通过 /bin/sh 执行
+func (s *TaskService) ImportCrontabEntry(entry *CrontabEntry, namespaceID uint, isEnabled bool) (*models.Task, error) {
+	task, err := s.CreateTaskFull(entry.Name, entry.Command, "command", entry.Schedule, "cron", nil, isEnabled, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	entry.TaskID = task.ID
+	if len(entry.Env) > 0 {
+		if err := s.SetTaskEnv(task, entry.Env); err != nil {
+			return task, err
+		}
+	}
+	return task, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrontabCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		script  string
+		command string
+		wantErr bool
+	}{
+		{
+			name:    "plain command",
+			text:    "/usr/bin/backup.sh --full",
+			script:  "/usr/bin/backup.sh --full",
+			command: "/usr/bin/backup.sh --full",
+		},
+		{
+			name:    "escaped percent",
+			text:    `date +\%Y-\%m-\%d`,
+			script:  "date +%Y-%m-%d",
+			command: "date +%Y-%m-%d",
+		},
+		{
+			name:    "stdin",
+			text:    "mail -s hi root%line one%line two",
+			script:  "{\nmail -s hi root\n} <<'B1CRON_STDIN'\nline one\nline two\nB1CRON_STDIN",
+			command: "mail -s hi root",
+		},
+		{
+			name:    "comment before stdin",
+			text:    "cat # note %echo INJECTED",
+			script:  "{\ncat # note\n} <<'B1CRON_STDIN'\necho INJECTED\nB1CRON_STDIN",
+			command: "cat # note",
+		},
+		{
+			name:    "pipeline reads stdin as a whole",
+			text:    "tr a-z A-Z | sort %b%a",
+			script:  "{\ntr a-z A-Z | sort\n} <<'B1CRON_STDIN'\nb\na\nB1CRON_STDIN",
+			command: "tr a-z A-Z | sort",
+		},
+		{
+			name:    "command list",
+			text:    "cd /tmp; cat %data",
+			script:  "{\ncd /tmp; cat\n} <<'B1CRON_STDIN'\ndata\nB1CRON_STDIN",
+			command: "cd /tmp; cat",
+		},
+		{
+			name:    "stdin containing the delimiter",
+			text:    "cat %B1CRON_STDIN",
+			script:  "{\ncat\n} <<'B1CRON_STDIN_'\nB1CRON_STDIN\nB1CRON_STDIN_",
+			command: "cat",
+		},
+		{
+			name:    "trailing newline in stdin",
+			text:    "cat %a%",
+			script:  "{\ncat\n} <<'B1CRON_STDIN'\na\nB1CRON_STDIN",
+			command: "cat",
+		},
+		{
+			name:    "only stdin",
+			text:    "%data",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, command, err := crontabCommand(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("crontabCommand(%q) succeeded, want error", tt.text)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("crontabCommand(%q) error: %v", tt.text, err)
+			}
+			if script != tt.script {
+				t.Errorf("script = %q, want %q", script, tt.script)
+			}
+			if command != tt.command {
+				t.Errorf("command = %q, want %q", command, tt.command)
+			}
+		})
+	}
+}
+
+func TestParseCrontab(t *testing.T) {
+	data := "SHELL=/bin/sh\r\n" +
+		"PATH=/usr/local/bin:/usr/bin\n" +
+		"MAILTO=ops@example.com\n" +
+		"\n" +
+		"# Nightly backup\n" +
+		"0 2 * * * /usr/bin/backup.sh\n" +
+		"\n" +
+		"GREETING=\"hello world\"\n" +
+		"@hourly   echo $GREETING | logger\n" +
+		"# */5 * * * * disabled.sh\n" +
+		"@reboot start.sh\n" +
+		"@fortnightly report.sh\n" +
+		"61 * * * * bad-minute.sh\n" +
+		"* * * * *\n" +
+		"CRON_TZ=UTC\n" +
+		"SHELL=/bin/bash\n" +
+		"1-2=x\n"
+
+	result := ParseCrontab(data)
+
+	wantEntries := []CrontabEntry{
+		{
+			Line:     6,
+			Text:     "0 2 * * * /usr/bin/backup.sh",
+			Name:     "Nightly backup",
+			Schedule: "0 2 * * *",
+			Command:  "/usr/bin/backup.sh",
+			Env:      map[string]string{"PATH": "/usr/local/bin:/usr/bin"},
+		},
+		{
+			Line:     9,
+			Text:     "@hourly   echo $GREETING | logger",
+			Name:     "echo $GREETING | logger",
+			Schedule: "@hourly",
+			Command:  "echo $GREETING | logger",
+			Env:      map[string]string{"PATH": "/usr/local/bin:/usr/bin", "GREETING": "hello world"},
+		},
+	}
+	if !reflect.DeepEqual(result.Entries, wantEntries) {
+		t.Errorf("entries = %+v, want %+v", result.Entries, wantEntries)
+	}
+
+	wantSkipped := []int{3, 10, 11, 12, 13, 14, 15, 16, 17}
+	var skipped []int
+	for _, skip := range result.Skipped {
+		skipped = append(skipped, skip.Line)
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped lines = %v, want %v (%+v)", skipped, wantSkipped, result.Skipped)
+	}
+}
+
+func TestParseCrontabNameFromLongCommand(t *testing.T) {
+	command := "/usr/local/bin/a-very-long-script-name-that-goes-on.sh --with --many --arguments"
+	result := ParseCrontab("*/5 * * * * " + command)
+	if len(result.Entries) != 1 {
+		t.Fatalf("entries = %+v, want one", result.Entries)
+	}
+	entry := result.Entries[0]
+	if entry.Command != command {
+		t.Errorf("command = %q, want %q", entry.Command, command)
+	}
+	if runes := []rune(entry.Name); len(runes) != maxCrontabNameLength+1 || runes[len(runes)-1] != '…' {
+		t.Errorf("name = %q, want the command cut to %d characters", entry.Name, maxCrontabNameLength)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExecutionFilter 执行记录查询条件，零值字段表示不过滤
+type ExecutionFilter struct {
+	Scope       NamespaceScope // 调用者可访问的命名空间，零值不返回任何记录
+	Search      string         // 任务名称模糊搜索
+	Query       string         // 输出和错误信息全文搜索
+	Statuses    []string       // success, failed, running
+	TaskIDs     []uint         // 任务ID列表
+	StartedFrom *time.Time     // 开始时间下限（含）
+	StartedTo   *time.Time     // 开始时间上限（不含）
+	MinDuration *int64         // 最短耗时（毫秒）
+	MaxDuration *int64         // 最长耗时（毫秒）
+	ExitCode    *int           // 退出码
+	SortBy      string         // started_at, duration, status, exit_code, task_name
+	SortOrder   string         // asc, desc
+}
+
+// executionSortColumns 允许排序的字段与实际列的映射
+var executionSortColumns = map[string]string{
+	"started_at": "task_executions.started_at",
+	"duration":   "task_executions.duration",
+	"status":     "task_executions.status",
+	"exit_code":  "task_executions.exit_code",
+	"task_name":  "tasks.name",
+}
+
+// Validate 校验排序参数
+func (f *ExecutionFilter) Validate() error {
+	if f.SortBy != "" {
+		if _, ok := executionSortColumns[f.SortBy]; !ok {
+			return fmt.Errorf("invalid sort field: %s", f.SortBy)
+		}
+	}
+	if f.SortOrder != "" && f.SortOrder != "asc" && f.SortOrder != "desc" {
+		return fmt.Errorf("invalid sort order: %s", f.SortOrder)
+	}
+	return nil
+}
+
+// needsTaskJoin 是否需要关联 tasks 表
+func (f *ExecutionFilter) needsTaskJoin() bool {
+	return f.Search != "" || f.SortBy == "task_name"
+}
+
+// apply 将过滤条件应用到查询上
+func (f *ExecutionFilter) apply(query *gorm.DB) *gorm.DB {
+	query = f.Scope.applyExecutions(query)
+	if f.needsTaskJoin() {
+		query = query.Joins("LEFT JOIN tasks ON tasks.id = task_executions.task_id")
+	}
+	if f.Search != "" {
+		query = query.Where("tasks.name LIKE ?", "%"+f.Search+"%")
+	}
+	if f.Query != "" {
+		query = applyOutputSearch(query, f.Query)
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where("task_executions.status IN ?", f.Statuses)
+	}
+	if len(f.TaskIDs) > 0 {
+		query = query.Where("task_executions.task_id IN ?", f.TaskIDs)
+	}
+	if f.StartedFrom != nil {
+		query = query.Where("task_executions.started_at >= ?", *f.StartedFrom)
+	}
+	if f.StartedTo != nil {
+		query = query.Where("task_executions.started_at < ?", *f.StartedTo)
+	}
+	if f.MinDuration != nil {
+		query = query.Where("task_executions.duration >= ?", *f.MinDuration)
+	}
+	if f.MaxDuration != nil {
+		query = query.Where("task_executions.duration <= ?", *f.MaxDuration)
+	}
+	if f.ExitCode != nil {
+		query = query.Where("task_executions.exit_code = ?", *f.ExitCode)
+	}
+	return query
+}
+
+// orderClause 生成排序子句，始终以ID作为次级排序保证分页稳定
+func (f *ExecutionFilter) orderClause() string {
+	column, ok := executionSortColumns[f.SortBy]
+	if !ok {
+		column = executionSortColumns["started_at"]
+	}
+	order := "DESC"
+	if f.SortOrder == "asc" {
+		order = "ASC"
+	}
+	return fmt.Sprintf("%s %s, task_executions.id %s", column, order, order)
+}
+
+// QueryExecutions 按条件分页查询执行记录
+func (s *TaskService) QueryExecutions(filter ExecutionFilter, page, pageSize int) ([]models.TaskExecution, int64, error) {
+	var executions []models.TaskExecution
+	var total int64
+
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 获取总数
+		if err := filter.apply(tx.Model(&models.TaskExecution{})).Count(&total).Error; err != nil {
+			return fmt.Errorf("failed to count executions: %w", err)
+		}
+
+		// 获取分页数据
+		query := filter.apply(tx.Preload("Task", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}))
+		if err := query.Order(filter.orderClause()).
+			Offset(offset).
+			Limit(pageSize).
+			Find(&executions).Error; err != nil {
+			return fmt.Errorf("failed to get executions: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return executions, total, nil
+}
+
+// ExportExecutions 按条件分批遍历所有匹配的执行记录，用于导出；
+// 按执行记录 ID 排序（方向取 SortOrder，默认从新到旧），以 ID 为游标分批读取
+func (s *TaskService) ExportExecutions(filter ExecutionFilter, fn func(execution *models.TaskExecution) error) error {
+	var batch []models.TaskExecution
+	query := filter.apply(database.GetDB().Preload("Task", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}))
+
+	order, cursor := "task_executions.id DESC", "task_executions.id < ?"
+	if filter.SortOrder == "asc" {
+		order, cursor = "task_executions.id ASC", "task_executions.id > ?"
+	}
+
+	// 按 ID 游标分批读取，避免一次性加载全部输出内容，也不会像偏移量那样越往后越慢
+	const batchSize = 500
+	var lastID uint
+	for {
+		batch = batch[:0]
+		page := query.Session(&gorm.Session{})
+		if lastID != 0 {
+			page = page.Where(cursor, lastID)
+		}
+		if err := page.Order(order).
+			Limit(batchSize).
+			Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to export executions: %w", err)
+		}
+
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 高亮标记使用控制字符，转义 HTML 后再替换为 <mark>，避免输出内容注入页面
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+	// snippetTokens 为 FTS5 snippet() 返回的最大 token 数（trigram 下约等于字符数）
+	snippetTokens = 64
+)
+
+// minFTSQueryLen trigram 分词器要求查询至少 3 个字符，更短的查询回退到 LIKE
+const minFTSQueryLen = 3
+
+// ExecutionSearchResult 全文搜索结果，片段为已转义并带 <mark> 高亮的 HTML
+type ExecutionSearchResult struct {
+	ExecutionID   uint      `json:"execution_id"`
+	TaskID        uint      `json:"task_id"`
+	TaskName      string    `json:"task_name"`
+	Status        string    `json:"status"`
+	StartedAt     time.Time `json:"started_at"`
+	OutputSnippet string    `json:"output_snippet"`
+	ErrorSnippet  string    `json:"error_snippet"`
+}
+
+// useFTS 判断该查询是否可以使用 FTS5 索引
+func useFTS(query string) bool {
+	return database.FTSEnabled() && utf8.RuneCountInString(query) >= minFTSQueryLen
+}
+
+// applyOutputSearch 为执行记录查询添加输出/错误信息全文搜索条件
+func applyOutputSearch(query *gorm.DB, text string) *gorm.DB {
+	if useFTS(text) {
+		return query.Where(fmt.Sprintf("task_executions.id IN (SELECT rowid FROM %[1]s WHERE %[1]s MATCH ?)", database.ExecutionFTSTable), database.FTSPhrase(text))
+	}
+	like := "%" + text + "%"
+	return query.Where("(task_executions.output LIKE ? OR task_executions.error_msg LIKE ?)", like, like)
+}
+
+// SearchExecutionOutput 在执行输出和错误信息中全文搜索，返回带高亮片段的结果
+func (s *TaskService) SearchExecutionOutput(scope NamespaceScope, text string, taskIDs []uint, page, pageSize int) ([]ExecutionSearchResult, int64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, 0, fmt.Errorf("search query cannot be empty")
+	}
+
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	if useFTS(text) {
+		return s.searchExecutionOutputFTS(scope, text, taskIDs, offset, pageSize)
+	}
+	return s.searchExecutionOutputLike(scope, text, taskIDs, offset, pageSize)
+}
+
+func (s *TaskService) searchExecutionOutputFTS(scope NamespaceScope, text string, taskIDs []uint, offset, limit int) ([]ExecutionSearchResult, int64, error) {
+	db := database.GetDB()
+	fts := database.ExecutionFTSTable
+
+	base := db.Table(fts).
+		Joins(fmt.Sprintf("JOIN task_executions ON task_executions.id = %s.rowid", fts)).
+		Joins("LEFT JOIN tasks ON tasks.id = task_executions.task_id").
+		Where(fmt.Sprintf("%s MATCH ?", fts), database.FTSPhrase(text))
+	base = scope.applyExecutions(base)
+	if len(taskIDs) > 0 {
+		base = base.Where("task_executions.task_id IN ?", taskIDs)
+	}
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	var results []ExecutionSearchResult
+	snippet := func(column int) string {
+		return fmt.Sprintf("snippet(%s, %d, '%s', '%s', '...', %d)", fts, column, highlightStart, highlightEnd, snippetTokens)
+	}
+	err := base.Session(&gorm.Session{}).
+		Select(fmt.Sprintf(`task_executions.id AS execution_id, task_executions.task_id, tasks.name AS task_name,
+			task_executions.status, task_executions.started_at,
+			%s AS output_snippet, %s AS error_snippet`, snippet(0), snippet(1))).
+		Order("task_executions.started_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Scan(&results).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search executions: %w", err)
+	}
+
+	for i := range results {
+		// snippet() 对未命中的列同样返回开头片段，这里只保留包含命中的片段
+		results[i].OutputSnippet = renderSnippet(results[i].OutputSnippet)
+		results[i].ErrorSnippet = renderSnippet(results[i].ErrorSnippet)
+	}
+	return results, total, nil
+}
+
+func (s *TaskService) searchExecutionOutputLike(scope NamespaceScope, text string, taskIDs []uint, offset, limit int) ([]ExecutionSearchResult, int64, error) {
+	query := applyOutputSearch(scope.applyExecutions(database.GetDB().Model(&models.TaskExecution{})), text)
+	if len(taskIDs) > 0 {
+		query = query.Where("task_executions.task_id IN ?", taskIDs)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	var executions []models.TaskExecution
+	if err := query.Session(&gorm.Session{}).
+		Preload("Task", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Order("task_executions.started_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&executions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search executions: %w", err)
+	}
+
+	results := make([]ExecutionSearchResult, 0, len(executions))
+	for _, execution := range executions {
+		results = append(results, ExecutionSearchResult{
+			ExecutionID:   execution.ID,
+			TaskID:        execution.TaskID,
+			TaskName:      execution.Task.Name,
+			Status:        execution.Status,
+			StartedAt:     execution.StartedAt,
+			OutputSnippet: renderSnippet(likeSnippet(execution.Output, text)),
+			ErrorSnippet:  renderSnippet(likeSnippet(execution.ErrorMsg, text)),
+		})
+	}
+	return results, total, nil
+}
+
+// likeSnippet 在不使用 FTS5 时截取命中位置附近的文本并加上高亮标记
+func likeSnippet(content, text string) string {
+	index := strings.Index(strings.ToLower(content), strings.ToLower(text))
+	// 大小写转换可能改变字节长度，此时无法定位原文，放弃高亮
+	if index < 0 || index+len(text) > len(content) || !strings.EqualFold(content[index:index+len(text)], text) {
+		return ""
+	}
+
+	const context = snippetTokens / 2
+	start := index
+	for n := 0; start > 0 && n < context; n++ {
+		_, size := utf8.DecodeLastRuneInString(content[:start])
+		start -= size
+	}
+	end := index + len(text)
+	for n := 0; end < len(content) && n < context; n++ {
+		_, size := utf8.DecodeRuneInString(content[end:])
+		end += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	b.WriteString(content[start:index])
+	b.WriteString(highlightStart)
+	b.WriteString(content[index : index+len(text)])
+	b.WriteString(highlightEnd)
+	b.WriteString(content[index+len(text) : end])
+	if end < len(content) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// renderSnippet 转义片段并把高亮标记替换为 <mark>，没有命中的片段返回空字符串
+func renderSnippet(snippet string) string {
+	if !strings.Contains(snippet, highlightStart) {
+		return ""
+	}
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightEnd, "</mark>")
+}
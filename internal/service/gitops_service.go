@@ -0,0 +1,423 @@
+package service
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitopsActor GitOps 同步写入审计日志时使用的操作者名称
+const gitopsActor = "gitops"
+
+// ManifestError 清单文件或其中某个任务的解析、校验错误
+type ManifestError struct {
+	File  string `json:"file"`
+	Slug  string `json:"slug,omitempty"`
+	Error string `json:"error"`
+
+	namespaceID uint // 任务所在的命名空间，0 表示整个文件出错
+}
+
+// GitOpsStatus 最近一次同步的结果
+type GitOpsStatus struct {
+	Enabled  bool            `json:"enabled"`
+	Dir      string          `json:"dir"`
+	DryRun   bool            `json:"dry_run"`
+	SyncedAt *time.Time      `json:"synced_at"`
+	Files    int             `json:"files"`
+	Tasks    int             `json:"tasks"`  // 清单中有效的任务数
+	Errors   []ManifestError `json:"errors"` // 出错的文件或任务不会被修改，也不会因此被禁用
+	Drift    []ImportItem    `json:"drift"`  // 与清单不一致的任务，dry_run 时只报告不修改
+	Error    string          `json:"error,omitempty"`
+	Hidden   int             `json:"hidden,omitempty"` // 调用者无权访问的命名空间中的偏差和错误数
+}
+
+// InScope 只保留调用者可访问的命名空间中的偏差和错误，其余只计数；整个文件的解析错误只有管理员可见
+func (s GitOpsStatus) InScope(scope NamespaceScope) GitOpsStatus {
+	if scope.All {
+		return s
+	}
+	filtered := s
+	filtered.Errors = []ManifestError{}
+	for _, item := range s.Errors {
+		if item.namespaceID != 0 && scope.Contains(item.namespaceID) {
+			filtered.Errors = append(filtered.Errors, item)
+		} else {
+			filtered.Hidden++
+		}
+	}
+	filtered.Drift = []ImportItem{}
+	for _, item := range s.Drift {
+		if scope.Contains(item.namespaceID) {
+			filtered.Drift = append(filtered.Drift, item)
+		} else {
+			filtered.Hidden++
+		}
+	}
+	return filtered
+}
+
+// manifestTask 清单中的任务及其所在文件（相对清单目录）
+type manifestTask struct {
+	file string
+	task BundleTask
+}
+
+// GitOpsService 定期读取清单目录，让任务与清单保持一致：
+// 创建和更新清单中的任务，禁用从清单中移除的任务，并记录解析错误和偏差
+type GitOpsService struct {
+	taskService *TaskService
+	config      *config.Config
+
+	syncMu sync.Mutex // 同一时间只进行一次同步
+	mu     sync.Mutex
+	status GitOpsStatus
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewGitOpsService 创建 GitOps 同步服务
+func NewGitOpsService(taskService *TaskService, cfg *config.Config) *GitOpsService {
+	return &GitOpsService{
+		taskService: taskService,
+		config:      cfg,
+		status: GitOpsStatus{
+			Enabled: cfg.GitOps.Enabled,
+			Dir:     cfg.GitOps.Dir,
+			DryRun:  cfg.GitOps.DryRun,
+			Errors:  []ManifestError{},
+			Drift:   []ImportItem{},
+		},
+	}
+}
+
+// Start 启动后台同步循环，未启用 GitOps 时不做任何事
+func (s *GitOpsService) Start() {
+	if !s.config.GitOps.Enabled {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stopCh != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.mu.Unlock()
+
+	interval := s.config.GetGitOpsInterval()
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Sync(); err != nil {
+				log.Printf("GitOps sync failed: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("GitOps sync started (dir: %s, interval: %s, dry_run: %v)", s.config.GitOps.Dir, interval, s.config.GitOps.DryRun)
+}
+
+// Stop 停止后台同步循环
+func (s *GitOpsService) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	<-doneCh
+}
+
+// Status 获取最近一次同步的结果
+func (s *GitOpsService) Status() GitOpsStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync 立即同步一次
+func (s *GitOpsService) Sync() (*GitOpsStatus, error) {
+	if !s.config.GitOps.Enabled {
+		return nil, fmt.Errorf("gitops is not enabled")
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	status := GitOpsStatus{
+		Enabled: true,
+		Dir:     s.config.GitOps.Dir,
+		DryRun:  s.config.GitOps.DryRun,
+		Errors:  []ManifestError{},
+		Drift:   []ImportItem{},
+	}
+	err := s.sync(&status)
+	now := time.Now()
+	status.SyncedAt = &now
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+	return &status, err
+}
+
+// loadManifests 读取清单目录中的全部任务；解析失败的文件记入 status.Errors 并返回在 failed 中
+func (s *GitOpsService) loadManifests(status *GitOpsStatus) ([]manifestTask, map[string]bool, error) {
+	dir := s.config.GitOps.Dir
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("manifest path is not a directory: %s", dir)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 跳过 .git 等隐藏目录和文件
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+	sort.Strings(files)
+
+	var tasks []manifestTask
+	failed := make(map[string]bool)
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var bundle *TaskBundle
+			if bundle, err = ParseTaskBundle(data); err == nil {
+				for _, task := range bundle.Tasks {
+					tasks = append(tasks, manifestTask{file: rel, task: task})
+				}
+			}
+		}
+		if err != nil {
+			failed[rel] = true
+			status.Errors = append(status.Errors, ManifestError{File: rel, Error: err.Error()})
+		}
+	}
+	status.Files = len(files)
+	return tasks, failed, nil
+}
+
+// sync 比较清单与现有任务，非 dry_run 时执行变更
+func (s *GitOpsService) sync(status *GitOpsStatus) error {
+	manifests, failed, err := s.loadManifests(status)
+	if err != nil {
+		return err
+	}
+
+	planner, err := s.taskService.newImportPlanner(AllNamespaces())
+	if err != nil {
+		return err
+	}
+
+	var items []ImportItem
+	for _, manifest := range manifests {
+		item, err := planner.plan(manifest.task)
+		if err != nil {
+			status.Errors = append(status.Errors, ManifestError{File: manifest.file, Slug: manifest.task.Slug, Error: err.Error(), namespaceID: planner.manifestNamespace(manifest.task)})
+			continue
+		}
+		status.Tasks++
+		item.Manifest = manifest.file
+
+		if task := planner.existing[item.Slug]; task != nil {
+			if task.Manifest != manifest.file {
+				// 接管界面中创建的同名任务，或任务移到了另一个清单文件
+				if item.Changes == nil {
+					item.Changes = make(map[string]audit.FieldChange)
+				}
+				item.Changes["manifest"] = audit.FieldChange{Before: task.Manifest, After: manifest.file}
+			}
+			ignoreCompletedOnce(&item, task)
+			item.Action = ImportUnchanged
+			if len(item.Changes) > 0 || item.ScriptDiff != nil {
+				item.Action = ImportUpdate
+			}
+		}
+		if item.Action != ImportUnchanged {
+			items = append(items, item)
+		}
+	}
+
+	// 从清单中移除的任务：禁用并解除管理；所在文件解析失败时保持不变
+	for i := range planner.tasks {
+		task := &planner.tasks[i]
+		if task.Manifest == "" || planner.seen[task.Slug] || failed[task.Manifest] {
+			continue
+		}
+		changes := map[string]audit.FieldChange{"manifest": {Before: task.Manifest, After: ""}}
+		if task.IsEnabled {
+			changes["enabled"] = audit.FieldChange{Before: true, After: false}
+		}
+		items = append(items, ImportItem{Slug: task.Slug, Action: ImportDisable, TaskID: task.ID, Name: task.Name, Changes: changes, Manifest: task.Manifest, namespaceID: task.NamespaceID})
+	}
+
+	if !s.config.GitOps.DryRun {
+		for i := range items {
+			s.apply(&items[i])
+		}
+	}
+	for i := range items {
+		redactEnvChange(&items[i])
+	}
+	status.Drift = append(status.Drift, items...)
+	return nil
+}
+
+// redactEnvChange 偏差会一直保留在同步状态中，环境变量可能包含密钥，只保留变量名
+func redactEnvChange(item *ImportItem) {
+	change, ok := item.Changes["env"]
+	if !ok {
+		return
+	}
+	before, _ := change.Before.(map[string]string)
+	after, _ := change.After.(map[string]string)
+	item.Changes["env"] = audit.RedactSecretChange(before, after)
+}
+
+// ignoreCompletedOnce 一次性任务执行后会被自动禁用，这不算与清单的偏差
+func ignoreCompletedOnce(item *ImportItem, task *models.Task) {
+	if task.ScheduleType != "once" || task.IsEnabled || task.ExecuteAt == nil || task.ExecuteAt.After(time.Now()) {
+		return
+	}
+	if _, ok := item.Changes["execute_at"]; !ok {
+		delete(item.Changes, "enabled")
+		// 其他字段变化时也不要重新启用已经执行过的任务
+		disabled := false
+		item.desired.Enabled = &disabled
+	}
+}
+
+// apply 执行一项同步变更，并像界面操作一样记录版本和审计日志
+func (s *GitOpsService) apply(item *ImportItem) {
+	var before audit.Snapshot
+	if item.TaskID != 0 {
+		if original, err := s.taskService.GetTaskByID(item.TaskID); err == nil {
+			before = s.snapshot(original)
+		}
+	}
+
+	var task *models.Task
+	var err error
+	if item.Action == ImportDisable {
+		task, err = s.release(item.TaskID)
+	} else {
+		task, err = s.taskService.ApplyImportItem(item)
+		// 即使部分字段写入失败也标记为受管理，下一次同步会重试
+		if task != nil {
+			if markErr := setTaskManifest(task, item.Manifest); err == nil {
+				err = markErr
+			}
+		}
+	}
+	if err != nil {
+		item.Error = err.Error()
+		log.Printf("GitOps failed to %s task %s: %v", item.Action, item.Slug, err)
+	}
+	if task == nil {
+		return
+	}
+
+	action := audit.ActionTaskUpdate
+	if item.Action == ImportCreate {
+		action = audit.ActionTaskCreate
+		before = nil
+	}
+	if item.Action != ImportDisable {
+		if _, err := s.taskService.RecordRevision(task, s.taskService.GetTaskScriptContent(task), nil, "synced from "+item.Manifest); err != nil {
+			log.Printf("Failed to record revision for task %d: %v", task.ID, err)
+		}
+	}
+	audit.Record(audit.Entry{
+		Action:     action,
+		ActorName:  gitopsActor,
+		TargetType: audit.TargetTask,
+		TargetID:   task.ID,
+		TargetName: task.Name,
+		Before:     before,
+		After:      s.snapshot(task),
+	})
+}
+
+func (s *GitOpsService) snapshot(task *models.Task) audit.Snapshot {
+	return audit.TaskSnapshot(task, s.taskService.GetTaskScriptContent(task))
+}
+
+// release 禁用已从清单中移除的任务并解除管理，之后可以在界面中修改或删除
+func (s *GitOpsService) release(id uint) (*models.Task, error) {
+	task, err := s.taskService.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if task.IsEnabled {
+		if task, err = s.taskService.UpdateTaskWithScript(id, task.Name, s.taskService.GetTaskScriptContent(task), task.ScriptType, task.ScheduleSpec, false); err != nil {
+			return nil, err
+		}
+	}
+	return task, setTaskManifest(task, "")
+}
+
+func setTaskManifest(task *models.Task, manifest string) error {
+	if task.Manifest == manifest {
+		return nil
+	}
+	if err := database.GetDB().Model(task).UpdateColumn("manifest", manifest).Error; err != nil {
+		return fmt.Errorf("failed to update task manifest: %w", err)
+	}
+	task.Manifest = manifest
+	return nil
+}
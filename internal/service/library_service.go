@@ -0,0 +1,386 @@
+package service
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// libraryNamePattern 模块名称即文件名（不含扩展名），也是 Python 的模块名
+var libraryNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// libraryLanguages 模块语言及其文件扩展名
+var libraryLanguages = map[string]string{
+	"shell":  "sh",
+	"python": "py",
+}
+
+// ErrLibraryModuleInUse 模块仍被任务使用，不能删除
+var ErrLibraryModuleInUse = errors.New("library module is used by tasks")
+
+// LibraryModuleSummary 模块及使用它的任务数
+type LibraryModuleSummary struct {
+	models.LibraryModule
+	TaskCount int `json:"task_count"`
+}
+
+// LibraryModuleUsage 使用模块的任务，Version 为固定的版本号，0 表示始终使用最新版本
+type LibraryModuleUsage struct {
+	TaskID   uint   `json:"task_id"`
+	TaskName string `json:"task_name"`
+	Version  int    `json:"version"`
+}
+
+// LibraryModuleDetail 模块、历史版本和使用它的任务
+type LibraryModuleDetail struct {
+	models.LibraryModule
+	Versions        []models.LibraryModuleVersion `json:"versions"`          // 不含内容，按版本号倒序
+	UsedBy          []LibraryModuleUsage          `json:"used_by"`           // 调用者可访问的命名空间中的任务
+	HiddenTaskCount int                           `json:"hidden_task_count"` // 其他命名空间中使用该模块的任务数
+}
+
+// LibraryService 共享脚本库管理服务
+type LibraryService struct {
+	scriptService *ScriptFileService
+}
+
+func NewLibraryService(cfg *config.Config) *LibraryService {
+	return &LibraryService{
+		scriptService: NewScriptFileService(cfg),
+	}
+}
+
+// parseLibraryRef 解析任务中的模块引用 name 或 name@version
+func parseLibraryRef(ref string) (string, int, error) {
+	ref = strings.TrimSpace(ref)
+	name, versionText, pinned := strings.Cut(ref, "@")
+	if !libraryNamePattern.MatchString(name) {
+		return "", 0, fmt.Errorf("invalid library module reference %q", ref)
+	}
+	if !pinned {
+		return name, 0, nil
+	}
+	version, err := strconv.Atoi(versionText)
+	if err != nil || version <= 0 {
+		return "", 0, fmt.Errorf("invalid library module version in %q", ref)
+	}
+	return name, version, nil
+}
+
+// CheckLibraries 检查任务引用的模块是否存在（固定版本时版本也要存在），返回去重排序后的引用
+func (s *LibraryService) CheckLibraries(refs []string) (models.StringList, error) {
+	seen := make(map[string]string, len(refs))
+	result := models.StringList{}
+	for _, ref := range refs {
+		if strings.TrimSpace(ref) == "" {
+			continue
+		}
+		name, version, err := parseLibraryRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		normalized := name
+		if version > 0 {
+			normalized = fmt.Sprintf("%s@%d", name, version)
+		}
+		if previous, ok := seen[name]; ok {
+			if previous == normalized {
+				continue
+			}
+			return nil, fmt.Errorf("library module %s is referenced more than once", name)
+		}
+		seen[name] = normalized
+
+		var module models.LibraryModule
+		if err := database.GetDB().Where("name = ?", name).First(&module).Error; err != nil {
+			return nil, fmt.Errorf("library module %s not found", name)
+		}
+		if version > module.Version {
+			return nil, fmt.Errorf("library module %s has no version %d", name, version)
+		}
+		result = append(result, normalized)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// validateModule 检查模块名称、语言和内容，内容需通过对应运行时的语法检查
+func (s *LibraryService) validateModule(name, language, content string) error {
+	if !libraryNamePattern.MatchString(name) {
+		return fmt.Errorf("module name must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+	}
+	if _, ok := libraryLanguages[language]; !ok {
+		return fmt.Errorf("unsupported module language: %s", language)
+	}
+	if err := s.scriptService.ValidateScriptContent(language, content); err != nil {
+		return fmt.Errorf("invalid module content: %w", err)
+	}
+	return s.scriptService.CheckScriptSyntax(language, content)
+}
+
+// ListModules 获取全部模块及使用它们的任务数（包括调用者无权访问的任务）
+func (s *LibraryService) ListModules() ([]LibraryModuleSummary, error) {
+	var modules []models.LibraryModule
+	if err := database.GetDB().Order("name").Find(&modules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get library modules: %w", err)
+	}
+	_, counts, err := s.moduleUsages(AllNamespaces())
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]LibraryModuleSummary, 0, len(modules))
+	for _, module := range modules {
+		summaries = append(summaries, LibraryModuleSummary{
+			LibraryModule: module,
+			TaskCount:     counts[module.Name],
+		})
+	}
+	return summaries, nil
+}
+
+// GetModule 获取模块、历史版本和使用它的任务；范围之外的任务只返回数量
+func (s *LibraryService) GetModule(id uint, scope NamespaceScope) (*LibraryModuleDetail, error) {
+	var module models.LibraryModule
+	if err := database.GetDB().First(&module, id).Error; err != nil {
+		return nil, fmt.Errorf("library module not found: %w", err)
+	}
+	var versions []models.LibraryModuleVersion
+	if err := database.GetDB().Omit("content").Where("module_id = ?", id).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get module versions: %w", err)
+	}
+	usages, counts, err := s.moduleUsages(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	usedBy := usages[module.Name]
+	if usedBy == nil {
+		usedBy = []LibraryModuleUsage{}
+	}
+	return &LibraryModuleDetail{
+		LibraryModule:   module,
+		Versions:        versions,
+		UsedBy:          usedBy,
+		HiddenTaskCount: counts[module.Name] - len(usedBy),
+	}, nil
+}
+
+// GetModuleVersion 获取模块的某个版本
+func (s *LibraryService) GetModuleVersion(id uint, version int) (*models.LibraryModuleVersion, error) {
+	var moduleVersion models.LibraryModuleVersion
+	if err := database.GetDB().Where("module_id = ? AND version = ?", id, version).First(&moduleVersion).Error; err != nil {
+		return nil, fmt.Errorf("module version not found: %w", err)
+	}
+	return &moduleVersion, nil
+}
+
+// CreateModule 创建模块并记录第 1 个版本
+func (s *LibraryService) CreateModule(name, language, description, content string, author *models.User) (*models.LibraryModule, error) {
+	if err := s.validateModule(name, language, content); err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := database.GetDB().Model(&models.LibraryModule{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check module name: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("library module %s already exists", name)
+	}
+
+	module := &models.LibraryModule{
+		Name:        name,
+		Language:    language,
+		Description: description,
+		Content:     content,
+		Version:     1,
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(module).Error; err != nil {
+			return fmt.Errorf("failed to create library module: %w", err)
+		}
+		return createModuleVersion(tx, module, author, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// UpdateModule 修改模块的说明和内容，内容变化时记录新版本；名称和语言不能修改
+func (s *LibraryService) UpdateModule(id uint, description, content, note string, author *models.User) (*models.LibraryModule, error) {
+	var module models.LibraryModule
+	if err := database.GetDB().First(&module, id).Error; err != nil {
+		return nil, fmt.Errorf("library module not found: %w", err)
+	}
+	if err := s.validateModule(module.Name, module.Language, content); err != nil {
+		return nil, err
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		changed := content != module.Content
+		module.Description = description
+		if changed {
+			module.Content = content
+			module.Version++
+		}
+		if err := tx.Save(&module).Error; err != nil {
+			return fmt.Errorf("failed to update library module: %w", err)
+		}
+		if !changed {
+			return nil
+		}
+		return createModuleVersion(tx, &module, author, note)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
+// createModuleVersion 记录模块当前内容为新版本
+func createModuleVersion(tx *gorm.DB, module *models.LibraryModule, author *models.User, note string) error {
+	version := &models.LibraryModuleVersion{
+		ModuleID:   module.ID,
+		Version:    module.Version,
+		Content:    module.Content,
+		AuthorName: "system",
+		Note:       note,
+	}
+	if author != nil {
+		version.AuthorID = &author.ID
+		version.AuthorName = author.Username
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return fmt.Errorf("failed to record module version: %w", err)
+	}
+	return nil
+}
+
+// DeleteModule 删除模块及其全部版本，仍被任务使用时返回 ErrLibraryModuleInUse
+func (s *LibraryService) DeleteModule(id uint) (*models.LibraryModule, error) {
+	var module models.LibraryModule
+	if err := database.GetDB().First(&module, id).Error; err != nil {
+		return nil, fmt.Errorf("library module not found: %w", err)
+	}
+	_, counts, err := s.moduleUsages(AllNamespaces())
+	if err != nil {
+		return nil, err
+	}
+	if counts[module.Name] > 0 {
+		return nil, ErrLibraryModuleInUse
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("module_id = ?", module.ID).Delete(&models.LibraryModuleVersion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete module versions: %w", err)
+		}
+		if err := tx.Delete(&module).Error; err != nil {
+			return fmt.Errorf("failed to delete library module: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
+// moduleUsages 按模块名称汇总引用了它的任务：范围内的任务明细，以及全部命名空间中的任务数
+func (s *LibraryService) moduleUsages(scope NamespaceScope) (map[string][]LibraryModuleUsage, map[string]int, error) {
+	var tasks []models.Task
+	if err := database.GetDB().Select("id", "name", "namespace_id", "libraries").Where("libraries <> '' AND libraries IS NOT NULL").Order("name").Find(&tasks).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get task libraries: %w", err)
+	}
+
+	usages := make(map[string][]LibraryModuleUsage)
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		for _, ref := range task.Libraries {
+			name, version, err := parseLibraryRef(ref)
+			if err != nil {
+				continue
+			}
+			counts[name]++
+			if !scope.Contains(task.NamespaceID) {
+				continue
+			}
+			usages[name] = append(usages[name], LibraryModuleUsage{
+				TaskID:   task.ID,
+				TaskName: task.Name,
+				Version:  version,
+			})
+		}
+	}
+	return usages, counts, nil
+}
+
+// Materialize 把任务引用的模块写入 dir 下的 lib 目录，返回该目录；没有引用模块时返回空字符串
+func (s *LibraryService) Materialize(refs []string, dir string) (string, error) {
+	if len(refs) == 0 {
+		return "", nil
+	}
+	libDir, err := filepath.Abs(filepath.Join(dir, "lib"))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve library directory: %w", err)
+	}
+	if err := os.MkdirAll(libDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create library directory: %w", err)
+	}
+
+	for _, ref := range refs {
+		name, version, err := parseLibraryRef(ref)
+		if err != nil {
+			return "", err
+		}
+		var module models.LibraryModule
+		if err := database.GetDB().Where("name = ?", name).First(&module).Error; err != nil {
+			return "", fmt.Errorf("library module %s not found", name)
+		}
+		content := module.Content
+		if version > 0 {
+			moduleVersion, err := s.GetModuleVersion(module.ID, version)
+			if err != nil {
+				return "", fmt.Errorf("library module %s has no version %d", name, version)
+			}
+			content = moduleVersion.Content
+		}
+
+		filename := module.Name + "." + libraryLanguages[module.Language]
+		if err := os.WriteFile(filepath.Join(libDir, filename), []byte(content), 0755); err != nil {
+			return "", fmt.Errorf("failed to write library module %s: %w", name, err)
+		}
+	}
+	return libDir, nil
+}
+
+// CheckLibraries 检查任务引用的共享库模块并返回整理后的引用
+func (s *TaskService) CheckLibraries(refs []string) (models.StringList, error) {
+	return s.libraries.CheckLibraries(refs)
+}
+
+// SetTaskLibraries 修改任务引用的共享库模块，下一次执行时生效
+func (s *TaskService) SetTaskLibraries(task *models.Task, refs []string) error {
+	libraries, err := s.libraries.CheckLibraries(refs)
+	if err != nil {
+		return err
+	}
+	if len(libraries) == 0 {
+		libraries = nil
+	}
+	if err := database.GetDB().Model(task).UpdateColumn("libraries", libraries).Error; err != nil {
+		return fmt.Errorf("failed to update task libraries: %w", err)
+	}
+	task.Libraries = libraries
+	return nil
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NamespaceScope 调用者可访问的命名空间范围，零值表示无任何访问权限
+type NamespaceScope struct {
+	All bool   // 管理员可访问全部命名空间
+	IDs []uint // 可访问的命名空间ID
+}
+
+// AllNamespaces 不受限制的访问范围，供管理员和后台任务使用
+func AllNamespaces() NamespaceScope {
+	return NamespaceScope{All: true}
+}
+
+// Contains 是否可访问指定命名空间
+func (s NamespaceScope) Contains(namespaceID uint) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.IDs {
+		if id == namespaceID {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTasks 限定任务查询的命名空间
+func (s NamespaceScope) applyTasks(query *gorm.DB) *gorm.DB {
+	if s.All {
+		return query
+	}
+	if len(s.IDs) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where("tasks.namespace_id IN ?", s.IDs)
+}
+
+// applyExecutions 限定执行记录查询的命名空间（包括已删除任务的记录）
+func (s NamespaceScope) applyExecutions(query *gorm.DB) *gorm.DB {
+	if s.All {
+		return query
+	}
+	if len(s.IDs) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where("task_executions.task_id IN (SELECT id FROM tasks WHERE namespace_id IN ?)", s.IDs)
+}
+
+// NamespaceWithMembers 命名空间及其成员和任务数
+type NamespaceWithMembers struct {
+	models.Namespace
+	Members   []models.User `json:"members"`
+	TaskCount int64         `json:"task_count"`
+}
+
+// NamespaceService 命名空间管理服务
+type NamespaceService struct{}
+
+func NewNamespaceService() *NamespaceService {
+	return &NamespaceService{}
+}
+
+// ScopeFor 获取用户可访问的命名空间范围
+func (s *NamespaceService) ScopeFor(user *models.User) (NamespaceScope, error) {
+	if user == nil {
+		return NamespaceScope{}, nil
+	}
+	if user.Role == auth.RoleAdmin {
+		return AllNamespaces(), nil
+	}
+
+	ids, err := s.UserNamespaceIDs(user.ID)
+	if err != nil {
+		return NamespaceScope{}, err
+	}
+	return NamespaceScope{IDs: ids}, nil
+}
+
+// UserNamespaceIDs 获取用户所属的命名空间ID
+func (s *NamespaceService) UserNamespaceIDs(userID uint) ([]uint, error) {
+	ids := []uint{}
+	if err := database.GetDB().Model(&models.NamespaceMember{}).
+		Where("user_id = ?", userID).
+		Order("namespace_id ASC").
+		Pluck("namespace_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user namespaces: %w", err)
+	}
+	return ids, nil
+}
+
+// ListNamespaces 获取范围内的命名空间
+func (s *NamespaceService) ListNamespaces(scope NamespaceScope) ([]models.Namespace, error) {
+	var namespaces []models.Namespace
+	query := database.GetDB().Order("name ASC")
+	if !scope.All {
+		if len(scope.IDs) == 0 {
+			return namespaces, nil
+		}
+		query = query.Where("id IN ?", scope.IDs)
+	}
+	if err := query.Find(&namespaces).Error; err != nil {
+		return nil, fmt.Errorf("failed to get namespaces: %w", err)
+	}
+	return namespaces, nil
+}
+
+// ListNamespacesWithMembers 获取全部命名空间及成员，供管理界面使用
+func (s *NamespaceService) ListNamespacesWithMembers() ([]NamespaceWithMembers, error) {
+	namespaces, err := s.ListNamespaces(AllNamespaces())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NamespaceWithMembers, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		item := NamespaceWithMembers{Namespace: namespace, Members: []models.User{}}
+		if err := database.GetDB().
+			Where("id IN (SELECT user_id FROM namespace_members WHERE namespace_id = ?)", namespace.ID).
+			Order("username ASC").
+			Find(&item.Members).Error; err != nil {
+			return nil, fmt.Errorf("failed to get namespace members: %w", err)
+		}
+		if err := database.GetDB().Model(&models.Task{}).
+			Where("namespace_id = ?", namespace.ID).
+			Count(&item.TaskCount).Error; err != nil {
+			return nil, fmt.Errorf("failed to count namespace tasks: %w", err)
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func (s *NamespaceService) GetNamespaceByID(id uint) (*models.Namespace, error) {
+	var namespace models.Namespace
+	if err := database.GetDB().First(&namespace, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get namespace: %w", err)
+	}
+	return &namespace, nil
+}
+
+func (s *NamespaceService) CreateNamespace(name, description string) (*models.Namespace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("namespace name cannot be empty")
+	}
+
+	var count int64
+	if err := database.GetDB().Model(&models.Namespace{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check namespace name: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("namespace already exists: %s", name)
+	}
+
+	namespace := &models.Namespace{Name: name, Description: strings.TrimSpace(description)}
+	if err := database.GetDB().Create(namespace).Error; err != nil {
+		return nil, fmt.Errorf("failed to create namespace: %w", err)
+	}
+	return namespace, nil
+}
+
+// DeleteNamespace 删除命名空间，默认命名空间和仍有任务的命名空间不能删除
+func (s *NamespaceService) DeleteNamespace(id uint) error {
+	namespace, err := s.GetNamespaceByID(id)
+	if err != nil {
+		return err
+	}
+	if namespace.Name == database.DefaultNamespaceName {
+		return fmt.Errorf("cannot delete the default namespace")
+	}
+
+	var count int64
+	if err := database.GetDB().Model(&models.Task{}).Where("namespace_id = ?", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count namespace tasks: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("namespace still has %d tasks", count)
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("namespace_id = ?", id).Delete(&models.NamespaceMember{}).Error; err != nil {
+			return fmt.Errorf("failed to delete namespace members: %w", err)
+		}
+		if err := tx.Delete(namespace).Error; err != nil {
+			return fmt.Errorf("failed to delete namespace: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *NamespaceService) AddMember(namespaceID, userID uint) error {
+	if _, err := s.GetNamespaceByID(namespaceID); err != nil {
+		return err
+	}
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	member := models.NamespaceMember{NamespaceID: namespaceID, UserID: userID}
+	if err := database.GetDB().FirstOrCreate(&member, member).Error; err != nil {
+		return fmt.Errorf("failed to add namespace member: %w", err)
+	}
+	return nil
+}
+
+func (s *NamespaceService) RemoveMember(namespaceID, userID uint) error {
+	result := database.GetDB().Where("namespace_id = ? AND user_id = ?", namespaceID, userID).Delete(&models.NamespaceMember{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove namespace member: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user is not a member of the namespace")
+	}
+	return nil
+}
+
+// SetUserNamespaces 设置用户所属的命名空间（覆盖原有成员关系）
+func (s *NamespaceService) SetUserNamespaces(userID uint, namespaceIDs []uint) error {
+	if err := checkNamespaceIDs(database.GetDB(), namespaceIDs); err != nil {
+		return err
+	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return replaceUserNamespaces(tx, userID, namespaceIDs)
+	})
+}
+
+// checkNamespaceIDs 确认命名空间全部存在，在写入之前调用
+func checkNamespaceIDs(db *gorm.DB, namespaceIDs []uint) error {
+	for _, namespaceID := range namespaceIDs {
+		if err := db.First(&models.Namespace{}, namespaceID).Error; err != nil {
+			return fmt.Errorf("namespace not found: %d", namespaceID)
+		}
+	}
+	return nil
+}
+
+// replaceUserNamespaces 在事务中覆盖用户所属的命名空间
+func replaceUserNamespaces(tx *gorm.DB, userID uint, namespaceIDs []uint) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.NamespaceMember{}).Error; err != nil {
+		return fmt.Errorf("failed to clear user namespaces: %w", err)
+	}
+	seen := make(map[uint]bool)
+	for _, namespaceID := range namespaceIDs {
+		if seen[namespaceID] {
+			continue
+		}
+		seen[namespaceID] = true
+		if err := tx.First(&models.Namespace{}, namespaceID).Error; err != nil {
+			return fmt.Errorf("namespace not found: %d", namespaceID)
+		}
+		if err := tx.Create(&models.NamespaceMember{NamespaceID: namespaceID, UserID: userID}).Error; err != nil {
+			return fmt.Errorf("failed to add namespace member: %w", err)
+		}
+	}
+	return nil
+}
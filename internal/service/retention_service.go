@@ -0,0 +1,285 @@
+package service
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPruneInProgress 已经有一次清理正在进行
+var ErrPruneInProgress = errors.New("pruning is already in progress")
+
+// RetentionService 执行记录保留策略与后台清理服务
+type RetentionService struct {
+	config *config.Config
+
+	mu         sync.Mutex
+	lastReport *PruneReport
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+
+	// pruneMu 同一时间只允许一次清理，避免后台任务和手动清理同时删除记录和执行 VACUUM
+	pruneMu sync.Mutex
+}
+
+// TaskPruneResult 单个任务的清理结果
+type TaskPruneResult struct {
+	TaskID       uint   `json:"task_id"`
+	TaskName     string `json:"task_name"`
+	MaxAgeDays   int    `json:"max_age_days"`
+	MaxRows      int    `json:"max_rows"`
+	DeletedByAge int64  `json:"deleted_by_age"`
+	DeletedByMax int64  `json:"deleted_by_max_rows"`
+}
+
+// PruneReport 一次清理的汇总报告
+type PruneReport struct {
+	DryRun       bool              `json:"dry_run"`
+	StartedAt    time.Time         `json:"started_at"`
+	CompletedAt  time.Time         `json:"completed_at"`
+	TotalDeleted int64             `json:"total_deleted"`
+	Vacuum       string            `json:"vacuum"`
+	Tasks        []TaskPruneResult `json:"tasks"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// NewRetentionService 创建保留策略服务
+func NewRetentionService(cfg *config.Config) *RetentionService {
+	return &RetentionService{
+		config: cfg,
+	}
+}
+
+// Start 启动后台清理循环
+func (s *RetentionService) Start() {
+	s.mu.Lock()
+	if s.stopCh != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stopCh = make(chan struct{})
+	s.doneCh = make(chan struct{})
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.mu.Unlock()
+
+	interval := s.config.GetPruneInterval()
+	go func() {
+		defer close(doneCh)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Prune(s.config.Retention.DryRun); errors.Is(err, ErrPruneInProgress) {
+				log.Printf("Skipping execution history pruning: %v", err)
+			} else if err != nil {
+				log.Printf("Execution history pruning failed: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	log.Printf("Execution history pruning started (interval: %s, dry_run: %v)", interval, s.config.Retention.DryRun)
+}
+
+// Stop 停止后台清理循环
+func (s *RetentionService) Stop() {
+	s.mu.Lock()
+	stopCh, doneCh := s.stopCh, s.doneCh
+	s.stopCh, s.doneCh = nil, nil
+	s.mu.Unlock()
+
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	<-doneCh
+}
+
+// LastReport 获取最近一次清理报告
+func (s *RetentionService) LastReport() *PruneReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastReport
+}
+
+// Prune 按保留策略清理执行记录，dryRun 为 true 时只统计不删除；
+// 已有清理正在进行时直接返回 ErrPruneInProgress
+func (s *RetentionService) Prune(dryRun bool) (*PruneReport, error) {
+	if !s.pruneMu.TryLock() {
+		return nil, ErrPruneInProgress
+	}
+	defer s.pruneMu.Unlock()
+
+	report := &PruneReport{
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+		Vacuum:    "none",
+		Tasks:     []TaskPruneResult{},
+	}
+
+	err := s.prune(report)
+	report.CompletedAt = time.Now()
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	// 预览请求不覆盖后台任务的实际清理结果
+	if !dryRun || s.lastReport == nil || s.lastReport.DryRun {
+		s.lastReport = report
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return report, err
+	}
+
+	if report.TotalDeleted > 0 {
+		action := "Pruned"
+		if dryRun {
+			action = "Would prune"
+		}
+		log.Printf("%s %d execution records (vacuum: %s)", action, report.TotalDeleted, report.Vacuum)
+	}
+	return report, nil
+}
+
+func (s *RetentionService) prune(report *PruneReport) error {
+	db := database.GetDB()
+
+	// 包括已删除任务遗留的执行记录
+	var taskIDs []uint
+	if err := db.Model(&models.TaskExecution{}).Distinct("task_id").Pluck("task_id", &taskIDs).Error; err != nil {
+		return fmt.Errorf("failed to list tasks with executions: %w", err)
+	}
+
+	for _, taskID := range taskIDs {
+		var task models.Task
+		if err := db.Unscoped().Select("id", "name", "retention_days", "retention_max_rows").First(&task, taskID).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to load task %d: %w", taskID, err)
+		}
+
+		result := TaskPruneResult{
+			TaskID:     taskID,
+			TaskName:   task.Name,
+			MaxAgeDays: s.config.Retention.MaxAgeDays,
+			MaxRows:    s.config.Retention.MaxRowsPerTask,
+		}
+		if task.RetentionDays > 0 {
+			result.MaxAgeDays = task.RetentionDays
+		}
+		if task.RetentionMaxRows > 0 {
+			result.MaxRows = task.RetentionMaxRows
+		}
+
+		if err := s.pruneTask(&result, report.DryRun); err != nil {
+			return err
+		}
+
+		if result.DeletedByAge > 0 || result.DeletedByMax > 0 {
+			report.Tasks = append(report.Tasks, result)
+			report.TotalDeleted += result.DeletedByAge + result.DeletedByMax
+		}
+	}
+
+	if !report.DryRun && report.TotalDeleted > 0 {
+		mode, err := s.vacuum()
+		if err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		report.Vacuum = mode
+	}
+
+	return nil
+}
+
+// pruneTask 清理单个任务的执行记录，正在运行的记录永远不会被清理
+func (s *RetentionService) pruneTask(result *TaskPruneResult, dryRun bool) error {
+	db := database.GetDB()
+
+	var cutoff time.Time
+	if result.MaxAgeDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -result.MaxAgeDays)
+	}
+
+	// 按保留天数清理
+	if !cutoff.IsZero() {
+		query := db.Where("task_id = ? AND status <> ? AND started_at < ?", result.TaskID, "running", cutoff)
+		if dryRun {
+			if err := query.Model(&models.TaskExecution{}).Count(&result.DeletedByAge).Error; err != nil {
+				return fmt.Errorf("failed to count expired executions for task %d: %w", result.TaskID, err)
+			}
+		} else {
+			res := query.Delete(&models.TaskExecution{})
+			if res.Error != nil {
+				return fmt.Errorf("failed to prune expired executions for task %d: %w", result.TaskID, res.Error)
+			}
+			result.DeletedByAge = res.RowsAffected
+		}
+	}
+
+	// 按最大条数清理，保留最新的 MaxRows 条
+	if result.MaxRows > 0 {
+		keep := db.Model(&models.TaskExecution{}).Select("id").
+			Where("task_id = ?", result.TaskID).
+			Order("started_at DESC").
+			Limit(result.MaxRows)
+		query := db.Where("task_id = ? AND status <> ? AND id NOT IN (?)", result.TaskID, "running", keep)
+		if dryRun {
+			// 预览时排除已被按天数规则统计过的记录
+			if !cutoff.IsZero() {
+				query = query.Where("started_at >= ?", cutoff)
+			}
+			if err := query.Model(&models.TaskExecution{}).Count(&result.DeletedByMax).Error; err != nil {
+				return fmt.Errorf("failed to count excess executions for task %d: %w", result.TaskID, err)
+			}
+		} else {
+			res := query.Delete(&models.TaskExecution{})
+			if res.Error != nil {
+				return fmt.Errorf("failed to prune excess executions for task %d: %w", result.TaskID, res.Error)
+			}
+			result.DeletedByMax = res.RowsAffected
+		}
+	}
+
+	return nil
+}
+
+// vacuum 回收 SQLite 空闲页，返回实际使用的方式
+func (s *RetentionService) vacuum() (string, error) {
+	db := database.GetDB()
+
+	switch s.config.Retention.Vacuum {
+	case "full":
+		return "full", db.Exec("VACUUM").Error
+	case "incremental":
+		var autoVacuum int
+		if err := db.Raw("PRAGMA auto_vacuum").Scan(&autoVacuum).Error; err != nil {
+			return "", err
+		}
+		// 2 = INCREMENTAL；切换模式需要在同一连接上执行一次完整 VACUUM 才会生效
+		if autoVacuum != 2 {
+			return "full", db.Connection(func(conn *gorm.DB) error {
+				if err := conn.Exec("PRAGMA auto_vacuum = INCREMENTAL").Error; err != nil {
+					return err
+				}
+				return conn.Exec("VACUUM").Error
+			})
+		}
+		return "incremental", db.Exec("PRAGMA incremental_vacuum").Error
+	default:
+		return "none", nil
+	}
+}
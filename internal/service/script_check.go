@@ -0,0 +1,226 @@
+package service
+
+import (
+	"b1cron/internal/models"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scriptCheckTimeout 单个语法检查或静态检查命令的超时时间
+const scriptCheckTimeout = 10 * time.Second
+
+// ScriptDiagnostic 语法检查或静态检查发现的一个问题，行号对应用户编写的内容（不含自动生成的头部）
+type ScriptDiagnostic struct {
+	Line     int    `json:"line"` // 从 1 开始，0 表示无法对应到具体的行
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"` // error, warning, info
+	Message  string `json:"message"`
+	Source   string `json:"source"` // 报告问题的程序，例如 bash、shellcheck
+}
+
+// ScriptCheckError 脚本没有通过语法检查
+type ScriptCheckError struct {
+	Diagnostics []ScriptDiagnostic
+}
+
+func (e *ScriptCheckError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return "script has syntax errors"
+	}
+	first := e.Diagnostics[0]
+	if first.Line > 0 {
+		return fmt.Sprintf("script has syntax errors: line %d: %s", first.Line, first.Message)
+	}
+	return "script has syntax errors: " + first.Message
+}
+
+// diagnosticPatterns 常见检查程序输出的位置格式，临时脚本文件名为 script.<扩展名>
+var diagnosticPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`script\.\w+:(\d+)(?::(\d+))?(?::\s*(.*))?$`), // gcc 格式：shellcheck -f gcc、node --check
+	regexp.MustCompile(`script\.\w+: line (\d+):()\s*(.*)$`),         // bash -n
+	regexp.MustCompile(`File ".*script\.\w+", line (\d+)()()`),       // python
+}
+
+// quotedSourcePattern bash 在错误之后单独输出一行出错的源码，例如 "line 2: `fi'"
+var quotedSourcePattern = regexp.MustCompile("^`.*'$")
+
+// diagnosticMessagePattern 位置之后单独一行的错误描述，例如 python 的 "SyntaxError: ..."
+var diagnosticMessagePattern = regexp.MustCompile(`^(\w*(Error|Exception)|error|warning)\b`)
+
+// diagnosticSeverityPattern gcc 格式中问题描述前的级别
+var diagnosticSeverityPattern = regexp.MustCompile(`^(error|warning|note|info|style):\s*`)
+
+// scriptFileContent 返回保存后的脚本文件内容，以及自动生成的头部占用的行数
+func (s *ScriptFileService) scriptFileContent(scriptType, content string) (string, int) {
+	if s.hasScriptHeader(content) {
+		return content, 0
+	}
+	header := s.generateScriptContent(0, "", scriptType, "")
+	return header + content, strings.Count(header, "\n")
+}
+
+// CheckScriptSyntax 用运行时的语法检查命令检查脚本，未通过时返回 *ScriptCheckError
+func (s *ScriptFileService) CheckScriptSyntax(scriptType, content string) error {
+	runtime, ok := s.runtime(scriptType)
+	if !ok || runtime.Check == "" {
+		return nil
+	}
+	diagnostics, failed, err := s.runScriptTool(runtime.Check, scriptType, content, "error")
+	if err != nil {
+		return err
+	}
+	if failed {
+		return &ScriptCheckError{Diagnostics: diagnostics}
+	}
+	return nil
+}
+
+// LintScript 依次执行语法检查和静态检查，返回全部问题；语法检查未通过时不再执行静态检查
+func (s *ScriptFileService) LintScript(scriptType, content string) ([]ScriptDiagnostic, error) {
+	diagnostics := []ScriptDiagnostic{}
+	runtime, ok := s.runtime(scriptType)
+	if !ok {
+		return diagnostics, nil
+	}
+
+	if runtime.Check != "" {
+		found, failed, err := s.runScriptTool(runtime.Check, scriptType, content, "error")
+		if err != nil {
+			return nil, err
+		}
+		if failed {
+			return append(diagnostics, found...), nil
+		}
+	}
+	if runtime.Lint != "" {
+		found, _, err := s.runScriptTool(runtime.Lint, scriptType, content, "warning")
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+	return diagnostics, nil
+}
+
+// runScriptTool 把脚本写入临时目录后执行检查命令并解析输出。
+// 程序没有安装时跳过；failed 表示检查命令以非零状态退出
+func (s *ScriptFileService) runScriptTool(template, scriptType, content, severity string) ([]ScriptDiagnostic, bool, error) {
+	fields := strings.Fields(template)
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, false, nil
+	}
+
+	dir, err := os.MkdirTemp("", "b1cron-check-")
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileContent, offset := s.scriptFileContent(scriptType, content)
+	path := filepath.Join(dir, "script."+s.getScriptExtension(scriptType))
+	if err := os.WriteFile(path, []byte(fileContent), 0644); err != nil {
+		return nil, false, fmt.Errorf("failed to write temporary script: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scriptCheckTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", strings.ReplaceAll(template, "{file}", shellQuote(path)))
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return []ScriptDiagnostic{{Severity: severity, Message: fmt.Sprintf("check timed out after %v", scriptCheckTimeout), Source: fields[0]}}, true, nil
+	}
+	failed := err != nil
+
+	// 输出中的临时目录对用户没有意义
+	output = []byte(strings.ReplaceAll(string(output), dir+string(filepath.Separator), ""))
+	diagnostics := parseDiagnostics(string(output), offset, severity, fields[0])
+	if failed && len(diagnostics) == 0 {
+		message := strings.TrimSpace(string(output))
+		if message == "" {
+			message = err.Error()
+		}
+		diagnostics = append(diagnostics, ScriptDiagnostic{Severity: severity, Message: message, Source: fields[0]})
+	}
+	return diagnostics, failed, nil
+}
+
+// parseDiagnostics 从检查程序的输出中提取带行号的问题，行号减去自动生成的头部的行数
+func parseDiagnostics(output string, offset int, severity, source string) []ScriptDiagnostic {
+	var diagnostics []ScriptDiagnostic
+	pending := -1 // 位置之后还没有找到描述的问题
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for _, text := range lines {
+		trimmed := strings.TrimSpace(text)
+		if pending >= 0 && diagnosticMessagePattern.MatchString(trimmed) {
+			diagnostics[pending].Message = trimmed
+			pending = -1
+			continue
+		}
+
+		for _, pattern := range diagnosticPatterns {
+			match := pattern.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			line, _ := strconv.Atoi(match[1])
+			column, _ := strconv.Atoi(match[2])
+			diagnostic := ScriptDiagnostic{Line: line - offset, Column: column, Severity: severity, Message: match[3], Source: source}
+			if diagnostic.Line < 1 {
+				diagnostic.Line, diagnostic.Column = 0, 0
+			}
+			if level := diagnosticSeverityPattern.FindStringSubmatch(diagnostic.Message); level != nil {
+				diagnostic.Severity = map[string]string{"note": "info", "style": "info"}[level[1]]
+				if diagnostic.Severity == "" {
+					diagnostic.Severity = level[1]
+				}
+				diagnostic.Message = diagnostic.Message[len(level[0]):]
+			}
+			if n := len(diagnostics); n > 0 && diagnostics[n-1].Line == diagnostic.Line && quotedSourcePattern.MatchString(diagnostic.Message) {
+				break
+			}
+			diagnostics = append(diagnostics, diagnostic)
+			if diagnostic.Message == "" {
+				pending = len(diagnostics) - 1
+			}
+			break
+		}
+	}
+
+	// 没有找到单独一行的描述时使用输出的最后一行
+	if pending >= 0 {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if text := strings.TrimSpace(lines[i]); text != "" {
+				diagnostics[pending].Message = text
+				break
+			}
+		}
+	}
+	return diagnostics
+}
+
+// checkTaskScript 在保存前检查脚本语法；更新时只有脚本内容或类型改变才检查，切换启用状态等操作不受已有脚本影响
+func (s *TaskService) checkTaskScript(task *models.Task, scriptType, command string) error {
+	if task != nil && scriptType == task.ScriptType && command == s.GetTaskScriptContent(task) {
+		return nil
+	}
+	return s.scriptService.CheckScriptSyntax(scriptType, command)
+}
+
+// ValidateScript 检查脚本内容，返回语法检查和静态检查发现的问题
+func (s *TaskService) ValidateScript(scriptType, command string) ([]ScriptDiagnostic, error) {
+	if err := s.scriptService.ValidateScriptType(scriptType); err != nil {
+		return nil, err
+	}
+	return s.scriptService.LintScript(scriptType, command)
+}
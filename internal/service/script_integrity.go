@@ -0,0 +1,157 @@
+package service
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ScriptChecksum 脚本内容的 SHA-256 校验和
+func ScriptChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// MaterializeScript 实现 scheduler.ScriptMaterializer：从数据库读取任务最新的执行命令和脚本内容，
+// 脚本任务校验内容后写入本次执行的临时目录。内容与保存时的校验和不一致时拒绝执行并记录审计日志。
+// 任务引用的共享库模块写入临时目录下的 lib 目录，并加入 PATH 和 PYTHONPATH
+func (s *TaskService) MaterializeScript(taskID uint, dir string, environ []string) (string, []string, error) {
+	var task models.Task
+	if err := database.GetDB().Select("id", "name", "command", "script_type", "script_content", "script_checksum", "libraries").First(&task, taskID).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	command := task.Command
+	if task.ScriptType != "command" {
+		if task.ScriptContent == "" {
+			return "", nil, fmt.Errorf("task %d has no script content", task.ID)
+		}
+		if err := s.verifyScriptChecksum(&task); err != nil {
+			return "", nil, err
+		}
+		var err error
+		if command, err = s.scriptService.MaterializeScript(dir, task.ID, task.ScriptType, task.ScriptContent); err != nil {
+			return "", nil, err
+		}
+	}
+
+	libDir, err := s.libraries.Materialize(task.Libraries, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	if libDir != "" {
+		environ = append(environ,
+			"B1CRON_LIB="+libDir,
+			"PATH="+prependPathList(libDir, lookupEnv(environ, "PATH")),
+			"PYTHONPATH="+prependPathList(libDir, lookupEnv(environ, "PYTHONPATH")),
+		)
+	}
+	return command, environ, nil
+}
+
+// verifyScriptChecksum 检查脚本内容与保存时的校验和是否一致，不一致时记录审计日志
+func (s *TaskService) verifyScriptChecksum(task *models.Task) error {
+	actual := ScriptChecksum(task.ScriptContent)
+	if task.ScriptChecksum == "" || actual == task.ScriptChecksum {
+		return nil
+	}
+
+	err := fmt.Errorf("script content does not match its checksum (expected %.12s, found %.12s); it was modified outside B1Cron", task.ScriptChecksum, actual)
+	log.Printf("Warning: script of task '%s' (ID: %d) was tampered with: %v", task.Name, task.ID, err)
+	audit.Record(audit.Entry{
+		Action:     audit.ActionTaskScriptTampered,
+		ActorName:  "system",
+		TargetType: audit.TargetTask,
+		TargetID:   task.ID,
+		TargetName: task.Name,
+		Before:     audit.Snapshot{"script_checksum": task.ScriptChecksum},
+		After:      audit.Snapshot{"script_checksum": actual},
+	})
+	return err
+}
+
+// lookupEnv 获取环境变量列表中最后一个同名变量的值（执行命令时以最后一个为准）
+func lookupEnv(environ []string, key string) string {
+	value := ""
+	for _, entry := range environ {
+		if v, ok := strings.CutPrefix(entry, key+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
+
+// prependPathList 把目录放在路径列表最前面
+func prependPathList(dir, list string) string {
+	if list == "" {
+		return dir
+	}
+	return dir + string(os.PathListSeparator) + list
+}
+
+// MigrateScriptFiles 把旧版本保存在数据目录中的脚本文件内容迁移到任务记录，迁移后不再读取这些文件。
+// 已经记录了校验和的任务保留原校验和，迁移前在 B1Cron 之外被修改的文件在执行时仍会被发现
+func (s *TaskService) MigrateScriptFiles() error {
+	var tasks []models.Task
+	if err := database.GetDB().Where("script_type <> ? AND script_path <> '' AND (script_content = '' OR script_content IS NULL)", "command").Find(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to get tasks with script files: %w", err)
+	}
+
+	migrated := 0
+	for i := range tasks {
+		task := &tasks[i]
+		content, err := s.scriptService.GetScriptContent(task.ScriptPath)
+		if err != nil {
+			log.Printf("Warning: failed to migrate script of task %d: %v", task.ID, err)
+			continue
+		}
+		_, execCommand, err := s.scriptService.BuildScript(task.ID, task.Name, task.ScriptType, content)
+		if err != nil {
+			log.Printf("Warning: failed to migrate script of task %d: %v", task.ID, err)
+			continue
+		}
+
+		checksum := task.ScriptChecksum
+		if checksum == "" {
+			checksum = ScriptChecksum(content)
+		}
+		if err := database.GetDB().Model(task).UpdateColumns(map[string]interface{}{
+			"script_content":  content,
+			"script_checksum": checksum,
+			"command":         execCommand,
+			"script_path":     "",
+		}).Error; err != nil {
+			return fmt.Errorf("failed to migrate script of task %d: %w", task.ID, err)
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Migrated scripts of %d tasks into the database; the files under the data directory are no longer used", migrated)
+	}
+	return nil
+}
+
+// buildTaskScript 生成更新后的脚本内容、执行命令和校验和；内容和类型都没有变化时保留原校验和，
+// 在 B1Cron 之外被修改的脚本不会因为启用/禁用等操作被当作正常内容
+func (s *TaskService) buildTaskScript(task *models.Task, name, scriptType, command string) (string, string, string, error) {
+	if scriptType != "command" && scriptType == task.ScriptType && command == task.ScriptContent {
+		_, execCommand, err := s.scriptService.BuildScript(task.ID, name, scriptType, command)
+		return task.ScriptContent, execCommand, task.ScriptChecksum, err
+	}
+
+	content, execCommand, err := s.scriptService.BuildScript(task.ID, name, scriptType, command)
+	if err != nil {
+		return "", "", "", err
+	}
+	checksum := ""
+	if content != "" {
+		checksum = ScriptChecksum(content)
+	}
+	return content, execCommand, checksum, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,8 +14,9 @@ import (
 
 // ScriptFileService 脚本文件管理服务
 type ScriptFileService struct {
-	dataDir string
-	config  *config.Config
+	dataDir  string
+	config   *config.Config
+	runtimes []config.RuntimeConfig
 }
 
 // NewScriptFileService 创建脚本文件服务
@@ -22,166 +25,157 @@ func NewScriptFileService(cfg *config.Config) *ScriptFileService {
 	if cfg != nil && cfg.Database.Path != "" {
 		dataDir = filepath.Dir(cfg.Database.Path)
 	}
+	runtimes := config.DefaultRuntimes()
+	if cfg != nil && len(cfg.Runtimes) > 0 {
+		runtimes = cfg.Runtimes
+	}
 	return &ScriptFileService{
-		dataDir: dataDir,
-		config:  cfg,
+		dataDir:  dataDir,
+		config:   cfg,
+		runtimes: runtimes,
 	}
 }
 
-// CreateScriptFile 创建脚本文件
-func (s *ScriptFileService) CreateScriptFile(taskID uint, taskName, scriptType, content string) (string, string, error) {
+// BuildScript 生成保存到任务记录中的完整脚本内容和用于显示的执行命令；
+// 内容已经包含头部注释时直接使用，否则添加头部注释。直接命令原样返回
+func (s *ScriptFileService) BuildScript(taskID uint, taskName, scriptType, content string) (string, string, error) {
 	if scriptType == "command" {
 		return "", content, nil
 	}
 
-	// 创建脚本目录
-	scriptDir := filepath.Join(s.dataDir, scriptType)
-	if err := os.MkdirAll(scriptDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create script directory: %w", err)
-	}
-
-	// 生成文件名和路径
-	ext := s.getScriptExtension(scriptType)
-	if ext == "" {
-		return "", "", fmt.Errorf("unsupported script type: %s", scriptType)
+	filename, err := s.scriptFileName(taskID, scriptType)
+	if err != nil {
+		return "", "", err
 	}
-
-	filename := fmt.Sprintf("task_%d.%s", taskID, ext)
-	scriptPath := filepath.Join(scriptType, filename)
-	fullPath := filepath.Join(s.dataDir, scriptPath)
-
-	// 生成脚本内容
-	scriptContent := s.generateScriptContent(taskID, taskName, scriptType, content)
-
-	// 写入脚本文件
-	if err := os.WriteFile(fullPath, []byte(scriptContent), 0755); err != nil {
-		return "", "", fmt.Errorf("failed to write script file: %w", err)
+	if !s.hasScriptHeader(content) {
+		content = s.generateScriptContent(taskID, taskName, scriptType, content)
 	}
-
-	// 生成执行命令
-	execCommand := s.generateExecCommand(scriptType, fullPath)
-
-	return scriptPath, execCommand, nil
+	return content, s.generateExecCommand(scriptType, filename), nil
 }
 
-// UpdateScriptFile 更新脚本文件
-func (s *ScriptFileService) UpdateScriptFile(taskID uint, taskName, scriptType, content, oldScriptPath string) (string, string, error) {
-	// 删除旧脚本文件
-	if oldScriptPath != "" {
-		s.DeleteScriptFile(oldScriptPath)
-	}
-
-	// 更新脚本文件时，检查内容是否已经包含头部注释
-	// 如果包含我们的头部注释格式，直接使用；否则添加头部注释
-	if s.hasScriptHeader(content) {
-		return s.CreateScriptFileWithContent(taskID, scriptType, content)
-	} else {
-		return s.CreateScriptFile(taskID, taskName, scriptType, content)
+// MaterializeScript 把脚本写入本次执行的临时目录，返回执行命令
+func (s *ScriptFileService) MaterializeScript(dir string, taskID uint, scriptType, content string) (string, error) {
+	filename, err := s.scriptFileName(taskID, scriptType)
+	if err != nil {
+		return "", err
 	}
-}
-
-// CreateScriptFileWithContent 使用完整内容创建脚本文件（不添加头部注释）
-func (s *ScriptFileService) CreateScriptFileWithContent(taskID uint, scriptType, content string) (string, string, error) {
-	if scriptType == "command" {
-		return "", content, nil
+	fullPath, err := filepath.Abs(filepath.Join(dir, filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve script path: %w", err)
 	}
-
-	// 创建脚本目录
-	scriptDir := filepath.Join(s.dataDir, scriptType)
-	if err := os.MkdirAll(scriptDir, 0755); err != nil {
-		return "", "", fmt.Errorf("failed to create script directory: %w", err)
+	if err := os.WriteFile(fullPath, []byte(content), 0755); err != nil {
+		return "", fmt.Errorf("failed to write script file: %w", err)
 	}
+	return s.generateExecCommand(scriptType, fullPath), nil
+}
 
-	// 生成文件名和路径
+// scriptFileName 执行时脚本文件的名称 task_<任务ID>.<扩展名>
+func (s *ScriptFileService) scriptFileName(taskID uint, scriptType string) (string, error) {
 	ext := s.getScriptExtension(scriptType)
 	if ext == "" {
-		return "", "", fmt.Errorf("unsupported script type: %s", scriptType)
+		return "", fmt.Errorf("unsupported script type: %s", scriptType)
 	}
+	return fmt.Sprintf("task_%d.%s", taskID, ext), nil
+}
 
-	filename := fmt.Sprintf("task_%d.%s", taskID, ext)
-	scriptPath := filepath.Join(scriptType, filename)
-	fullPath := filepath.Join(s.dataDir, scriptPath)
+// hasScriptHeader 检查内容是否已包含我们的脚本头部注释
+func (s *ScriptFileService) hasScriptHeader(content string) bool {
+	return strings.Contains(content, "B1Cron Task:") ||
+		strings.Contains(content, "Task ID:")
+}
 
-	// 直接写入用户提供的完整内容
-	if err := os.WriteFile(fullPath, []byte(content), 0755); err != nil {
-		return "", "", fmt.Errorf("failed to write script file: %w", err)
+// runtime 获取脚本类型对应的运行时
+func (s *ScriptFileService) runtime(scriptType string) (*config.RuntimeConfig, bool) {
+	for i := range s.runtimes {
+		if s.runtimes[i].Name == scriptType {
+			return &s.runtimes[i], true
+		}
 	}
-
-	// 生成执行命令
-	execCommand := s.generateExecCommand(scriptType, fullPath)
-
-	return scriptPath, execCommand, nil
+	return nil, false
 }
 
-// hasScriptHeader 检查内容是否已包含我们的脚本头部注释
-func (s *ScriptFileService) hasScriptHeader(content string) bool {
-	return strings.Contains(content, "# B1Cron Task:") || 
-		   strings.Contains(content, "Task ID:")
+// Runtimes 返回所有可用的脚本运行时，顺序与配置一致
+func (s *ScriptFileService) Runtimes() []config.RuntimeConfig {
+	return s.runtimes
 }
 
-// DeleteScriptFile 删除脚本文件
-func (s *ScriptFileService) DeleteScriptFile(scriptPath string) error {
-	if scriptPath == "" {
+// ValidateScriptType 检查脚本类型是 command 或已注册的运行时
+func (s *ScriptFileService) ValidateScriptType(scriptType string) error {
+	if scriptType == "command" {
 		return nil
 	}
-
-	fullPath := filepath.Join(s.dataDir, scriptPath)
-	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete script file: %w", err)
+	if _, ok := s.runtime(scriptType); !ok {
+		return fmt.Errorf("unsupported script type: %s", scriptType)
 	}
-
 	return nil
 }
 
 // getScriptExtension 获取脚本文件扩展名
 func (s *ScriptFileService) getScriptExtension(scriptType string) string {
-	extensions := map[string]string{
-		"shell":  "sh",
-		"python": "py",
+	runtime, ok := s.runtime(scriptType)
+	if !ok {
+		return ""
 	}
-	return extensions[scriptType]
+	return runtime.Extension
 }
 
 // generateScriptContent 生成脚本内容
 func (s *ScriptFileService) generateScriptContent(taskID uint, taskName, scriptType, userContent string) string {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	
-	var header string
-	switch scriptType {
-	case "shell":
-		header = fmt.Sprintf(`#!/bin/bash
-# B1Cron Task: %s
-# Created: %s
-# Task ID: %d
-
-`, taskName, timestamp, taskID)
-	case "python":
-		header = fmt.Sprintf(`#!/usr/bin/env python3
-# -*- coding: utf-8 -*-
-# B1Cron Task: %s
-# Created: %s
-# Task ID: %d
-
-`, taskName, timestamp, taskID)
-	default:
+	runtime, ok := s.runtime(scriptType)
+	if !ok {
 		return userContent
 	}
 
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	header := strings.NewReplacer(
+		"{name}", taskName,
+		"{created}", timestamp,
+		"{id}", strconv.FormatUint(uint64(taskID), 10),
+	).Replace(runtime.Header)
+
 	return header + userContent
 }
 
+// headerPattern 把运行时的头部模板转换为匹配已生成头部的正则表达式，末尾的空行可有可无
+func headerPattern(header string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(strings.TrimRight(header, "\n"))
+	pattern = strings.NewReplacer(
+		`\{name\}`, `.*`,
+		`\{created\}`, `.*`,
+		`\{id\}`, `\d+`,
+		"\n", `\r?\n`,
+	).Replace(pattern)
+	return regexp.MustCompile(`^` + pattern + `(\r?\n|$)([ \t]*\r?\n)?`)
+}
+
+// StripScriptHeader 去掉 generateScriptContent 生成的头部注释，只保留用户编写的内容；
+// 内容不是以生成的头部开头时原样返回
+func (s *ScriptFileService) StripScriptHeader(scriptType, content string) string {
+	runtime, ok := s.runtime(scriptType)
+	if !ok || strings.TrimSpace(runtime.Header) == "" {
+		return content
+	}
+	if loc := headerPattern(runtime.Header).FindStringIndex(content); loc != nil {
+		return content[loc[1]:]
+	}
+	return content
+}
+
 // generateExecCommand 生成执行命令
 func (s *ScriptFileService) generateExecCommand(scriptType, fullPath string) string {
-	switch scriptType {
-	case "shell":
-		return fmt.Sprintf("/bin/bash %s", fullPath)
-	case "python":
-		// 使用 python3 命令，依赖 shell 的 PATH 环境变量查找
-		// 在Docker Alpine环境中更加可靠
-		return fmt.Sprintf("python3 %s", fullPath)
-	default:
+	runtime, ok := s.runtime(scriptType)
+	if !ok {
 		return fullPath
 	}
+	return strings.ReplaceAll(runtime.Command, "{file}", shellQuote(fullPath))
+}
+
+// shellQuote 路径中含有 shell 特殊字符时加上单引号
+func shellQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:@%+=,") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
 }
 
 // ValidateScriptContent 验证脚本内容
@@ -195,15 +189,10 @@ func (s *ScriptFileService) ValidateScriptContent(scriptType, content string) er
 		return fmt.Errorf("script content too large (max 1MB)")
 	}
 
-	// 基本安全检查：禁止包含可能的路径遍历
-	if strings.Contains(content, "../") {
-		return fmt.Errorf("script content contains potentially unsafe path traversal")
-	}
-
 	return nil
 }
 
-// GetScriptContent 读取脚本文件内容
+// GetScriptContent 读取旧版本保存在数据目录中的脚本文件内容，用于迁移到数据库
 func (s *ScriptFileService) GetScriptContent(scriptPath string) (string, error) {
 	if scriptPath == "" {
 		return "", nil
@@ -217,4 +206,4 @@ func (s *ScriptFileService) GetScriptContent(scriptPath string) (string, error)
 
 	// 直接返回完整的文件内容，包括头部注释
 	return string(content), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"time"
+)
+
+// SessionService 登录会话管理服务
+type SessionService struct{}
+
+func NewSessionService() *SessionService {
+	return &SessionService{}
+}
+
+// ListSessions 获取用户未过期的会话，最近活动的在前
+func (s *SessionService) ListSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := database.GetDB().
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession 删除用户的某个会话，使用该会话的 JWT 立即失效
+func (s *SessionService) RevokeSession(userID uint, sessionID string) error {
+	result := database.GetDB().Where("id = ? AND user_id = ?", sessionID, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("session not found")
+	}
+	return nil
+}
+
+// RevokeUserSessions 删除用户除 keepID 以外的全部会话（keepID 为空时全部删除），返回删除的数量
+func (s *SessionService) RevokeUserSessions(userID uint, keepID string) (int64, error) {
+	result := database.GetDB().Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
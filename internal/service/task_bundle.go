@@ -0,0 +1,624 @@
+package service
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TaskBundleVersion 任务定义包的格式版本
+const TaskBundleVersion = 1
+
+// 导入模式：merge 只新增和更新包中的任务，replace 还会删除包中没有的任务
+const (
+	ImportModeMerge   = "merge"
+	ImportModeReplace = "replace"
+)
+
+// 导入计划中每个任务的动作
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportDelete    = "delete"
+	ImportUnchanged = "unchanged"
+	ImportDisable   = "disable" // GitOps：任务已从清单中移除
+)
+
+// maxSlugLength slug 的最大长度
+const maxSlugLength = 64
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	slugSeparator = regexp.MustCompile(`[^a-z0-9]+`)
+	envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ErrSlugTaken slug 已被其他任务使用
+var ErrSlugTaken = errors.New("slug is already used by another task")
+
+// ErrTaskManaged 任务由 GitOps 清单管理，只能通过修改清单变更
+var ErrTaskManaged = errors.New("task is managed by a GitOps manifest and is read-only")
+
+// TaskBundle 可以提交到 git 的任务定义包
+type TaskBundle struct {
+	Version    int          `yaml:"version" json:"version"`
+	ExportedAt time.Time    `yaml:"exported_at,omitempty" json:"exported_at,omitempty"`
+	Tasks      []BundleTask `yaml:"tasks" json:"tasks"`
+}
+
+// BundleTask 任务定义包中的单个任务，以 slug 作为跨实例的稳定标识
+type BundleTask struct {
+	Slug             string            `yaml:"slug" json:"slug"`
+	Name             string            `yaml:"name" json:"name"`
+	Namespace        string            `yaml:"namespace,omitempty" json:"namespace,omitempty"` // 为空时使用默认命名空间
+	ScriptType       string            `yaml:"script_type,omitempty" json:"script_type,omitempty"`
+	ScheduleType     string            `yaml:"schedule_type,omitempty" json:"schedule_type,omitempty"`
+	Schedule         string            `yaml:"schedule,omitempty" json:"schedule,omitempty"`
+	ExecuteAt        *time.Time        `yaml:"execute_at,omitempty" json:"execute_at,omitempty"`
+	Enabled          *bool             `yaml:"enabled,omitempty" json:"enabled,omitempty"` // 未填写时为启用
+	Env              map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Requirements     string            `yaml:"requirements,omitempty" json:"requirements,omitempty"` // Python 任务的 pip 依赖，每行一个
+	Libraries        []string          `yaml:"libraries,omitempty" json:"libraries,omitempty"`       // 共享库模块，name 或 name@version
+	RetentionDays    int               `yaml:"retention_days,omitempty" json:"retention_days,omitempty"`
+	RetentionMaxRows int               `yaml:"retention_max_rows,omitempty" json:"retention_max_rows,omitempty"`
+	Script           string            `yaml:"script" json:"script"` // 脚本内容（不含自动生成的头部），command 类型为命令本身
+}
+
+// ImportItem 导入计划中的一项
+type ImportItem struct {
+	Slug       string                       `json:"slug"`
+	Action     string                       `json:"action"`
+	TaskID     uint                         `json:"task_id,omitempty"`
+	Name       string                       `json:"name"`
+	Changes    map[string]audit.FieldChange `json:"changes,omitempty"`     // 脚本以外发生变化的字段
+	ScriptDiff []DiffLine                   `json:"script_diff,omitempty"` // 脚本内容的逐行差异
+	Error      string                       `json:"error,omitempty"`
+	Manifest   string                       `json:"manifest,omitempty"` // GitOps 同步时任务所在的清单文件
+
+	desired     *BundleTask
+	namespaceID uint
+}
+
+// ImportPlan 导入任务定义包的变更计划
+type ImportPlan struct {
+	Mode    string         `json:"mode"`
+	DryRun  bool           `json:"dry_run"`
+	Items   []ImportItem   `json:"items"`
+	Summary map[string]int `json:"summary"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// Slugify 由任务名称生成 slug，只保留小写字母、数字和连字符
+func Slugify(name string) string {
+	slug := strings.Trim(slugSeparator.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > maxSlugLength-4 {
+		slug = strings.TrimRight(slug[:maxSlugLength-4], "-")
+	}
+	return slug
+}
+
+// ValidateSlug 检查 slug 格式
+func ValidateSlug(slug string) error {
+	if len(slug) > maxSlugLength || !slugPattern.MatchString(slug) {
+		return fmt.Errorf("invalid slug %q: use lowercase letters, digits and hyphens (max %d characters)", slug, maxSlugLength)
+	}
+	return nil
+}
+
+// ValidateEnv 检查环境变量名称
+func ValidateEnv(env map[string]string) error {
+	for key := range env {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+	}
+	return nil
+}
+
+// slugInUse slug 是否已被 excludeID 以外的任务使用
+func slugInUse(slug string, excludeID uint) (bool, error) {
+	var count int64
+	if err := database.GetDB().Model(&models.Task{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check task slug: %w", err)
+	}
+	return count > 0, nil
+}
+
+// uniqueSlug 在 base 后追加序号直到不与其他任务冲突，base 为空时使用 task-<id>
+func (s *TaskService) uniqueSlug(base string, taskID uint) string {
+	if base == "" {
+		base = fmt.Sprintf("task-%d", taskID)
+	}
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := slugInUse(candidate, taskID)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			return fmt.Sprintf("task-%d", taskID)
+		}
+		if !taken {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// CheckSlug 检查 slug 格式以及是否已被其他任务使用
+func (s *TaskService) CheckSlug(slug string, excludeID uint) error {
+	if err := ValidateSlug(slug); err != nil {
+		return err
+	}
+	taken, err := slugInUse(slug, excludeID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", ErrSlugTaken, slug)
+	}
+	return nil
+}
+
+// SetTaskSlug 修改任务的 slug
+func (s *TaskService) SetTaskSlug(task *models.Task, slug string) error {
+	if slug == task.Slug {
+		return nil
+	}
+	if err := s.CheckSlug(slug, task.ID); err != nil {
+		return err
+	}
+	if err := database.GetDB().Model(task).UpdateColumn("slug", slug).Error; err != nil {
+		return fmt.Errorf("failed to update task slug: %w", err)
+	}
+	task.Slug = slug
+	return nil
+}
+
+// SetTaskEnv 修改任务的环境变量，下一次执行时生效
+func (s *TaskService) SetTaskEnv(task *models.Task, env models.EnvVars) error {
+	if err := ValidateEnv(env); err != nil {
+		return err
+	}
+	if len(env) == 0 {
+		env = nil
+	}
+	if err := database.GetDB().Model(task).UpdateColumn("env", env).Error; err != nil {
+		return fmt.Errorf("failed to update task env: %w", err)
+	}
+	task.Env = env
+	return nil
+}
+
+// EnsureTaskSlugs 为还没有 slug 的任务（升级前创建的任务）生成 slug
+func (s *TaskService) EnsureTaskSlugs() error {
+	var tasks []models.Task
+	if err := database.GetDB().Where("slug = '' OR slug IS NULL").Order("id").Find(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to get tasks without slugs: %w", err)
+	}
+
+	for i := range tasks {
+		slug := s.uniqueSlug(Slugify(tasks[i].Name), tasks[i].ID)
+		if err := database.GetDB().Model(&tasks[i]).UpdateColumn("slug", slug).Error; err != nil {
+			return fmt.Errorf("failed to update task slug: %w", err)
+		}
+	}
+	if len(tasks) > 0 {
+		log.Printf("Generated slugs for %d tasks", len(tasks))
+	}
+	return nil
+}
+
+// ParseTaskBundle 解析 YAML 或 JSON 格式的任务定义包，拒绝未知字段以便及早发现拼写错误
+func ParseTaskBundle(data []byte) (*TaskBundle, error) {
+	var bundle TaskBundle
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("invalid JSON bundle: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(trimmed))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("invalid YAML bundle: %w", err)
+		}
+	}
+
+	if bundle.Version != 0 && bundle.Version != TaskBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+// bundleTask 把任务转换为定义包中的格式，任务需要预加载 Namespace
+func (s *TaskService) bundleTask(task *models.Task) BundleTask {
+	enabled := task.IsEnabled
+	item := BundleTask{
+		Slug:             task.Slug,
+		Name:             task.Name,
+		ScriptType:       task.ScriptType,
+		ScheduleType:     task.ScheduleType,
+		Schedule:         task.ScheduleSpec,
+		ExecuteAt:        task.ExecuteAt,
+		Enabled:          &enabled,
+		Env:              task.Env,
+		Requirements:     task.Requirements,
+		Libraries:        task.Libraries,
+		RetentionDays:    task.RetentionDays,
+		RetentionMaxRows: task.RetentionMaxRows,
+		Script:           s.scriptService.StripScriptHeader(task.ScriptType, s.GetTaskScriptContent(task)),
+	}
+	if task.Namespace != nil {
+		item.Namespace = task.Namespace.Name
+	}
+	if item.ScheduleType == "once" {
+		item.Schedule = ""
+	}
+	return item
+}
+
+// ExportTasks 导出范围内的全部任务，按 slug 排序以便在 git 中比较
+func (s *TaskService) ExportTasks(scope NamespaceScope) (*TaskBundle, error) {
+	tasks, err := s.GetAllTasks(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &TaskBundle{
+		Version:    TaskBundleVersion,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Tasks:      make([]BundleTask, 0, len(tasks)),
+	}
+	for i := range tasks {
+		bundle.Tasks = append(bundle.Tasks, s.bundleTask(&tasks[i]))
+	}
+	sort.Slice(bundle.Tasks, func(i, j int) bool { return bundle.Tasks[i].Slug < bundle.Tasks[j].Slug })
+	return bundle, nil
+}
+
+// normalize 填充默认值并检查任务定义
+func (t *BundleTask) normalize() error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.ScriptType == "" {
+		t.ScriptType = "command"
+	}
+	if t.ScheduleType == "" {
+		t.ScheduleType = "cron"
+	}
+	if t.Enabled == nil {
+		enabled := true
+		t.Enabled = &enabled
+	}
+	if len(t.Env) == 0 {
+		t.Env = nil
+	}
+
+	switch t.ScheduleType {
+	case "cron":
+		if t.Schedule == "" {
+			return fmt.Errorf("schedule is required for cron tasks")
+		}
+		t.ExecuteAt = nil
+	case "once":
+		if t.ExecuteAt == nil {
+			return fmt.Errorf("execute_at is required for one-time tasks")
+		}
+		t.Schedule = ""
+	default:
+		return fmt.Errorf("unsupported schedule_type %q", t.ScheduleType)
+	}
+	if strings.TrimSpace(t.Script) == "" {
+		return fmt.Errorf("script is required")
+	}
+	if t.RetentionDays < 0 || t.RetentionMaxRows < 0 {
+		return fmt.Errorf("retention values cannot be negative")
+	}
+	requirements, err := ParseRequirements(t.Requirements)
+	if err != nil {
+		return err
+	}
+	t.Requirements = requirements
+	return ValidateEnv(t.Env)
+}
+
+// diffBundleTasks 比较当前任务与期望的定义，返回字段变更和脚本差异
+func diffBundleTasks(current, desired *BundleTask) (map[string]audit.FieldChange, []DiffLine) {
+	changes := make(map[string]audit.FieldChange)
+	addField := func(name string, before, after interface{}) {
+		if !reflect.DeepEqual(before, after) {
+			changes[name] = audit.FieldChange{Before: before, After: after}
+		}
+	}
+	addField("name", current.Name, desired.Name)
+	addField("namespace", current.Namespace, desired.Namespace)
+	addField("script_type", current.ScriptType, desired.ScriptType)
+	addField("schedule_type", current.ScheduleType, desired.ScheduleType)
+	addField("schedule", current.Schedule, desired.Schedule)
+	addField("execute_at", formatRevisionTime(current.ExecuteAt), formatRevisionTime(desired.ExecuteAt))
+	addField("enabled", *current.Enabled, *desired.Enabled)
+	addField("env", envOrEmpty(current.Env), envOrEmpty(desired.Env))
+	addField("requirements", current.Requirements, desired.Requirements)
+	addField("libraries", listOrEmpty(current.Libraries), listOrEmpty(desired.Libraries))
+	addField("retention_days", current.RetentionDays, desired.RetentionDays)
+	addField("retention_max_rows", current.RetentionMaxRows, desired.RetentionMaxRows)
+
+	var script []DiffLine
+	if current.Script != desired.Script {
+		script = DiffLines(current.Script, desired.Script)
+	}
+	return changes, script
+}
+
+func envOrEmpty(env map[string]string) map[string]string {
+	if env == nil {
+		return map[string]string{}
+	}
+	return env
+}
+
+// listOrEmpty nil 和空列表视为相同
+func listOrEmpty(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// manifestNamespace 清单中的任务所属的命名空间：已有任务按 slug 取其所在命名空间，否则取清单中填写的命名空间，无法确定时返回 0
+func (p *importPlanner) manifestNamespace(desired BundleTask) uint {
+	if task := p.existing[desired.Slug]; task != nil {
+		return task.NamespaceID
+	}
+	name := desired.Namespace
+	if name == "" {
+		name = p.defaultName
+	}
+	return p.namespaceIDs[name]
+}
+
+// importPlanner 按 slug 把定义包中的任务与现有任务比较
+type importPlanner struct {
+	s            *TaskService
+	scope        NamespaceScope
+	namespaceIDs map[string]uint
+	defaultName  string
+	tasks        []models.Task
+	existing     map[string]*models.Task
+	seen         map[string]bool
+}
+
+func (s *TaskService) newImportPlanner(scope NamespaceScope) (*importPlanner, error) {
+	var namespaces []models.Namespace
+	if err := database.GetDB().Find(&namespaces).Error; err != nil {
+		return nil, fmt.Errorf("failed to get namespaces: %w", err)
+	}
+	defaultID, err := database.DefaultNamespaceID()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按 slug 匹配时查找全部任务，避免与范围外的任务 slug 冲突
+	tasks, err := s.GetAllTasks(AllNamespaces())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &importPlanner{
+		s:            s,
+		scope:        scope,
+		namespaceIDs: make(map[string]uint, len(namespaces)),
+		tasks:        tasks,
+		existing:     make(map[string]*models.Task, len(tasks)),
+		seen:         make(map[string]bool),
+	}
+	for _, namespace := range namespaces {
+		p.namespaceIDs[namespace.Name] = namespace.ID
+		if namespace.ID == defaultID {
+			p.defaultName = namespace.Name
+		}
+	}
+	for i := range tasks {
+		p.existing[tasks[i].Slug] = &tasks[i]
+	}
+	return p, nil
+}
+
+// plan 检查单个任务定义并生成计划项
+func (p *importPlanner) plan(desired BundleTask) (ImportItem, error) {
+	if err := ValidateSlug(desired.Slug); err != nil {
+		return ImportItem{}, err
+	}
+	if p.seen[desired.Slug] {
+		return ImportItem{}, errors.New("duplicate slug")
+	}
+	p.seen[desired.Slug] = true
+	if err := desired.normalize(); err != nil {
+		return ImportItem{}, err
+	}
+	if err := p.s.ValidateScriptType(desired.ScriptType); err != nil {
+		return ImportItem{}, fmt.Errorf("unsupported script_type %q", desired.ScriptType)
+	}
+	if _, err := p.s.CheckRequirements(desired.ScriptType, desired.Requirements); err != nil {
+		return ImportItem{}, err
+	}
+	libraries, err := p.s.CheckLibraries(desired.Libraries)
+	if err != nil {
+		return ImportItem{}, err
+	}
+	desired.Libraries = libraries
+
+	if desired.Namespace == "" {
+		desired.Namespace = p.defaultName
+	}
+	namespaceID, ok := p.namespaceIDs[desired.Namespace]
+	if !ok {
+		return ImportItem{}, fmt.Errorf("namespace %q not found", desired.Namespace)
+	}
+	if !p.scope.Contains(namespaceID) {
+		return ImportItem{}, errors.New("namespace not accessible")
+	}
+
+	item := ImportItem{Slug: desired.Slug, Name: desired.Name, desired: &desired, namespaceID: namespaceID}
+	task, ok := p.existing[desired.Slug]
+	if !ok {
+		item.Action = ImportCreate
+		return item, nil
+	}
+	if !p.scope.Contains(task.NamespaceID) {
+		return ImportItem{}, ErrSlugTaken
+	}
+	current := p.s.bundleTask(task)
+	item.TaskID = task.ID
+	item.Changes, item.ScriptDiff = diffBundleTasks(&current, &desired)
+	item.Action = ImportUnchanged
+	if len(item.Changes) > 0 || item.ScriptDiff != nil {
+		item.Action = ImportUpdate
+	}
+	return item, nil
+}
+
+// summarize 统计计划中每种动作的数量
+func (plan *ImportPlan) summarize() {
+	plan.Summary = map[string]int{}
+	for _, item := range plan.Items {
+		plan.Summary[item.Action]++
+	}
+}
+
+// PlanImport 按 slug 把定义包与现有任务比较，生成导入计划；定义包有误时返回错误，不做任何修改。
+// 由 GitOps 清单管理的任务不能通过导入修改，replace 模式也不会删除它们
+func (s *TaskService) PlanImport(bundle *TaskBundle, mode string, scope NamespaceScope) (*ImportPlan, error) {
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		return nil, fmt.Errorf("invalid import mode %q, expected merge or replace", mode)
+	}
+
+	planner, err := s.newImportPlanner(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ImportPlan{Mode: mode, Items: []ImportItem{}}
+	for i := range bundle.Tasks {
+		item, err := planner.plan(bundle.Tasks[i])
+		if err != nil {
+			return nil, fmt.Errorf("task #%d (%s): %w", i+1, bundle.Tasks[i].Slug, err)
+		}
+		if task := planner.existing[item.Slug]; task != nil && task.Manifest != "" {
+			return nil, fmt.Errorf("task %s: %w (%s)", item.Slug, ErrTaskManaged, task.Manifest)
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	if mode == ImportModeReplace {
+		var deletes []ImportItem
+		for i := range planner.tasks {
+			task := &planner.tasks[i]
+			if !planner.seen[task.Slug] && task.Manifest == "" && scope.Contains(task.NamespaceID) {
+				deletes = append(deletes, ImportItem{Slug: task.Slug, Action: ImportDelete, TaskID: task.ID, Name: task.Name})
+			}
+		}
+		sort.Slice(deletes, func(i, j int) bool { return deletes[i].Slug < deletes[j].Slug })
+		plan.Items = append(plan.Items, deletes...)
+	}
+
+	plan.summarize()
+	return plan, nil
+}
+
+// ApplyImportItem 执行导入计划中的一项，删除时返回被删除前的任务
+func (s *TaskService) ApplyImportItem(item *ImportItem) (*models.Task, error) {
+	desired := item.desired
+	switch item.Action {
+	case ImportCreate:
+		task, err := s.CreateTaskFull(desired.Name, desired.Script, desired.ScriptType, desired.Schedule, desired.ScheduleType, desired.ExecuteAt, *desired.Enabled, item.namespaceID)
+		if err != nil {
+			return nil, err
+		}
+		item.TaskID = task.ID
+		return task, s.applyTaskSettings(task, desired)
+
+	case ImportUpdate:
+		task, err := s.GetTaskByID(item.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if hasScheduleChanges(item) {
+			if task, err = s.UpdateTaskFull(item.TaskID, desired.Name, desired.Script, desired.ScriptType, desired.Schedule, desired.ScheduleType, desired.ExecuteAt, *desired.Enabled); err != nil {
+				return nil, err
+			}
+		}
+		if task.NamespaceID != item.namespaceID {
+			if task, err = s.MoveTask(task.ID, item.namespaceID); err != nil {
+				return nil, err
+			}
+		}
+		return task, s.applyTaskSettings(task, desired)
+
+	case ImportDelete:
+		task, err := s.GetTaskByID(item.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		return task, s.DeleteTask(item.TaskID)
+	}
+	return nil, nil
+}
+
+// hasScheduleChanges 是否有需要通过 UpdateTaskFull 修改的字段（脚本、调度、启用状态）
+func hasScheduleChanges(item *ImportItem) bool {
+	if item.ScriptDiff != nil {
+		return true
+	}
+	for _, field := range []string{"name", "script_type", "schedule_type", "schedule", "execute_at", "enabled"} {
+		if _, ok := item.Changes[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTaskSettings 写入 CreateTaskFull/UpdateTaskFull 以外的字段：slug、环境变量、Python 依赖、共享库模块和保留策略
+func (s *TaskService) applyTaskSettings(task *models.Task, desired *BundleTask) error {
+	if err := s.SetTaskSlug(task, desired.Slug); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(envOrEmpty(task.Env), envOrEmpty(desired.Env)) {
+		if err := s.SetTaskEnv(task, desired.Env); err != nil {
+			return err
+		}
+	}
+	if task.Requirements != desired.Requirements {
+		if err := s.SetTaskRequirements(task, desired.Requirements); err != nil {
+			return err
+		}
+	}
+	if !reflect.DeepEqual(listOrEmpty(task.Libraries), listOrEmpty(desired.Libraries)) {
+		if err := s.SetTaskLibraries(task, desired.Libraries); err != nil {
+			return err
+		}
+	}
+	if task.RetentionDays != desired.RetentionDays || task.RetentionMaxRows != desired.RetentionMaxRows {
+		if _, err := s.UpdateTaskRetention(task.ID, desired.RetentionDays, desired.RetentionMaxRows); err != nil {
+			return err
+		}
+		task.RetentionDays = desired.RetentionDays
+		task.RetentionMaxRows = desired.RetentionMaxRows
+	}
+	return nil
+}
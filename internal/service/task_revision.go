@@ -0,0 +1,308 @@
+package service
+
+import (
+	"b1cron/internal/audit"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxDiffLines 超过该行数的脚本不做逐行比较，直接整体替换，避免比较耗时过长
+const maxDiffLines = 5000
+
+// DiffLine 逐行差异中的一行
+type DiffLine struct {
+	Type    string `json:"type"` // equal, add, delete
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// RevisionDiff 两个版本之间的差异
+type RevisionDiff struct {
+	From   *models.TaskRevision         `json:"from"`
+	To     *models.TaskRevision         `json:"to"`
+	Fields map[string]audit.FieldChange `json:"fields"` // 脚本以外发生变化的字段
+	Lines  []DiffLine                   `json:"lines"`  // 脚本内容的逐行差异
+}
+
+// sameRevisionContent 两个版本的脚本和调度是否一致
+func sameRevisionContent(a, b *models.TaskRevision) bool {
+	sameExecuteAt := (a.ExecuteAt == nil && b.ExecuteAt == nil) ||
+		(a.ExecuteAt != nil && b.ExecuteAt != nil && a.ExecuteAt.Equal(*b.ExecuteAt))
+	return a.Name == b.Name &&
+		a.ScriptType == b.ScriptType &&
+		a.Content == b.Content &&
+		a.ScheduleSpec == b.ScheduleSpec &&
+		a.ScheduleType == b.ScheduleType &&
+		sameExecuteAt
+}
+
+// RecordRevision 为任务当前的脚本和调度记录新版本；与最新版本相同时不重复记录
+func (s *TaskService) RecordRevision(task *models.Task, content string, author *models.User, note string) (*models.TaskRevision, error) {
+	revision := &models.TaskRevision{
+		TaskID:       task.ID,
+		Name:         task.Name,
+		ScriptType:   task.ScriptType,
+		Content:      content,
+		ScheduleSpec: task.ScheduleSpec,
+		ScheduleType: task.ScheduleType,
+		ExecuteAt:    task.ExecuteAt,
+		AuthorName:   "system",
+		Note:         note,
+	}
+	if author != nil {
+		revision.AuthorID = &author.ID
+		revision.AuthorName = author.Username
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var latest []models.TaskRevision
+		if err := tx.Where("task_id = ?", task.ID).Order("revision DESC").Limit(1).Find(&latest).Error; err != nil {
+			return fmt.Errorf("failed to get latest revision: %w", err)
+		}
+		revision.Revision = 1
+		if len(latest) > 0 {
+			if sameRevisionContent(&latest[0], revision) {
+				revision = &latest[0]
+				return nil
+			}
+			revision.Revision = latest[0].Revision + 1
+		}
+
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create task revision: %w", err)
+		}
+		if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).UpdateColumn("current_revision", revision.Revision).Error; err != nil {
+			return fmt.Errorf("failed to update task revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	task.CurrentRevision = revision.Revision
+	return revision, nil
+}
+
+// EnsureInitialRevisions 为还没有版本记录的任务（升级前创建的任务）记录初始版本
+func (s *TaskService) EnsureInitialRevisions() error {
+	var tasks []models.Task
+	if err := database.GetDB().Where("current_revision = 0").Find(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to get tasks without revisions: %w", err)
+	}
+
+	for i := range tasks {
+		if _, err := s.RecordRevision(&tasks[i], s.GetTaskScriptContent(&tasks[i]), nil, "initial revision"); err != nil {
+			return err
+		}
+	}
+	if len(tasks) > 0 {
+		log.Printf("Recorded initial revisions for %d tasks", len(tasks))
+	}
+	return nil
+}
+
+// ListRevisions 获取任务的全部版本，最新的在前
+func (s *TaskService) ListRevisions(taskID uint) ([]models.TaskRevision, error) {
+	var revisions []models.TaskRevision
+	if err := database.GetDB().Where("task_id = ?", taskID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func (s *TaskService) GetRevision(taskID uint, revision int) (*models.TaskRevision, error) {
+	var rev models.TaskRevision
+	if err := database.GetDB().Where("task_id = ? AND revision = ?", taskID, revision).First(&rev).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task revision: %w", err)
+	}
+	return &rev, nil
+}
+
+// DiffRevisions 比较任务的两个版本
+func (s *TaskService) DiffRevisions(taskID uint, from, to int) (*RevisionDiff, error) {
+	fromRev, err := s.GetRevision(taskID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := s.GetRevision(taskID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]audit.FieldChange)
+	addField := func(name string, before, after interface{}) {
+		if before != after {
+			fields[name] = audit.FieldChange{Before: before, After: after}
+		}
+	}
+	addField("name", fromRev.Name, toRev.Name)
+	addField("script_type", fromRev.ScriptType, toRev.ScriptType)
+	addField("schedule_spec", fromRev.ScheduleSpec, toRev.ScheduleSpec)
+	addField("schedule_type", fromRev.ScheduleType, toRev.ScheduleType)
+	addField("execute_at", formatRevisionTime(fromRev.ExecuteAt), formatRevisionTime(toRev.ExecuteAt))
+
+	return &RevisionDiff{
+		From:   fromRev,
+		To:     toRev,
+		Fields: fields,
+		Lines:  DiffLines(fromRev.Content, toRev.Content),
+	}, nil
+}
+
+// RestoreRevision 通过 UpdateTaskFull 把任务恢复到指定版本，并记录为新版本
+func (s *TaskService) RestoreRevision(taskID uint, revision int, author *models.User) (*models.Task, error) {
+	rev, err := s.GetRevision(taskID, revision)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.GetTaskByID(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.UpdateTaskFull(taskID, rev.Name, rev.Content, rev.ScriptType, rev.ScheduleSpec, rev.ScheduleType, rev.ExecuteAt, task.IsEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	// 任务已经恢复，记录版本失败时只记录日志，不影响调用方记录审计日志
+	if _, err := s.RecordRevision(updated, s.GetTaskScriptContent(updated), author, fmt.Sprintf("restored from revision %d", revision)); err != nil {
+		log.Printf("Failed to record revision for task %d: %v", updated.ID, err)
+	}
+	return updated, nil
+}
+
+func formatRevisionTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// DiffLines 逐行差异，使用线性空间的 Myers 算法，内存占用与行数成正比
+func DiffLines(before, after string) []DiffLine {
+	d := &lineDiffer{a: splitLines(before), b: splitLines(after)}
+	d.lines = make([]DiffLine, 0, len(d.a)+len(d.b))
+	if len(d.a) > maxDiffLines || len(d.b) > maxDiffLines {
+		d.replace(0, len(d.a), 0, len(d.b))
+		return d.lines
+	}
+	d.diff(0, len(d.a), 0, len(d.b))
+	return d.lines
+}
+
+// lineDiffer 按顺序生成 a[a0:a1] 与 b[b0:b1] 之间的差异
+type lineDiffer struct {
+	a, b  []string
+	lines []DiffLine
+}
+
+func (d *lineDiffer) equal(i, j int) {
+	d.lines = append(d.lines, DiffLine{Type: "equal", Text: d.a[i], OldLine: i + 1, NewLine: j + 1})
+}
+
+// replace 删除 a[a0:a1] 并添加 b[b0:b1]
+func (d *lineDiffer) replace(a0, a1, b0, b1 int) {
+	for i := a0; i < a1; i++ {
+		d.lines = append(d.lines, DiffLine{Type: "delete", Text: d.a[i], OldLine: i + 1})
+	}
+	for j := b0; j < b1; j++ {
+		d.lines = append(d.lines, DiffLine{Type: "add", Text: d.b[j], NewLine: j + 1})
+	}
+}
+
+func (d *lineDiffer) diff(a0, a1, b0, b1 int) {
+	// 去掉相同的开头和结尾
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.equal(a0, b0)
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1-suffix > a0 && b1-suffix > b0 && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	a1, b1 = a1-suffix, b1-suffix
+
+	if a0 == a1 || b0 == b1 {
+		d.replace(a0, a1, b0, b1)
+	} else {
+		// 以最短编辑路径中间的一段相同内容为界分成两半，分别递归
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		if (x == a0 && y == b0 && u == x) || (u == a1 && v == b1 && u == x) {
+			d.replace(a0, a1, b0, b1) // 无法再分割，不应出现
+		} else {
+			d.diff(a0, x, b0, y)
+			for i := x; i < u; i++ {
+				d.equal(i, y+i-x)
+			}
+			d.diff(u, a1, v, b1)
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.equal(a1+i, b1+i)
+	}
+}
+
+// middleSnake 从两端同时搜索最短编辑路径，返回路径中间一段相同内容的起点 (x, y) 和终点 (u, v)
+func (d *lineDiffer) middleSnake(a0, a1, b0, b1 int) (int, int, int, int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	forward := make([]int, 2*limit+3)  // forward[offset+k]：对角线 k = x-y 上从起点出发能到达的最远 x
+	backward := make([]int, 2*limit+3) // backward[offset+k]：从终点反向出发，同样以反向坐标表示
+
+	for step := 0; step <= limit; step++ {
+		for k := -step; k <= step; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -step || (k != step && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if odd && k >= delta-(step-1) && k <= delta+(step-1) && x+backward[offset+delta-k] >= n {
+				return a0 + startX, b0 + startY, a0 + x, b0 + y
+			}
+		}
+		for k := -step; k <= step; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -step || (k != step && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if !odd && delta-k >= -step && delta-k <= step && x+forward[offset+delta-k] >= n {
+				return a0 + n - x, b0 + m - y, a0 + n - startX, b0 + m - startY
+			}
+		}
+	}
+	return a0, b0, a0, b0
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -15,12 +15,16 @@ import (
 type TaskService struct {
 	schedulerService *scheduler.SchedulerService
 	scriptService    *ScriptFileService
+	venvs            *VenvService
+	libraries        *LibraryService
 }
 
-func NewTaskService(schedulerService *scheduler.SchedulerService, cfg *config.Config) *TaskService {
+func NewTaskService(schedulerService *scheduler.SchedulerService, venvs *VenvService, libraries *LibraryService, cfg *config.Config) *TaskService {
 	return &TaskService{
 		schedulerService: schedulerService,
 		scriptService:    NewScriptFileService(cfg),
+		venvs:            venvs,
+		libraries:        libraries,
 	}
 }
 
@@ -29,10 +33,21 @@ func (s *TaskService) CreateTask(name, command, scheduleSpec string, isEnabled b
 }
 
 func (s *TaskService) CreateTaskWithScript(name, command, scriptType, scheduleSpec string, isEnabled bool) (*models.Task, error) {
-	// 验证脚本内容
+	// 验证脚本类型和内容
+	if err := s.scriptService.ValidateScriptType(scriptType); err != nil {
+		return nil, err
+	}
 	if err := s.scriptService.ValidateScriptContent(scriptType, command); err != nil {
 		return nil, fmt.Errorf("invalid script content: %w", err)
 	}
+	if err := s.checkTaskScript(nil, scriptType, command); err != nil {
+		return nil, err
+	}
+
+	namespaceID, err := database.DefaultNamespaceID()
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建任务记录（先不保存到数据库）
 	task := &models.Task{
@@ -40,6 +55,7 @@ func (s *TaskService) CreateTaskWithScript(name, command, scriptType, scheduleSp
 		ScriptType:   scriptType,
 		ScheduleSpec: scheduleSpec,
 		IsEnabled:    isEnabled,
+		NamespaceID:  namespaceID,
 	}
 
 	// 先保存到数据库获取ID
@@ -47,24 +63,27 @@ func (s *TaskService) CreateTaskWithScript(name, command, scriptType, scheduleSp
 		return nil, fmt.Errorf("failed to create task in database: %w", err)
 	}
 
-	// 创建脚本文件并获取执行命令
-	scriptPath, execCommand, err := s.scriptService.CreateScriptFile(task.ID, name, scriptType, command)
+	// 生成脚本内容和执行命令（头部注释中包含任务ID）
+	scriptContent, execCommand, checksum, err := s.buildTaskScript(task, name, scriptType, command)
 	if err != nil {
 		// 回滚数据库记录
 		database.GetDB().Delete(task)
-		return nil, fmt.Errorf("failed to create script file: %w", err)
+		return nil, fmt.Errorf("failed to build script: %w", err)
 	}
 
-	// 更新任务的脚本路径和执行命令
-	task.ScriptPath = scriptPath
+	// 更新任务的脚本内容和执行命令
+	task.ScriptContent = scriptContent
+	task.ScriptChecksum = checksum
 	task.Command = execCommand
+	task.Slug = s.uniqueSlug(Slugify(name), task.ID)
+	// Create 会把零值 false 替换为字段默认值 true，这里恢复调用者指定的启用状态
+	task.IsEnabled = isEnabled
 
 	// 如果启用任务，进行调度
 	if isEnabled {
 		jobUUID, err := s.schedulerService.ScheduleTask(task)
 		if err != nil {
-			// 清理脚本文件和数据库记录
-			s.scriptService.DeleteScriptFile(scriptPath)
+			// 清理数据库记录
 			database.GetDB().Delete(task)
 			return nil, fmt.Errorf("failed to schedule task: %w", err)
 		}
@@ -73,11 +92,10 @@ func (s *TaskService) CreateTaskWithScript(name, command, scriptType, scheduleSp
 
 	// 保存更新的任务信息
 	if err := database.GetDB().Save(task).Error; err != nil {
-		// 清理调度任务和脚本文件
+		// 清理调度任务
 		if isEnabled && task.GocronJobID != uuid.Nil {
 			s.schedulerService.UnscheduleTask(task.GocronJobID)
 		}
-		s.scriptService.DeleteScriptFile(scriptPath)
 		database.GetDB().Delete(task)
 		return nil, fmt.Errorf("failed to update task in database: %w", err)
 	}
@@ -86,11 +104,21 @@ func (s *TaskService) CreateTaskWithScript(name, command, scriptType, scheduleSp
 }
 
 // CreateTaskFull 创建完整的任务（支持调度类型和执行时间）
-func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, scheduleType string, executeAt *time.Time, isEnabled bool) (*models.Task, error) {
-	// 验证脚本内容
+func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, scheduleType string, executeAt *time.Time, isEnabled bool, namespaceID uint) (*models.Task, error) {
+	if err := database.GetDB().First(&models.Namespace{}, namespaceID).Error; err != nil {
+		return nil, fmt.Errorf("namespace not found: %d", namespaceID)
+	}
+
+	// 验证脚本类型和内容
+	if err := s.scriptService.ValidateScriptType(scriptType); err != nil {
+		return nil, err
+	}
 	if err := s.scriptService.ValidateScriptContent(scriptType, command); err != nil {
 		return nil, fmt.Errorf("invalid script content: %w", err)
 	}
+	if err := s.checkTaskScript(nil, scriptType, command); err != nil {
+		return nil, err
+	}
 
 	// 验证一次性任务的执行时间
 	if scheduleType == "once" {
@@ -110,6 +138,7 @@ func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, sc
 		ScheduleType: scheduleType,
 		ExecuteAt:    executeAt,
 		IsEnabled:    isEnabled,
+		NamespaceID:  namespaceID,
 	}
 
 	// 先保存到数据库获取ID
@@ -117,24 +146,27 @@ func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, sc
 		return nil, fmt.Errorf("failed to create task in database: %w", err)
 	}
 
-	// 创建脚本文件并获取执行命令
-	scriptPath, execCommand, err := s.scriptService.CreateScriptFile(task.ID, name, scriptType, command)
+	// 生成脚本内容和执行命令（头部注释中包含任务ID）
+	scriptContent, execCommand, checksum, err := s.buildTaskScript(task, name, scriptType, command)
 	if err != nil {
 		// 回滚数据库记录
 		database.GetDB().Delete(task)
-		return nil, fmt.Errorf("failed to create script file: %w", err)
+		return nil, fmt.Errorf("failed to build script: %w", err)
 	}
 
-	// 更新任务的脚本路径和执行命令
-	task.ScriptPath = scriptPath
+	// 更新任务的脚本内容和执行命令
+	task.ScriptContent = scriptContent
+	task.ScriptChecksum = checksum
 	task.Command = execCommand
+	task.Slug = s.uniqueSlug(Slugify(name), task.ID)
+	// Create 会把零值 false 替换为字段默认值 true，这里恢复调用者指定的启用状态
+	task.IsEnabled = isEnabled
 
 	// 如果启用任务，进行调度
 	if isEnabled {
 		jobUUID, err := s.schedulerService.ScheduleTask(task)
 		if err != nil {
-			// 清理脚本文件和数据库记录
-			s.scriptService.DeleteScriptFile(scriptPath)
+			// 清理数据库记录
 			database.GetDB().Delete(task)
 			return nil, fmt.Errorf("failed to schedule task: %w", err)
 		}
@@ -143,11 +175,10 @@ func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, sc
 
 	// 保存更新的任务信息
 	if err := database.GetDB().Save(task).Error; err != nil {
-		// 清理调度任务和脚本文件
+		// 清理调度任务
 		if isEnabled && task.GocronJobID != uuid.Nil {
 			s.schedulerService.UnscheduleTask(task.GocronJobID)
 		}
-		s.scriptService.DeleteScriptFile(scriptPath)
 		database.GetDB().Delete(task)
 		return nil, fmt.Errorf("failed to update task in database: %w", err)
 	}
@@ -155,9 +186,10 @@ func (s *TaskService) CreateTaskFull(name, command, scriptType, scheduleSpec, sc
 	return task, nil
 }
 
-func (s *TaskService) GetAllTasks() ([]models.Task, error) {
+// GetAllTasks 获取范围内的全部任务
+func (s *TaskService) GetAllTasks(scope NamespaceScope) ([]models.Task, error) {
 	var tasks []models.Task
-	if err := database.GetDB().Find(&tasks).Error; err != nil {
+	if err := scope.applyTasks(database.GetDB().Preload("Namespace")).Find(&tasks).Error; err != nil {
 		return nil, fmt.Errorf("failed to get tasks: %w", err)
 	}
 	return tasks, nil
@@ -171,12 +203,27 @@ func (s *TaskService) GetTaskByID(id uint) (*models.Task, error) {
 	return &task, nil
 }
 
+// GetTaskInScope 获取任务，任务不在调用者的命名空间范围内时视为不存在
+func (s *TaskService) GetTaskInScope(id uint, scope NamespaceScope) (*models.Task, error) {
+	task, err := s.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.Contains(task.NamespaceID) {
+		return nil, fmt.Errorf("failed to get task: %w", gorm.ErrRecordNotFound)
+	}
+	return task, nil
+}
+
 func (s *TaskService) UpdateTask(id uint, name, command, scheduleSpec string, isEnabled bool) (*models.Task, error) {
 	return s.UpdateTaskWithScript(id, name, command, "command", scheduleSpec, isEnabled)
 }
 
 func (s *TaskService) UpdateTaskWithScript(id uint, name, command, scriptType, scheduleSpec string, isEnabled bool) (*models.Task, error) {
-	// 验证脚本内容
+	// 验证脚本类型和内容
+	if err := s.scriptService.ValidateScriptType(scriptType); err != nil {
+		return nil, err
+	}
 	if err := s.scriptService.ValidateScriptContent(scriptType, command); err != nil {
 		return nil, fmt.Errorf("invalid script content: %w", err)
 	}
@@ -185,6 +232,9 @@ func (s *TaskService) UpdateTaskWithScript(id uint, name, command, scriptType, s
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkTaskScript(task, scriptType, command); err != nil {
+		return nil, err
+	}
 
 	// 取消当前调度
 	if task.GocronJobID != uuid.Nil {
@@ -194,18 +244,19 @@ func (s *TaskService) UpdateTaskWithScript(id uint, name, command, scriptType, s
 		task.GocronJobID = uuid.Nil
 	}
 
-	// 更新脚本文件
-	oldScriptPath := task.ScriptPath
-	scriptPath, execCommand, err := s.scriptService.UpdateScriptFile(id, name, scriptType, command, oldScriptPath)
+	// 更新脚本内容
+	scriptContent, execCommand, checksum, err := s.buildTaskScript(task, name, scriptType, command)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update script file: %w", err)
+		return nil, fmt.Errorf("failed to build script: %w", err)
 	}
 
 	// 更新任务信息
 	task.Name = name
 	task.Command = execCommand
 	task.ScriptType = scriptType
-	task.ScriptPath = scriptPath
+	task.ScriptPath = ""
+	task.ScriptContent = scriptContent
+	task.ScriptChecksum = checksum
 	task.ScheduleSpec = scheduleSpec
 	task.IsEnabled = isEnabled
 
@@ -232,20 +283,32 @@ func (s *TaskService) UpdateTaskWithScript(id uint, name, command, scriptType, s
 	return task, nil
 }
 
-// UpdateTaskFull 更新完整的任务（支持调度类型和执行时间）
+// ValidationError 任务内容不合法，处理器返回 400
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// UpdateTaskFull 更新完整的任务（支持调度类型和执行时间）；内容不合法时返回 *ValidationError 或 *ScriptCheckError
 func (s *TaskService) UpdateTaskFull(id uint, name, command, scriptType, scheduleSpec, scheduleType string, executeAt *time.Time, isEnabled bool) (*models.Task, error) {
-	// 验证脚本内容
+	// 验证脚本类型和内容
+	if err := s.scriptService.ValidateScriptType(scriptType); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
 	if err := s.scriptService.ValidateScriptContent(scriptType, command); err != nil {
-		return nil, fmt.Errorf("invalid script content: %w", err)
+		return nil, &ValidationError{Err: fmt.Errorf("invalid script content: %w", err)}
 	}
 
 	// 验证一次性任务的执行时间
 	if scheduleType == "once" {
 		if executeAt == nil {
-			return nil, fmt.Errorf("execute_at is required for one-time tasks")
+			return nil, &ValidationError{Err: fmt.Errorf("execute_at is required for one-time tasks")}
 		}
 		if executeAt.Before(time.Now()) {
-			return nil, fmt.Errorf("execute_at must be in the future")
+			return nil, &ValidationError{Err: fmt.Errorf("execute_at must be in the future")}
 		}
 	}
 
@@ -253,6 +316,9 @@ func (s *TaskService) UpdateTaskFull(id uint, name, command, scriptType, schedul
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkTaskScript(task, scriptType, command); err != nil {
+		return nil, err
+	}
 
 	// 取消当前调度
 	if task.GocronJobID != uuid.Nil {
@@ -262,18 +328,19 @@ func (s *TaskService) UpdateTaskFull(id uint, name, command, scriptType, schedul
 		task.GocronJobID = uuid.Nil
 	}
 
-	// 更新脚本文件
-	oldScriptPath := task.ScriptPath
-	scriptPath, execCommand, err := s.scriptService.UpdateScriptFile(id, name, scriptType, command, oldScriptPath)
+	// 更新脚本内容
+	scriptContent, execCommand, checksum, err := s.buildTaskScript(task, name, scriptType, command)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update script file: %w", err)
+		return nil, fmt.Errorf("failed to build script: %w", err)
 	}
 
 	// 更新任务信息
 	task.Name = name
 	task.Command = execCommand
 	task.ScriptType = scriptType
-	task.ScriptPath = scriptPath
+	task.ScriptPath = ""
+	task.ScriptContent = scriptContent
+	task.ScriptChecksum = checksum
 	task.ScheduleSpec = scheduleSpec
 	task.ScheduleType = scheduleType
 	task.ExecuteAt = executeAt
@@ -315,11 +382,6 @@ func (s *TaskService) DeleteTask(id uint) error {
 		}
 	}
 
-	// 删除脚本文件
-	if err := s.scriptService.DeleteScriptFile(task.ScriptPath); err != nil {
-		fmt.Printf("Warning: failed to delete script file: %v\n", err)
-	}
-
 	// 从数据库删除
 	if err := database.GetDB().Delete(task).Error; err != nil {
 		return fmt.Errorf("failed to delete task from database: %w", err)
@@ -337,17 +399,73 @@ func (s *TaskService) ToggleTask(id uint) (*models.Task, error) {
 	return s.UpdateTaskWithScript(id, task.Name, s.GetTaskScriptContent(task), task.ScriptType, task.ScheduleSpec, !task.IsEnabled)
 }
 
+// RunTaskNow 立即执行一次任务
+func (s *TaskService) RunTaskNow(id uint) (*models.Task, error) {
+	task, err := s.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.schedulerService.RunNow(task)
+	return task, nil
+}
+
+// UpdateTaskRetention 更新任务的执行记录保留策略（0 表示使用全局策略）
+func (s *TaskService) UpdateTaskRetention(id uint, retentionDays, retentionMaxRows int) (*models.Task, error) {
+	if retentionDays < 0 || retentionMaxRows < 0 {
+		return nil, fmt.Errorf("retention values cannot be negative")
+	}
+
+	task, err := s.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"retention_days":     retentionDays,
+		"retention_max_rows": retentionMaxRows,
+	}
+	if err := database.GetDB().Model(task).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update task retention: %w", err)
+	}
+
+	task.RetentionDays = retentionDays
+	task.RetentionMaxRows = retentionMaxRows
+	return task, nil
+}
+
+// MoveTask 把任务移动到另一个命名空间
+func (s *TaskService) MoveTask(id, namespaceID uint) (*models.Task, error) {
+	task, err := s.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.GetDB().First(&models.Namespace{}, namespaceID).Error; err != nil {
+		return nil, fmt.Errorf("namespace not found: %d", namespaceID)
+	}
+
+	if err := database.GetDB().Model(task).Update("namespace_id", namespaceID).Error; err != nil {
+		return nil, fmt.Errorf("failed to move task: %w", err)
+	}
+	task.NamespaceID = namespaceID
+	return task, nil
+}
+
+// ScriptRuntimes 返回可用的脚本运行时
+func (s *TaskService) ScriptRuntimes() []config.RuntimeConfig {
+	return s.scriptService.Runtimes()
+}
+
+// ValidateScriptType 检查脚本类型是 command 或已注册的运行时
+func (s *TaskService) ValidateScriptType(scriptType string) error {
+	return s.scriptService.ValidateScriptType(scriptType)
+}
+
 func (s *TaskService) GetTaskScriptContent(task *models.Task) string {
 	if task.ScriptType == "command" {
 		return task.Command
 	}
-	
-	content, err := s.scriptService.GetScriptContent(task.ScriptPath)
-	if err != nil {
-		fmt.Printf("Warning: failed to read script content: %v\n", err)
-		return ""
-	}
-	return content
+	return task.ScriptContent
 }
 
 func (s *TaskService) GetTaskExecutions(taskID uint, limit int) ([]models.TaskExecution, error) {
@@ -362,9 +480,22 @@ func (s *TaskService) GetTaskExecutions(taskID uint, limit int) ([]models.TaskEx
 	return executions, nil
 }
 
-func (s *TaskService) GetRecentExecutions(limit int) ([]models.TaskExecution, error) {
+// GetExecutionInScope 获取单条执行记录，所属任务不在范围内时视为不存在
+func (s *TaskService) GetExecutionInScope(id uint, scope NamespaceScope) (*models.TaskExecution, error) {
+	var execution models.TaskExecution
+	if err := database.GetDB().First(&execution, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	if _, err := s.GetTaskInScope(execution.TaskID, scope); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// GetRecentExecutions 获取范围内最近的执行记录
+func (s *TaskService) GetRecentExecutions(scope NamespaceScope, limit int) ([]models.TaskExecution, error) {
 	var executions []models.TaskExecution
-	query := database.GetDB().Preload("Task", func(db *gorm.DB) *gorm.DB {
+	query := scope.applyExecutions(database.GetDB()).Preload("Task", func(db *gorm.DB) *gorm.DB {
 		return db.Unscoped()
 	}).Order("started_at DESC")
 	if limit > 0 {
@@ -377,91 +508,37 @@ func (s *TaskService) GetRecentExecutions(limit int) ([]models.TaskExecution, er
 }
 
 // GetRecentExecutionsWithPagination 获取支持搜索和分页的最近执行记录
-func (s *TaskService) GetRecentExecutionsWithPagination(search string, page, pageSize int) ([]models.TaskExecution, int64, error) {
-	var executions []models.TaskExecution
-	var total int64
-	
-	// 构建基础查询
-	baseQuery := database.GetDB().Model(&models.TaskExecution{})
-	
-	// 添加搜索条件
-	if search != "" {
-		baseQuery = baseQuery.Joins("JOIN tasks ON tasks.id = task_executions.task_id").
-			Where("tasks.name LIKE ?", "%"+search+"%")
-	}
-	
-	// 使用窗口函数一次性获取数据和总数
-	// 优化：使用单个查询替代两个分离的查询
-	offset := (page - 1) * pageSize
-	if offset < 0 {
-		offset = 0
-	}
-	
-	// 构建最终查询
-	resultQuery := database.GetDB().Preload("Task", func(db *gorm.DB) *gorm.DB {
-		return db.Unscoped()
-	})
-	
-	if search != "" {
-		resultQuery = resultQuery.Joins("JOIN tasks ON tasks.id = task_executions.task_id").
-			Where("tasks.name LIKE ?", "%"+search+"%")
-	}
-	
-	// 使用事务确保数据一致性
-	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
-		// 获取总数
-		if err := baseQuery.Count(&total).Error; err != nil {
-			return fmt.Errorf("failed to count executions: %w", err)
-		}
-		
-		// 获取分页数据
-		if err := resultQuery.Order("started_at DESC").
-			Offset(offset).
-			Limit(pageSize).
-			Find(&executions).Error; err != nil {
-			return fmt.Errorf("failed to get recent executions: %w", err)
-		}
-		
-		return nil
-	})
-	
-	if err != nil {
-		return nil, 0, err
-	}
-	
-	return executions, total, nil
+func (s *TaskService) GetRecentExecutionsWithPagination(scope NamespaceScope, search string, page, pageSize int) ([]models.TaskExecution, int64, error) {
+	return s.QueryExecutions(ExecutionFilter{Scope: scope, Search: search}, page, pageSize)
 }
 
-func (s *TaskService) GetExecutionStats() (map[string]interface{}, error) {
+// GetExecutionStats 获取范围内的执行统计
+func (s *TaskService) GetExecutionStats(scope NamespaceScope) (map[string]interface{}, error) {
 	var stats map[string]interface{} = make(map[string]interface{})
-	
-	// 总执行次数
-	var totalExecutions int64
-	if err := database.GetDB().Model(&models.TaskExecution{}).Count(&totalExecutions).Error; err != nil {
-		return nil, fmt.Errorf("failed to count total executions: %w", err)
-	}
-	stats["total_executions"] = totalExecutions
-	
-	// 成功执行次数
-	var successExecutions int64
-	if err := database.GetDB().Model(&models.TaskExecution{}).Where("status = ?", "success").Count(&successExecutions).Error; err != nil {
-		return nil, fmt.Errorf("failed to count success executions: %w", err)
-	}
-	stats["success_executions"] = successExecutions
-	
-	// 失败执行次数
-	var failedExecutions int64
-	if err := database.GetDB().Model(&models.TaskExecution{}).Where("status = ?", "failed").Count(&failedExecutions).Error; err != nil {
-		return nil, fmt.Errorf("failed to count failed executions: %w", err)
-	}
-	stats["failed_executions"] = failedExecutions
-	
+
+	// 单次聚合查询获取总数、成功数和失败数
+	var counts struct {
+		Total   int64
+		Success int64
+		Failed  int64
+	}
+	if err := scope.applyExecutions(database.GetDB().Model(&models.TaskExecution{})).
+		Select("COUNT(*) AS total, " +
+			"COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0) AS success, " +
+			"COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) AS failed").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count executions: %w", err)
+	}
+	stats["total_executions"] = counts.Total
+	stats["success_executions"] = counts.Success
+	stats["failed_executions"] = counts.Failed
+
 	// 计算成功率
 	var successRate float64 = 0
-	if totalExecutions > 0 {
-		successRate = float64(successExecutions) / float64(totalExecutions) * 100
+	if counts.Total > 0 {
+		successRate = float64(counts.Success) / float64(counts.Total) * 100
 	}
 	stats["success_rate"] = successRate
-	
+
 	return stats, nil
-}
\ No newline at end of file
+}
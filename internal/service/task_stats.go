@@ -0,0 +1,247 @@
+package service
+
+import (
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaskStatsWindow 某个时间窗口内的执行统计
+type TaskStatsWindow struct {
+	Total       int64   `json:"total"`
+	Success     int64   `json:"success"`
+	Failed      int64   `json:"failed"`
+	SuccessRate float64 `json:"success_rate"` // 百分比，无执行记录时为 0
+}
+
+// DurationStats 执行耗时分布（毫秒）
+type DurationStats struct {
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+	Max int64 `json:"max"`
+}
+
+// DailyTrend 每日平均耗时与成功率
+type DailyTrend struct {
+	Date        string  `json:"date"` // YYYY-MM-DD（本地时区）
+	Total       int64   `json:"total"`
+	Failed      int64   `json:"failed"`
+	AvgDuration int64   `json:"avg_duration"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// FailureStreaks 连续失败统计
+type FailureStreaks struct {
+	Current int `json:"current"` // 从最近一次执行往前数的连续失败次数
+	Longest int `json:"longest"` // 统计周期内最长的连续失败次数
+}
+
+// TaskStats 单个任务的执行统计
+type TaskStats struct {
+	TaskID   uint                       `json:"task_id"`
+	Windows  map[string]TaskStatsWindow `json:"windows"` // 24h, 7d, 30d
+	Duration DurationStats              `json:"duration"`
+	Trend    []DailyTrend               `json:"trend"`
+	Streaks  FailureStreaks             `json:"failure_streaks"`
+}
+
+// taskStatsDays 统计周期（天）
+const taskStatsDays = 30
+
+// maxStatsTasks 批量获取统计时一次最多的任务数
+const maxStatsTasks = 500
+
+// GetTaskStats 获取任务最近30天的成功率、耗时分布、每日趋势和连续失败统计
+func (s *TaskService) GetTaskStats(taskID uint) (*TaskStats, error) {
+	if _, err := s.GetTaskByID(taskID); err != nil {
+		return nil, err
+	}
+
+	stats, err := buildTaskStats([]uint{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return stats[0], nil
+}
+
+// GetTasksStats 批量获取多个任务的执行统计，不在范围内或不存在的任务会被忽略
+func (s *TaskService) GetTasksStats(taskIDs []uint, scope NamespaceScope) ([]*TaskStats, error) {
+	if len(taskIDs) > maxStatsTasks {
+		return nil, fmt.Errorf("too many tasks: at most %d per request", maxStatsTasks)
+	}
+	if len(taskIDs) == 0 {
+		return []*TaskStats{}, nil
+	}
+
+	var visible []uint
+	if err := scope.applyTasks(database.GetDB().Model(&models.Task{})).
+		Where("tasks.id IN ?", taskIDs).
+		Order("tasks.id").
+		Pluck("tasks.id", &visible).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	if len(visible) == 0 {
+		return []*TaskStats{}, nil
+	}
+	return buildTaskStats(visible)
+}
+
+// buildTaskStats 在数据库中聚合各任务最近30天的执行记录，运行中的记录不参与统计
+func buildTaskStats(taskIDs []uint) ([]*TaskStats, error) {
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	trendStart := dayStart.AddDate(0, 0, -(taskStatsDays - 1))
+	// 30d 窗口是最早的时间边界，其他窗口和每日趋势都在它之内
+	since := now.AddDate(0, 0, -taskStatsDays)
+
+	db := database.GetDB()
+	executions := func() *gorm.DB {
+		return db.Model(&models.TaskExecution{}).
+			Where("task_id IN ? AND started_at >= ? AND status <> ?", taskIDs, since, "running")
+	}
+
+	result := make([]*TaskStats, 0, len(taskIDs))
+	byTask := make(map[uint]*TaskStats, len(taskIDs))
+	for _, taskID := range taskIDs {
+		stats := &TaskStats{
+			TaskID:  taskID,
+			Windows: make(map[string]TaskStatsWindow),
+			Trend:   make([]DailyTrend, taskStatsDays),
+		}
+		for i := range stats.Trend {
+			stats.Trend[i].Date = trendStart.AddDate(0, 0, i).Format("2006-01-02")
+		}
+		for _, name := range []string{"24h", "7d", "30d"} {
+			stats.Windows[name] = TaskStatsWindow{}
+		}
+		result = append(result, stats)
+		byTask[taskID] = stats
+	}
+
+	// 各时间窗口的执行次数和最长耗时
+	var counts []struct {
+		TaskID      uint
+		Total       int64
+		Success     int64
+		Total7d     int64
+		Success7d   int64
+		Total24h    int64
+		Success24h  int64
+		MaxDuration int64
+	}
+	last7d, last24h := now.AddDate(0, 0, -7), now.Add(-24*time.Hour)
+	if err := executions().
+		Select("task_id, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS success, "+
+			"SUM(CASE WHEN julianday(started_at) >= julianday(?) THEN 1 ELSE 0 END) AS total7d, "+
+			"SUM(CASE WHEN julianday(started_at) >= julianday(?) AND status = 'success' THEN 1 ELSE 0 END) AS success7d, "+
+			"SUM(CASE WHEN julianday(started_at) >= julianday(?) THEN 1 ELSE 0 END) AS total24h, "+
+			"SUM(CASE WHEN julianday(started_at) >= julianday(?) AND status = 'success' THEN 1 ELSE 0 END) AS success24h, "+
+			"MAX(duration) AS max_duration", last7d, last7d, last24h, last24h).
+		Group("task_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count task executions: %w", err)
+	}
+	// 每个任务的成功次数，用于判断最后一段连续失败是否还在继续
+	successes := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		stats := byTask[count.TaskID]
+		stats.Windows["24h"] = newTaskStatsWindow(count.Total24h, count.Success24h)
+		stats.Windows["7d"] = newTaskStatsWindow(count.Total7d, count.Success7d)
+		stats.Windows["30d"] = newTaskStatsWindow(count.Total, count.Success)
+		stats.Duration.Max = count.MaxDuration
+		successes[count.TaskID] = count.Success
+	}
+
+	// 耗时分布，使用最近秩法：排名为 ceil(p * n / 100)
+	var ranked []struct {
+		TaskID   uint
+		Duration int64
+		Position int64
+		Total    int64
+	}
+	if err := db.Table("(?) AS ranked", executions().
+		Select("task_id, duration, "+
+			"ROW_NUMBER() OVER (PARTITION BY task_id ORDER BY duration) AS position, "+
+			"COUNT(*) OVER (PARTITION BY task_id) AS total")).
+		Where("position = (50 * total + 99) / 100 OR position = (95 * total + 99) / 100").
+		Scan(&ranked).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task durations: %w", err)
+	}
+	for _, row := range ranked {
+		stats := byTask[row.TaskID]
+		if row.Position == (50*row.Total+99)/100 {
+			stats.Duration.P50 = row.Duration
+		}
+		if row.Position == (95*row.Total+99)/100 {
+			stats.Duration.P95 = row.Duration
+		}
+	}
+
+	// 每日趋势，按距离统计起始日零点的天数分组，没有执行的日期也保留以便绘图
+	var days []struct {
+		TaskID        uint
+		Day           int
+		Total         int64
+		Failed        int64
+		DurationTotal int64
+	}
+	if err := executions().
+		Select("task_id, CAST(julianday(started_at) - julianday(?) AS INTEGER) AS day, COUNT(*) AS total, "+
+			"SUM(CASE WHEN status <> 'success' THEN 1 ELSE 0 END) AS failed, "+
+			"SUM(duration) AS duration_total", trendStart).
+		Where("julianday(started_at) >= julianday(?)", trendStart).
+		Group("task_id, day").
+		Scan(&days).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task trend: %w", err)
+	}
+	for _, row := range days {
+		if row.Day < 0 || row.Day >= taskStatsDays || row.Total == 0 {
+			continue
+		}
+		day := &byTask[row.TaskID].Trend[row.Day]
+		day.Total = row.Total
+		day.Failed = row.Failed
+		day.AvgDuration = row.DurationTotal / row.Total
+		day.SuccessRate = float64(row.Total-row.Failed) / float64(row.Total) * 100
+	}
+
+	// 连续失败：以之前的成功次数给每次执行分组，同一组中的失败是连续的
+	var streaks []struct {
+		TaskID    uint
+		Successes int64
+		Failures  int
+	}
+	if err := db.Table("(?) AS grouped", executions().
+		Select("task_id, status, "+
+			"SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) OVER (PARTITION BY task_id ORDER BY started_at, id) AS successes")).
+		Select("task_id, successes, COUNT(*) AS failures").
+		Where("status <> ?", "success").
+		Group("task_id, successes").
+		Scan(&streaks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get task failure streaks: %w", err)
+	}
+	for _, row := range streaks {
+		stats := byTask[row.TaskID]
+		if row.Failures > stats.Streaks.Longest {
+			stats.Streaks.Longest = row.Failures
+		}
+		if row.Successes == successes[row.TaskID] {
+			stats.Streaks.Current = row.Failures
+		}
+	}
+
+	return result, nil
+}
+
+// newTaskStatsWindow 根据执行次数和成功次数生成窗口统计
+func newTaskStatsWindow(total, success int64) TaskStatsWindow {
+	w := TaskStatsWindow{Total: total, Success: success, Failed: total - success}
+	if total > 0 {
+		w.SuccessRate = float64(success) / float64(total) * 100
+	}
+	return w
+}
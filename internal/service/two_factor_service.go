@@ -0,0 +1,196 @@
+package service
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// TwoFactorService TOTP 两步验证的开通、关闭和恢复码管理
+type TwoFactorService struct{}
+
+// TOTPSetup 开通两步验证时展示给用户的密钥和二维码
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG 图片的 data URI
+}
+
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{}
+}
+
+// BeginSetup 生成新的 TOTP 密钥并保存为待确认状态，用户用认证器扫码后通过 Enable 确认
+func (s *TwoFactorService) BeginSetup(user *models.User) (*TOTPSetup, error) {
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	key, err := auth.GenerateTOTPKey(user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	if err := database.GetDB().Model(user).UpdateColumn("totp_secret", key.Secret()).Error; err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	return &TOTPSetup{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Enable 校验认证器生成的动态码，开启两步验证并返回新的恢复码（只展示一次）
+func (s *TwoFactorService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("two-factor setup has not been started")
+	}
+
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, 0, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor authentication code")
+	}
+
+	var codes []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}
+		if err := tx.Model(user).UpdateColumns(updates).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	return codes, nil
+}
+
+// Disable 用户自己关闭两步验证，需要当前密码和动态码（或恢复码）
+func (s *TwoFactorService) Disable(user *models.User, password, code string) error {
+	if !user.TOTPEnabled {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return fmt.Errorf("current password is incorrect")
+	}
+	if err := s.verify(user, code); err != nil {
+		return err
+	}
+	return s.Reset(user.ID)
+}
+
+// Reset 清除用户的两步验证设置和恢复码，管理员可为丢失认证器的用户重置
+func (s *TwoFactorService) Reset(userID uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumns(updates).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes 作废旧的恢复码并生成新的一组，需要动态码（或恢复码）
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if !user.TOTPEnabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+	if err := s.verify(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RemainingRecoveryCodes 统计用户未使用的恢复码数量
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	if err := database.GetDB().Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (s *TwoFactorService) verify(user *models.User, code string) error {
+	ok, err := auth.VerifySecondFactor(user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invalid two-factor authentication code")
+	}
+	return nil
+}
+
+// replaceRecoveryCodes 删除用户现有的恢复码并生成新的一组，数据库只保存哈希
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: auth.HashRecoveryCode(code)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
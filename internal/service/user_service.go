@@ -0,0 +1,341 @@
+package service
+
+import (
+	"b1cron/internal/auth"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrUsernameTaken 单点登录用户的用户名已被其他账户使用
+var ErrUsernameTaken = errors.New("username is already used by another account")
+
+// UserService 用户管理服务
+type UserService struct{}
+
+func NewUserService() *UserService {
+	return &UserService{}
+}
+
+func (s *UserService) ListUsers() ([]models.User, error) {
+	var users []models.User
+	if err := database.GetDB().Order("id ASC").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+func (s *UserService) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := database.GetDB().First(&user, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername 按用户名查找用户
+func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := database.GetDB().Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// CreateUser 创建用户并设置所属的命名空间，两者在同一个事务中写入；新用户首次登录必须修改密码
+func (s *UserService) CreateUser(username, password, role string, namespaceIDs []uint) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("username cannot be empty")
+	}
+	if !auth.ValidRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	var count int64
+	if err := database.GetDB().Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check username: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("username already exists: %s", username)
+	}
+	if err := checkNamespaceIDs(database.GetDB(), namespaceIDs); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Username:            username,
+		PasswordHash:        string(hashedPassword),
+		Role:                role,
+		ForcePasswordChange: true,
+	}
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return replaceUserNamespaces(tx, user.ID, namespaceIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ProvisionExternalUser 单点登录用户首次登录时自动创建账户，之后每次登录按身份提供方映射的角色同步；
+// 返回用户、是否新建和同步前的角色
+func (s *UserService) ProvisionExternalUser(provider, externalID, username, role string) (*models.User, bool, string, error) {
+	if !auth.ValidRole(role) {
+		return nil, false, "", fmt.Errorf("invalid role: %s", role)
+	}
+
+	var user models.User
+	err := database.GetDB().Where("auth_provider = ? AND external_id = ?", provider, externalID).First(&user).Error
+	if err == nil {
+		previousRole := user.Role
+		if user.Role != role {
+			// 身份提供方撤销最后一个管理员时保留原角色，避免系统中没有管理员
+			if user.Role == auth.RoleAdmin {
+				if err := s.ensureAnotherAdmin(user.ID); err != nil {
+					log.Printf("Keeping admin role for %s: %v", user.Username, err)
+					return &user, false, previousRole, nil
+				}
+			}
+			if err := database.GetDB().Model(&user).Update("role", role).Error; err != nil {
+				return nil, false, "", fmt.Errorf("failed to update user role: %w", err)
+			}
+			user.Role = role
+		}
+		return &user, false, previousRole, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, false, "", fmt.Errorf("username cannot be empty")
+	}
+	var count int64
+	if err := database.GetDB().Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, false, "", fmt.Errorf("failed to check username: %w", err)
+	}
+	if count > 0 {
+		// 不自动关联同名的本地账户，否则身份提供方可以接管任意本地用户
+		return nil, false, "", ErrUsernameTaken
+	}
+
+	// 单点登录用户不使用密码登录，设置一个随机密码
+	randomPassword := make([]byte, 32)
+	if _, err := rand.Read(randomPassword); err != nil {
+		return nil, false, "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(randomPassword)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, false, "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user = models.User{
+		Username:     username,
+		PasswordHash: string(hashedPassword),
+		Role:         role,
+		AuthProvider: provider,
+		ExternalID:   externalID,
+	}
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		return nil, false, "", fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, true, "", nil
+}
+
+// UserUpdate 管理员对用户的修改，零值的字段不修改
+type UserUpdate struct {
+	Role         string
+	Password     string
+	NamespaceIDs *[]uint // 非空时覆盖用户所属的命名空间
+}
+
+// UpdateUser 修改用户的角色、密码和所属命名空间：先校验全部修改，再在同一个事务中写入，
+// 任何一项失败时都不会留下部分修改。不允许移除最后一个管理员
+func (s *UserService) UpdateUser(id uint, update UserUpdate) (*models.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Role != "" {
+		if !auth.ValidRole(update.Role) {
+			return nil, fmt.Errorf("invalid role: %s", update.Role)
+		}
+		if user.Role == auth.RoleAdmin && update.Role != auth.RoleAdmin {
+			if err := s.ensureAnotherAdmin(user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var hashedPassword string
+	if update.Password != "" {
+		if hashedPassword, err = hashResetPassword(user, update.Password); err != nil {
+			return nil, err
+		}
+	}
+	if update.NamespaceIDs != nil {
+		if err := checkNamespaceIDs(database.GetDB(), *update.NamespaceIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if update.Role != "" {
+			if err := tx.Model(user).Update("role", update.Role).Error; err != nil {
+				return fmt.Errorf("failed to update user role: %w", err)
+			}
+		}
+		if hashedPassword != "" {
+			if err := resetPassword(tx, user, hashedPassword); err != nil {
+				return err
+			}
+		}
+		if update.NamespaceIDs != nil {
+			return replaceUserNamespaces(tx, user.ID, *update.NamespaceIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Role != "" {
+		user.Role = update.Role
+	}
+	if hashedPassword != "" {
+		user.PasswordHash = hashedPassword
+		user.ForcePasswordChange = true
+	}
+	return user, nil
+}
+
+// ResetPassword 管理员重置用户密码，用户的全部会话立即失效，下次登录必须修改密码
+func (s *UserService) ResetPassword(id uint, password string) error {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := hashResetPassword(user, password)
+	if err != nil {
+		return err
+	}
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return resetPassword(tx, user, hashedPassword)
+	})
+}
+
+// hashResetPassword 确认用户使用本地密码登录，并计算新密码的哈希
+func hashResetPassword(user *models.User, password string) (string, error) {
+	if user.AuthProvider != "" && user.AuthProvider != auth.ProviderLocal {
+		return "", fmt.Errorf("cannot set a password for %s user %s", user.AuthProvider, user.Username)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashedPassword), nil
+}
+
+// resetPassword 在事务中写入新密码并删除用户的全部会话
+func resetPassword(tx *gorm.DB, user *models.User, hashedPassword string) error {
+	updates := map[string]interface{}{
+		"password_hash":         hashedPassword,
+		"force_password_change": true,
+	}
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+// UnlockUser 解除账户的登录锁定并清零失败次数
+func (s *UserService) UnlockUser(id uint) (*models.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}
+	if err := database.GetDB().Model(user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to unlock user: %w", err)
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	return user, nil
+}
+
+// DeleteUser 删除用户，不能删除自己或最后一个管理员
+func (s *UserService) DeleteUser(id, currentUserID uint) error {
+	if id == currentUserID {
+		return fmt.Errorf("cannot delete the current user")
+	}
+
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == auth.RoleAdmin {
+		if err := s.ensureAnotherAdmin(user.ID); err != nil {
+			return err
+		}
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.NamespaceMember{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user namespaces: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.APIToken{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user api tokens: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user recovery codes: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user sessions: %w", err)
+		}
+		// 物理删除，释放用户名以便重新创建
+		if err := tx.Unscoped().Delete(user).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *UserService) ensureAnotherAdmin(excludeID uint) error {
+	var count int64
+	err := database.GetDB().Model(&models.User{}).
+		Where("role = ? AND id <> ?", auth.RoleAdmin, excludeID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("at least one admin is required")
+	}
+	return nil
+}
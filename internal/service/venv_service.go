@@ -0,0 +1,390 @@
+package service
+
+import (
+	"b1cron/internal/config"
+	"b1cron/internal/database"
+	"b1cron/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// venvReadyMarker 虚拟环境构建成功后写入的标记文件，内容为完成时间
+const venvReadyMarker = ".b1cron-ready"
+
+// venvLogTailLines 构建失败时错误信息中附带的日志行数
+const venvLogTailLines = 20
+
+// 虚拟环境的状态
+const (
+	VenvReady    = "ready"
+	VenvBuilding = "building"
+	VenvFailed   = "failed"
+	VenvMissing  = "missing"
+)
+
+// ErrVenvBuilding 虚拟环境正在构建
+var ErrVenvBuilding = errors.New("virtualenv is already being built")
+
+// VenvStatus 虚拟环境的状态和最近一次构建的日志
+type VenvStatus struct {
+	Hash    string     `json:"hash"`
+	State   string     `json:"state"`
+	Path    string     `json:"path"`
+	BuiltAt *time.Time `json:"built_at,omitempty"`
+	Log     string     `json:"log"`
+}
+
+// venvBuild 正在进行的构建，done 关闭后 err 为构建结果
+type venvBuild struct {
+	done chan struct{}
+	err  error
+}
+
+// VenvService 按依赖列表构建并缓存 Python 虚拟环境，依赖相同的任务共用同一个虚拟环境
+type VenvService struct {
+	dir     string
+	python  config.PythonConfig
+	timeout time.Duration
+	scripts *ScriptFileService
+
+	mu     sync.Mutex
+	builds map[string]*venvBuild
+	// failures 构建失败的虚拟环境及错误（含日志末尾），依赖变化或手动重新构建之前不再自动重试
+	failures map[string]error
+}
+
+// NewVenvService 创建虚拟环境服务，虚拟环境保存在数据目录的 venvs 子目录中
+func NewVenvService(cfg *config.Config) *VenvService {
+	scripts := NewScriptFileService(cfg)
+	dir, err := filepath.Abs(filepath.Join(scripts.dataDir, "venvs"))
+	if err != nil {
+		dir = filepath.Join(scripts.dataDir, "venvs")
+	}
+	return &VenvService{
+		dir:     dir,
+		python:  cfg.Python,
+		timeout: cfg.GetVenvBuildTimeout(),
+		scripts: scripts,
+		builds:  make(map[string]*venvBuild),
+
+		failures: make(map[string]error),
+	}
+}
+
+// ParseRequirements 检查依赖列表并去掉注释和空行。每行一个 pip 依赖说明，
+// 不允许 -r、--index-url 等选项，安装来源由配置决定
+func ParseRequirements(text string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			return "", fmt.Errorf("invalid requirement %q: pip options are not allowed", line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SupportsRequirements 脚本类型是否为 Python 运行时（脚本扩展名为 py）
+func (s *VenvService) SupportsRequirements(scriptType string) bool {
+	runtime, ok := s.scripts.runtime(scriptType)
+	return ok && runtime.Extension == "py"
+}
+
+// hash 依赖列表（与顺序无关）和解释器决定虚拟环境
+func (s *VenvService) hash(requirements string) string {
+	lines := strings.Split(requirements, "\n")
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(s.python.Interpreter + "\n" + strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// paths 返回虚拟环境目录和构建日志路径
+func (s *VenvService) paths(hash string) (string, string) {
+	return filepath.Join(s.dir, hash), filepath.Join(s.dir, hash+".log")
+}
+
+// binDir 虚拟环境中可执行文件所在的目录
+func binDir(dir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "Scripts")
+	}
+	return filepath.Join(dir, "bin")
+}
+
+// Status 获取依赖列表对应的虚拟环境的状态
+func (s *VenvService) Status(requirements string) *VenvStatus {
+	hash := s.hash(requirements)
+	dir, logPath := s.paths(hash)
+	status := &VenvStatus{Hash: hash, Path: dir, State: VenvMissing}
+	if data, err := os.ReadFile(logPath); err == nil {
+		status.Log = string(data)
+		status.State = VenvFailed
+	}
+
+	s.mu.Lock()
+	_, building := s.builds[hash]
+	s.mu.Unlock()
+
+	switch {
+	case building:
+		status.State = VenvBuilding
+	case s.ready(dir):
+		status.State = VenvReady
+		if info, err := os.Stat(filepath.Join(dir, venvReadyMarker)); err == nil {
+			builtAt := info.ModTime()
+			status.BuiltAt = &builtAt
+		}
+	}
+	return status
+}
+
+func (s *VenvService) ready(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, venvReadyMarker))
+	return err == nil
+}
+
+// Build 在后台构建虚拟环境，已经可用、正在构建或上次构建失败时不做任何事
+func (s *VenvService) Build(requirements string) {
+	hash := s.hash(requirements)
+	dir, _ := s.paths(hash)
+	if !s.ready(dir) && s.failure(hash) == nil {
+		s.start(requirements, hash)
+	}
+}
+
+// failure 虚拟环境上次构建失败的错误
+func (s *VenvService) failure(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[hash]
+}
+
+// Rebuild 删除已有的虚拟环境并在后台重新构建
+func (s *VenvService) Rebuild(requirements string) error {
+	hash := s.hash(requirements)
+	s.mu.Lock()
+	_, building := s.builds[hash]
+	s.mu.Unlock()
+	if building {
+		return ErrVenvBuilding
+	}
+
+	dir, _ := s.paths(hash)
+	if err := os.Remove(filepath.Join(dir, venvReadyMarker)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove virtualenv: %w", err)
+	}
+	s.mu.Lock()
+	delete(s.failures, hash)
+	s.mu.Unlock()
+	s.start(requirements, hash)
+	return nil
+}
+
+// Ensure 返回可用的虚拟环境目录，需要时构建并等待完成；
+// 上次构建失败时直接返回失败原因，不会每次执行都重新构建
+func (s *VenvService) Ensure(requirements string) (string, error) {
+	hash := s.hash(requirements)
+	dir, _ := s.paths(hash)
+	if s.ready(dir) {
+		return dir, nil
+	}
+	if err := s.failure(hash); err != nil {
+		return dir, fmt.Errorf("%w\n(rebuild the virtualenv in the task editor after fixing the requirements)", err)
+	}
+	build := s.start(requirements, hash)
+	<-build.done
+	return dir, build.err
+}
+
+// start 开始构建，同一个虚拟环境同时只有一个构建
+func (s *VenvService) start(requirements, hash string) *venvBuild {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if build, ok := s.builds[hash]; ok {
+		return build
+	}
+
+	build := &venvBuild{done: make(chan struct{})}
+	s.builds[hash] = build
+	go func() {
+		build.err = s.build(requirements, hash)
+		if build.err != nil {
+			log.Printf("Failed to build virtualenv %s: %v", hash, build.err)
+		} else {
+			log.Printf("Virtualenv %s built", hash)
+		}
+		s.mu.Lock()
+		delete(s.builds, hash)
+		if build.err != nil {
+			s.failures[hash] = build.err
+		} else {
+			delete(s.failures, hash)
+		}
+		s.mu.Unlock()
+		close(build.done)
+	}()
+	return build
+}
+
+// build 创建虚拟环境并安装依赖，输出写入构建日志；失败时删除不完整的虚拟环境
+func (s *VenvService) build(requirements, hash string) error {
+	dir, logPath := s.paths(hash)
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create virtualenv directory: %w", err)
+	}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create build log: %w", err)
+	}
+	defer logFile.Close()
+
+	fmt.Fprintf(logFile, "# %s building virtualenv %s\n# requirements:\n%s\n\n", time.Now().Format("2006-01-02 15:04:05"), hash, requirements)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove old virtualenv: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	run := func(name string, args ...string) error {
+		fmt.Fprintf(logFile, "$ %s %s\n", name, strings.Join(args, " "))
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %v", s.timeout)
+		}
+		return err
+	}
+
+	requirementsPath := filepath.Join(dir, "requirements.txt")
+	install := []string{"-m", "pip", "install", "--disable-pip-version-check", "--no-input", "-r", requirementsPath}
+	if s.python.Wheelhouse != "" {
+		install = append(install, "--no-index", "--find-links", s.python.Wheelhouse)
+	} else if s.python.IndexURL != "" {
+		install = append(install, "--index-url", s.python.IndexURL)
+	}
+
+	err = run(s.python.Interpreter, "-m", "venv", dir)
+	if err == nil {
+		err = os.WriteFile(requirementsPath, []byte(requirements+"\n"), 0644)
+	}
+	if err == nil {
+		err = run(filepath.Join(binDir(dir), "python"), install...)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, venvReadyMarker), []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(logFile, "\n# build failed: %v\n", err)
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to build virtualenv: %w\n%s", err, logTail(logPath, venvLogTailLines))
+	}
+	fmt.Fprintf(logFile, "\n# %s build succeeded\n", time.Now().Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// logTail 读取日志的最后几行
+func logTail(path string, lines int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	all := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, "\n")
+}
+
+// Prepare 实现 scheduler.TaskEnvironment：声明了依赖的 Python 任务在对应的虚拟环境中执行，
+// 虚拟环境的 bin 目录放在 PATH 最前面，执行命令中的 python3 即为虚拟环境中的解释器
+func (s *VenvService) Prepare(taskID uint, environ []string) ([]string, error) {
+	var task models.Task
+	if err := database.GetDB().Select("id", "script_type", "requirements").First(&task, taskID).Error; err != nil {
+		return environ, nil
+	}
+	if task.Requirements == "" || !s.SupportsRequirements(task.ScriptType) {
+		return environ, nil
+	}
+
+	dir, err := s.Ensure(task.Requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	path := ""
+	for _, entry := range environ {
+		if value, ok := strings.CutPrefix(entry, "PATH="); ok {
+			path = value
+		}
+	}
+	bin := binDir(dir)
+	if path != "" {
+		bin += string(os.PathListSeparator) + path
+	}
+	return append(environ, "VIRTUAL_ENV="+dir, "PATH="+bin), nil
+}
+
+// CheckRequirements 检查依赖列表并返回整理后的内容，只有 Python 任务可以声明依赖
+func (s *TaskService) CheckRequirements(scriptType, requirements string) (string, error) {
+	requirements, err := ParseRequirements(requirements)
+	if err != nil {
+		return "", err
+	}
+	if requirements != "" && !s.venvs.SupportsRequirements(scriptType) {
+		return "", fmt.Errorf("requirements are only supported for Python tasks")
+	}
+	return requirements, nil
+}
+
+// SetTaskRequirements 修改任务的依赖列表，并在后台构建对应的虚拟环境
+func (s *TaskService) SetTaskRequirements(task *models.Task, requirements string) error {
+	requirements, err := s.CheckRequirements(task.ScriptType, requirements)
+	if err != nil {
+		return err
+	}
+	if err := database.GetDB().Model(task).UpdateColumn("requirements", requirements).Error; err != nil {
+		return fmt.Errorf("failed to update task requirements: %w", err)
+	}
+	task.Requirements = requirements
+	if requirements != "" {
+		s.venvs.Build(requirements)
+	}
+	return nil
+}
+
+// GetTaskVenv 获取任务的虚拟环境状态，任务没有声明依赖时返回 nil
+func (s *TaskService) GetTaskVenv(task *models.Task) *VenvStatus {
+	if task.Requirements == "" || !s.venvs.SupportsRequirements(task.ScriptType) {
+		return nil
+	}
+	return s.venvs.Status(task.Requirements)
+}
+
+// RebuildTaskVenv 重新构建任务的虚拟环境
+func (s *TaskService) RebuildTaskVenv(task *models.Task) error {
+	if task.Requirements == "" || !s.venvs.SupportsRequirements(task.ScriptType) {
+		return fmt.Errorf("task has no requirements")
+	}
+	return s.venvs.Rebuild(task.Requirements)
+}
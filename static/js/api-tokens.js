@@ -0,0 +1,161 @@
+/**
+ * B1Cron API Tokens
+ *
+ * 个人 API 令牌管理：创建、查看和撤销令牌
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    const form = document.getElementById('createAPITokenForm');
+    if (form) {
+        form.addEventListener('submit', handleCreateAPIToken);
+    }
+});
+
+function showAPITokens() {
+    window.b1cron.closeModal('settingsModal');
+    window.b1cron.showModal('apiTokensModal');
+    document.getElementById('newTokenBox').classList.add('hidden');
+    document.getElementById('newTokenValue').value = '';
+    loadAPITokens();
+}
+
+async function loadAPITokens() {
+    const tbody = document.getElementById('apiTokensTableBody');
+    if (!tbody) return;
+
+    try {
+        const response = await fetch('/api/tokens');
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载令牌失败', 'error');
+            return;
+        }
+        renderAPITokens(tbody, data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function formatTokenTime(value, emptyText) {
+    return value ? new Date(value).toLocaleString('zh-CN') : emptyText;
+}
+
+function renderAPITokens(tbody, tokens) {
+    tbody.innerHTML = '';
+
+    if (tokens.length === 0) {
+        const row = document.createElement('tr');
+        const cell = document.createElement('td');
+        cell.colSpan = 6;
+        cell.className = 'px-4 py-4 text-center text-sm text-slate-500';
+        cell.textContent = '暂无令牌';
+        row.appendChild(cell);
+        tbody.appendChild(row);
+        return;
+    }
+
+    const now = new Date();
+    tokens.forEach(token => {
+        const row = document.createElement('tr');
+        const expired = token.expires_at && new Date(token.expires_at) < now;
+        const inactive = token.revoked_at || expired;
+        if (inactive) {
+            row.className = 'opacity-50';
+        }
+
+        const cells = [
+            token.name,
+            `${token.prefix}…`,
+            token.scopes,
+            formatTokenTime(token.expires_at, '永不过期'),
+            formatTokenTime(token.last_used_at, '从未使用')
+        ];
+        cells.forEach((text, index) => {
+            const cell = document.createElement('td');
+            cell.className = 'px-4 py-2 text-sm text-slate-700' + (index === 1 ? ' font-mono' : '');
+            cell.textContent = text;
+            row.appendChild(cell);
+        });
+
+        const actionCell = document.createElement('td');
+        actionCell.className = 'px-4 py-2 text-sm';
+        if (token.revoked_at) {
+            actionCell.textContent = '已撤销';
+        } else if (expired) {
+            actionCell.textContent = '已过期';
+        } else {
+            actionCell.appendChild(createUserActionButton('撤销', 'text-red-700 border-red-300 bg-red-50 hover:bg-red-100', () => revokeAPIToken(token)));
+        }
+        row.appendChild(actionCell);
+
+        tbody.appendChild(row);
+    });
+}
+
+async function handleCreateAPIToken(e) {
+    e.preventDefault();
+
+    const form = e.target;
+    const formData = new FormData(form);
+    const scopes = formData.getAll('scopes');
+    if (scopes.length === 0) {
+        window.b1cron.showToast('请至少选择一个权限', 'error');
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/tokens', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                name: formData.get('name'),
+                scopes: scopes,
+                expires_in_days: parseInt(formData.get('expires_in_days'), 10)
+            })
+        });
+        const data = await response.json();
+
+        if (response.ok) {
+            document.getElementById('newTokenValue').value = data.token;
+            document.getElementById('newTokenBox').classList.remove('hidden');
+            form.reset();
+            loadAPITokens();
+        } else {
+            window.b1cron.showToast(data.error || data.message || '创建令牌失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function copyNewToken() {
+    const input = document.getElementById('newTokenValue');
+    input.select();
+    navigator.clipboard.writeText(input.value).then(
+        () => window.b1cron.showToast('已复制到剪贴板', 'success'),
+        () => window.b1cron.showToast('复制失败，请手动复制', 'error')
+    );
+}
+
+async function revokeAPIToken(token) {
+    if (!confirm(`确定要撤销令牌「${token.name}」吗？使用该令牌的脚本将立即失效。`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`/api/tokens/${token.id}`, { method: 'DELETE' });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('令牌已撤销', 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '撤销令牌失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadAPITokens();
+}
+
+window.showAPITokens = showAPITokens;
+window.copyNewToken = copyNewToken;
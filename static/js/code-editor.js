@@ -10,9 +10,12 @@ class CodeEditor {
             mode: 'shell',
             theme: 'default',
             lineWrapping: true,
+            gutters: ['cm-diagnostics'],
             ...options
         };
         this.editor = null;
+        this.diagnostics = [];
+        this.diagnosticList = null;
         this.init();
     }
 
@@ -82,6 +85,69 @@ class CodeEditor {
         }
     }
 
+    // 显示语法检查的结果：有行号的问题在对应行标记背景和边栏图标，全部问题列在编辑器下方
+    setDiagnostics(diagnostics) {
+        this.clearDiagnostics();
+        this.diagnostics = diagnostics || [];
+        if (!this.diagnostics.length) return;
+
+        if (this.editor) {
+            const lastLine = this.editor.lineCount() - 1;
+            this.diagnostics.forEach(diagnostic => {
+                if (!diagnostic.line) return;
+                const line = Math.min(diagnostic.line - 1, lastLine);
+                const severity = diagnostic.severity === 'error' ? 'error' : 'warning';
+                this.editor.addLineClass(line, 'background', `cm-diagnostic-${severity}`);
+
+                const marker = document.createElement('span');
+                marker.className = `cm-diagnostic-marker cm-diagnostic-marker-${severity}`;
+                marker.textContent = '●';
+                const previous = this.editor.lineInfo(line).gutterMarkers;
+                const title = previous && previous['cm-diagnostics'] ? previous['cm-diagnostics'].title + '\n' : '';
+                marker.title = title + diagnostic.message;
+                this.editor.setGutterMarker(line, 'cm-diagnostics', marker);
+            });
+        }
+
+        this.diagnosticList = document.createElement('ul');
+        this.diagnosticList.className = 'cm-diagnostic-list';
+        this.diagnostics.forEach(diagnostic => {
+            const item = document.createElement('li');
+            item.className = `cm-diagnostic-item cm-diagnostic-item-${diagnostic.severity}`;
+            const location = diagnostic.line ? `第 ${diagnostic.line} 行` : '脚本';
+            item.textContent = `${location}: ${diagnostic.message}`;
+            if (diagnostic.source) {
+                item.title = diagnostic.source;
+            }
+            if (diagnostic.line && this.editor) {
+                item.addEventListener('click', () => {
+                    this.editor.setCursor({ line: diagnostic.line - 1, ch: Math.max((diagnostic.column || 1) - 1, 0) });
+                    this.editor.focus();
+                });
+            }
+            this.diagnosticList.appendChild(item);
+        });
+        const anchor = this.editor ? this.editor.getWrapperElement() : this.textarea;
+        if (anchor) {
+            anchor.insertAdjacentElement('afterend', this.diagnosticList);
+        }
+    }
+
+    clearDiagnostics() {
+        if (this.editor) {
+            this.editor.clearGutter('cm-diagnostics');
+            for (let line = 0; line < this.editor.lineCount(); line++) {
+                this.editor.removeLineClass(line, 'background', 'cm-diagnostic-error');
+                this.editor.removeLineClass(line, 'background', 'cm-diagnostic-warning');
+            }
+        }
+        if (this.diagnosticList) {
+            this.diagnosticList.remove();
+            this.diagnosticList = null;
+        }
+        this.diagnostics = [];
+    }
+
     focus() {
         if (this.editor) {
             this.editor.focus();
@@ -0,0 +1,159 @@
+/**
+ * B1Cron Crontab Import
+ *
+ * 把现有服务器的 crontab 导入为任务：先预览可导入的任务和无法映射的行，确认后再创建
+ */
+
+function showCrontabImport() {
+    document.getElementById('crontabImportFile').value = '';
+    document.getElementById('crontabImportContent').value = '';
+    resetCrontabImportPlan();
+    window.b1cron.showModal('crontabImportModal');
+}
+
+function resetCrontabImportPlan() {
+    document.getElementById('crontabImportSummary').textContent = '';
+    document.getElementById('crontabImportPlan').innerHTML = '';
+    document.getElementById('crontabImportApply').disabled = true;
+}
+
+async function requestCrontabImport(dryRun) {
+    const content = document.getElementById('crontabImportContent').value;
+    if (!content.trim()) {
+        window.b1cron.showToast('请选择文件或粘贴 crontab 内容', 'error');
+        return null;
+    }
+
+    const params = new URLSearchParams({
+        dry_run: dryRun ? 'true' : 'false',
+        enabled: document.getElementById('crontabImportEnabled').checked ? 'true' : 'false'
+    });
+    const namespace = document.getElementById('crontabImportNamespace');
+    if (namespace) {
+        params.set('namespace_id', namespace.value);
+    }
+
+    try {
+        const response = await fetch(`/api/tasks/import/crontab?${params}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'text/plain' },
+            body: content
+        });
+        const data = await response.json();
+        if (!response.ok && !data.entries) {
+            window.b1cron.showToast(data.error || data.message || '导入失败', 'error');
+            return null;
+        }
+        return { ok: response.ok, result: data };
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+        return null;
+    }
+}
+
+async function previewCrontabImport() {
+    resetCrontabImportPlan();
+    const response = await requestCrontabImport(true);
+    if (!response) return;
+
+    renderCrontabImport(response.result);
+    document.getElementById('crontabImportApply').disabled = response.result.entries.length === 0;
+}
+
+async function applyCrontabImport() {
+    document.getElementById('crontabImportApply').disabled = true;
+    const response = await requestCrontabImport(false);
+    if (!response) return;
+
+    renderCrontabImport(response.result);
+    if (!response.ok) {
+        window.b1cron.showToast(response.result.error || '部分任务导入失败', 'error');
+        return;
+    }
+    window.b1cron.showToast('crontab 导入成功', 'success');
+    setTimeout(() => location.reload(), 1000);
+}
+
+function renderCrontabImport(result) {
+    const parts = [`可导入 ${result.entries.length} 个任务`];
+    if (result.skipped.length > 0) {
+        parts.push(`${result.skipped.length} 行无法映射`);
+    }
+    document.getElementById('crontabImportSummary').textContent = parts.join('，');
+
+    const container = document.getElementById('crontabImportPlan');
+    container.innerHTML = '';
+    result.skipped.forEach(line => container.appendChild(renderCrontabSkip(line)));
+    result.entries.forEach(entry => container.appendChild(renderCrontabEntry(entry, result.dry_run)));
+}
+
+function renderCrontabSkip(line) {
+    const box = document.createElement('div');
+    box.className = 'border border-slate-200 bg-warning-50 rounded-lg p-3 text-sm';
+
+    const text = document.createElement('div');
+    text.className = 'font-mono text-xs text-slate-700';
+    text.textContent = `${line.line}: ${line.text}`;
+    const reason = document.createElement('div');
+    reason.className = 'mt-1 text-warning-600';
+    reason.textContent = line.reason;
+    box.append(text, reason);
+    return box;
+}
+
+function renderCrontabEntry(entry, dryRun) {
+    const box = document.createElement('div');
+    box.className = 'border border-slate-200 rounded-lg p-3 text-sm';
+
+    const header = document.createElement('div');
+    header.className = 'flex items-center gap-2';
+    const badge = document.createElement('span');
+    badge.className = 'px-2 py-0.5 rounded text-xs font-medium ' + (entry.error ? 'bg-red-100 text-red-800' : 'bg-success-100 text-success-800');
+    badge.textContent = entry.error ? '失败' : dryRun ? '新增' : '已创建';
+    const name = document.createElement('span');
+    name.className = 'text-slate-900';
+    name.textContent = entry.name;
+    const schedule = document.createElement('span');
+    schedule.className = 'font-mono text-xs text-slate-500';
+    schedule.textContent = `${entry.schedule}（第 ${entry.line} 行）`;
+    header.append(badge, name, schedule);
+    box.appendChild(header);
+
+    if (entry.error) {
+        const error = document.createElement('div');
+        error.className = 'mt-2 text-red-600';
+        error.textContent = entry.error;
+        box.appendChild(error);
+    }
+
+    const command = document.createElement('pre');
+    command.className = 'mt-2 font-mono text-xs bg-slate-50 border border-slate-200 rounded-lg px-3 py-2 max-h-40 overflow-auto whitespace-pre-wrap';
+    command.textContent = entry.command;
+    box.appendChild(command);
+
+    const env = Object.keys(entry.env || {}).sort();
+    if (env.length > 0) {
+        const line = document.createElement('div');
+        line.className = 'mt-1 font-mono text-xs text-slate-600';
+        line.textContent = env.map(key => `${key}=${entry.env[key]}`).join('  ');
+        box.appendChild(line);
+    }
+    return box;
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    const file = document.getElementById('crontabImportFile');
+    const content = document.getElementById('crontabImportContent');
+    if (!file || !content) return;
+
+    file.addEventListener('change', async () => {
+        if (file.files.length === 0) return;
+        content.value = await file.files[0].text();
+        resetCrontabImportPlan();
+    });
+    content.addEventListener('input', resetCrontabImportPlan);
+});
+
+window.showCrontabImport = showCrontabImport;
+window.previewCrontabImport = previewCrontabImport;
+window.applyCrontabImport = applyCrontabImport;
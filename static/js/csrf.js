@@ -0,0 +1,50 @@
+/**
+ * B1Cron CSRF
+ *
+ * 写请求（POST/PUT/PATCH/DELETE）自动在请求头中带上 CSRF 令牌，覆盖 fetch 和 HTMX 请求。
+ * 令牌由服务端写入 b1cron_csrf Cookie，需要在其他脚本之前加载
+ */
+
+(function() {
+    const CSRF_COOKIE = 'b1cron_csrf';
+    const CSRF_HEADER = 'X-CSRF-Token';
+    const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+    function getCSRFToken() {
+        const match = document.cookie.match(new RegExp(`(?:^|;\\s*)${CSRF_COOKIE}=([^;]*)`));
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    // 只给本站的请求带上令牌，避免泄露给第三方
+    function isSameOrigin(url) {
+        try {
+            return new URL(url, window.location.href).origin === window.location.origin;
+        } catch (error) {
+            return false;
+        }
+    }
+
+    const originalFetch = window.fetch.bind(window);
+    window.fetch = function(input, init = {}) {
+        const request = input instanceof Request ? input : null;
+        const method = (init.method || (request && request.method) || 'GET').toUpperCase();
+        const url = request ? request.url : String(input);
+
+        if (!SAFE_METHODS.includes(method) && isSameOrigin(url)) {
+            const headers = new Headers(init.headers || (request && request.headers) || undefined);
+            if (!headers.has(CSRF_HEADER)) {
+                headers.set(CSRF_HEADER, getCSRFToken());
+            }
+            init = Object.assign({}, init, { headers });
+        }
+        return originalFetch(input, init);
+    };
+
+    document.addEventListener('htmx:configRequest', (e) => {
+        if (!SAFE_METHODS.includes(e.detail.verb.toUpperCase())) {
+            e.detail.headers[CSRF_HEADER] = getCSRFToken();
+        }
+    });
+
+    window.getCSRFToken = getCSRFToken;
+})();
@@ -34,6 +34,7 @@ function bindGlobalEvents() {
     // 其他不需要组件化的功能
     bindPasswordChange();
     bindExecutionDetails();
+    loadRetentionPolicy();
 }
 
 /**
@@ -85,6 +86,102 @@ async function handlePasswordChange(e) {
     }
 }
 
+/**
+ * 立即执行任务
+ */
+async function runTaskNow(taskId) {
+    try {
+        const response = await fetch(`/api/tasks/${taskId}/run`, { method: 'POST' });
+        const data = await response.json();
+        
+        if (response.ok) {
+            window.b1cron.showToast('任务已开始执行', 'success');
+            // 稍后刷新执行记录以显示本次执行
+            setTimeout(() => {
+                if (typeof loadExecutions === 'function') loadExecutions();
+            }, 1500);
+        } else {
+            window.b1cron.showToast(data.error || data.message || '执行失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+/**
+ * 执行记录保留策略
+ */
+async function loadRetentionPolicy() {
+    const policyElement = document.getElementById('retentionPolicy');
+    if (!policyElement) return;
+    
+    try {
+        const response = await fetch('/api/retention');
+        if (!response.ok) return;
+        
+        const data = await response.json();
+        const policy = data.policy || {};
+        const age = policy.MaxAgeDays > 0 ? `${policy.MaxAgeDays} 天` : '不限';
+        const rows = policy.MaxRowsPerTask > 0 ? `${policy.MaxRowsPerTask} 条/任务` : '不限';
+        policyElement.textContent = `保留时长: ${age}，保留条数: ${rows}，清理间隔: ${policy.PruneInterval}`;
+        
+        if (data.last_report) {
+            renderRetentionReport(data.last_report);
+        }
+    } catch (error) {
+        console.error('Failed to load retention policy:', error);
+    }
+}
+
+function renderRetentionReport(report) {
+    const reportElement = document.getElementById('retentionReport');
+    if (!reportElement) return;
+    
+    const time = new Date(report.completed_at).toLocaleString('zh-CN');
+    const title = report.dry_run ? '预览' : '上次清理';
+    let html = `<div class="font-medium">${title} (${time}): ${report.dry_run ? '将删除' : '已删除'} ${report.total_deleted} 条</div>`;
+    
+    (report.tasks || []).forEach(task => {
+        const name = escapeHtml(task.task_name || `#${task.task_id}`);
+        html += `<div class="pl-2">${name}: 按时间 ${task.deleted_by_age} 条，按条数 ${task.deleted_by_max_rows} 条</div>`;
+    });
+    
+    if (report.error) {
+        html += `<div class="text-red-600">${escapeHtml(report.error)}</div>`;
+    }
+    reportElement.innerHTML = html;
+}
+
+async function previewRetentionPrune() {
+    await requestRetentionPrune(true);
+}
+
+async function runRetentionPrune() {
+    if (!confirm('确定要按保留策略立即删除过期的执行记录吗？')) {
+        return;
+    }
+    await requestRetentionPrune(false);
+}
+
+async function requestRetentionPrune(dryRun) {
+    try {
+        const response = await fetch(`/api/retention/prune?dry_run=${dryRun}`, { method: 'POST' });
+        const data = await response.json();
+        
+        if (response.ok) {
+            renderRetentionReport(data);
+            if (!dryRun) {
+                window.b1cron.showToast(`已清理 ${data.total_deleted} 条执行记录`, 'success');
+            }
+        } else {
+            if (data.report) renderRetentionReport(data.report);
+            window.b1cron.showToast(response.status === 409 ? '已有清理正在进行，请稍后再试' : (data.error || '清理失败'), 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
 /**
  * 执行详情功能
  */
@@ -104,8 +201,9 @@ function showExecutionDetailFromElement(element) {
         const duration = parseInt(executionData.duration) || 0;
         const output = executionData.output || '';
         const errorMsg = executionData.error || '';
+        const revision = parseInt(executionData.revision) || 0;
         
-        showExecutionDetail(taskName, status, startTime, endTime, duration, output, errorMsg);
+        showExecutionDetail(taskName, status, startTime, endTime, duration, output, errorMsg, revision);
     } catch (error) {
         console.error('Error parsing execution data:', error);
         // 回退到旧的数据格式（如果存在）
@@ -122,9 +220,11 @@ function showExecutionDetailFromElement(element) {
 }
 
 // 显示执行详情模态框
-function showExecutionDetail(taskName, status, startTime, endTime, duration, output, errorMsg) {
+function showExecutionDetail(taskName, status, startTime, endTime, duration, output, errorMsg, revision) {
     // 设置基本信息
     document.getElementById('detailTaskName').textContent = taskName;
+    // 执行时的脚本版本，升级前的执行记录没有版本
+    document.getElementById('detailRevision').textContent = revision ? `脚本版本 v${revision}` : '';
     
     // 设置状态徽章
     const statusElement = document.getElementById('detailStatus');
@@ -225,7 +325,13 @@ async function fetchTaskAndEdit(taskId) {
 // // 为了向后兼容，保留一些全局函数引用
 // window.editTaskFromElement = editTaskFromElement;
 // window.showExecutionDetailFromElement = showExecutionDetailFromElement;
+window.runTaskNow = runTaskNow;
+window.previewRetentionPrune = previewRetentionPrune;
+window.runRetentionPrune = runRetentionPrune;
 
 // 将函数添加到全局作用域，供HTML调用
 window.editTaskFromElement = editTaskFromElement;
-window.showExecutionDetailFromElement = showExecutionDetailFromElement;
\ No newline at end of file
+window.showExecutionDetailFromElement = showExecutionDetailFromElement;
+window.runTaskNow = runTaskNow;
+window.previewRetentionPrune = previewRetentionPrune;
+window.runRetentionPrune = runRetentionPrune;
\ No newline at end of file
@@ -1,13 +1,14 @@
 /**
  * B1Cron Execution Records Management
  * 
- * 处理执行记录的搜索和分页功能
+ * 处理执行记录的搜索、过滤、排序、分页和导出功能
  */
 
 // 全局变量
 let executionCurrentPage = 1;
 let executionPageSize = 20;
 let executionSearchTerm = '';
+let executionOutputTerm = '';
 let executionTotalPages = 1;
 let executionTotalItems = 0;
 
@@ -29,8 +30,60 @@ document.addEventListener('DOMContentLoaded', function() {
             }, 300); // 300ms防抖
         });
     }
+    
+    // 输出内容搜索同样防抖
+    let outputTimeout;
+    const outputInput = document.getElementById('executionOutputInput');
+    if (outputInput) {
+        outputInput.addEventListener('input', function() {
+            clearTimeout(outputTimeout);
+            outputTimeout = setTimeout(() => {
+                executionOutputTerm = this.value.trim();
+                applyExecutionFilters();
+            }, 300);
+        });
+    }
 });
 
+// 构建过滤和排序参数（分页和导出共用）
+function buildExecutionFilterParams() {
+    const params = new URLSearchParams();
+    
+    if (executionSearchTerm) {
+        params.append('search', executionSearchTerm);
+    }
+    if (executionOutputTerm) {
+        params.append('q', executionOutputTerm);
+    }
+    
+    const statusFilter = document.getElementById('executionStatusFilter');
+    if (statusFilter && statusFilter.value) {
+        params.append('status', statusFilter.value);
+    }
+    
+    const sortSelect = document.getElementById('executionSort');
+    if (sortSelect && sortSelect.value) {
+        const [sort, order] = sortSelect.value.split(':');
+        params.append('sort', sort);
+        params.append('order', order);
+    }
+    
+    return params;
+}
+
+// 过滤条件变化时回到第一页重新加载
+function applyExecutionFilters() {
+    executionCurrentPage = 1;
+    loadExecutions();
+}
+
+// 按当前过滤条件导出执行记录
+function exportExecutions(format) {
+    const params = buildExecutionFilterParams();
+    params.append('format', format);
+    window.location.href = `/api/executions/export?${params}`;
+}
+
 // 加载执行记录
 async function loadExecutions() {
     const pageSize = document.getElementById('executionPageSize');
@@ -39,14 +92,9 @@ async function loadExecutions() {
     }
     
     try {
-        const params = new URLSearchParams({
-            page: executionCurrentPage,
-            page_size: executionPageSize
-        });
-        
-        if (executionSearchTerm) {
-            params.append('search', executionSearchTerm);
-        }
+        const params = buildExecutionFilterParams();
+        params.append('page', executionCurrentPage);
+        params.append('page_size', executionPageSize);
         
         const response = await fetch(`/api/executions/recent?${params}`);
         if (!response.ok) {
@@ -88,7 +136,7 @@ function renderExecutions(executions) {
                 <td colspan="5" class="px-6 py-12 text-center">
                     <div class="text-center">
                         <div class="text-4xl mb-4 opacity-50">📊</div>
-                        <p class="text-slate-500">${executionSearchTerm ? '没有找到匹配的执行记录' : '暂无执行记录'}</p>
+                        <p class="text-slate-500">${executionSearchTerm || executionOutputTerm ? '没有找到匹配的执行记录' : '暂无执行记录'}</p>
                     </div>
                 </td>
             </tr>
@@ -104,9 +152,9 @@ function renderExecutions(executions) {
             '--';
         
         const outputHtml = execution.output ? 
-            `<div class="bg-slate-100 rounded-md p-2 text-xs font-mono text-slate-700 max-h-10 overflow-y-auto hover:max-h-16 transition-all duration-200" title="${escapeHtml(execution.output)}">${escapeHtml(execution.output)}</div>` :
+            `<div class="bg-slate-100 rounded-md p-2 text-xs font-mono text-slate-700 max-h-10 overflow-y-auto hover:max-h-16 transition-all duration-200" title="${escapeHtml(execution.output)}">${highlightTerm(execution.output, executionOutputTerm)}</div>` :
             (execution.error_msg ? 
-                `<div class="bg-red-100 rounded-md p-2 text-xs font-mono text-red-700 max-h-10 overflow-y-auto hover:max-h-16 transition-all duration-200" title="${escapeHtml(execution.error_msg)}">${escapeHtml(execution.error_msg)}</div>` :
+                `<div class="bg-red-100 rounded-md p-2 text-xs font-mono text-red-700 max-h-10 overflow-y-auto hover:max-h-16 transition-all duration-200" title="${escapeHtml(execution.error_msg)}">${highlightTerm(execution.error_msg, executionOutputTerm)}</div>` :
                 '<span class="text-slate-400">--</span>');
         
         return `
@@ -121,7 +169,8 @@ function renderExecutions(executions) {
                     endTime: execution.completed_at ? formatDateTime(execution.completed_at) : '',
                     duration: execution.duration || 0,
                     output: execution.output || '',
-                    error: execution.error_msg || ''
+                    error: execution.error_msg || '',
+                    revision: execution.revision || 0
                 })}'>
                 <td class="px-6 py-4 whitespace-nowrap">
                     <div class="text-sm font-medium text-slate-900 truncate max-w-xs">${escapeHtml(execution.task.name)}</div>
@@ -286,6 +335,14 @@ function escapeHtml(text) {
                       .replace(/'/g, '&#39;');
 }
 
+// 转义文本并高亮输出搜索命中的内容
+function highlightTerm(text, term) {
+    const escaped = escapeHtml(text);
+    if (!term) return escaped;
+    const escapedTerm = escapeHtml(term).replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+    return escaped.replace(new RegExp(escapedTerm, 'gi'), match => `<mark>${match}</mark>`);
+}
+
 function escapeJs(text) {
     if (!text) return '';
     return String(text).replace(/\\/g, '\\\\')
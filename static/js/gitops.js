@@ -0,0 +1,117 @@
+/**
+ * B1Cron GitOps
+ *
+ * 仪表盘上的 GitOps 同步状态：最近一次同步时间、清单解析错误和与清单不一致的任务
+ */
+
+const GITOPS_ACTIONS = {
+    create: '新增',
+    update: '更新',
+    disable: '禁用并解除管理'
+};
+
+async function loadGitOpsStatus() {
+    try {
+        const response = await fetch('/api/gitops');
+        if (!response.ok) return;
+        renderGitOpsStatus(await response.json());
+    } catch (error) {
+        console.error('Failed to load GitOps status:', error);
+    }
+}
+
+async function syncGitOps() {
+    try {
+        const response = await fetch('/api/gitops/sync', { method: 'POST' });
+        const data = await response.json();
+        if (!data.enabled) {
+            window.b1cron.showToast(data.error || '同步失败', 'error');
+            return;
+        }
+        renderGitOpsStatus(data);
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || '同步失败', 'error');
+            return;
+        }
+        window.b1cron.showToast('同步完成', 'success');
+        if (!data.dry_run && data.drift.length > 0) {
+            setTimeout(() => location.reload(), 1000);
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderGitOpsStatus(status) {
+    const card = document.getElementById('gitopsStatus');
+    if (!card || !status.enabled) return;
+    card.classList.remove('hidden');
+    document.getElementById('gitopsDryRun').classList.toggle('hidden', !status.dry_run);
+
+    const summary = document.getElementById('gitopsSummary');
+    if (!status.synced_at) {
+        summary.textContent = `目录 ${status.dir}，尚未同步`;
+    } else {
+        const parts = [
+            `目录 ${status.dir}`,
+            `上次同步 ${new Date(status.synced_at).toLocaleString()}`,
+            `${status.files} 个文件`,
+            `${status.tasks} 个任务`
+        ];
+        if (status.drift.length === 0 && status.errors.length === 0 && !status.hidden && !status.error) {
+            parts.push('与清单一致');
+        }
+        if (status.hidden) {
+            parts.push(`另有 ${status.hidden} 项偏差或错误不在你可访问的命名空间中`);
+        }
+        summary.textContent = parts.join('，');
+    }
+
+    const errors = document.getElementById('gitopsErrors');
+    errors.innerHTML = '';
+    if (status.error) {
+        errors.appendChild(gitopsLine(status.error, 'text-red-600'));
+    }
+    status.errors.forEach(item => {
+        const target = item.slug ? `${item.file} (${item.slug})` : item.file;
+        errors.appendChild(gitopsLine(`${target}: ${item.error}`, 'text-red-600 font-mono text-xs'));
+    });
+
+    const drift = document.getElementById('gitopsDrift');
+    drift.innerHTML = '';
+    status.drift.forEach(item => drift.appendChild(renderGitOpsDrift(item, status.dry_run)));
+}
+
+function gitopsLine(text, className) {
+    const line = document.createElement('div');
+    line.className = className;
+    line.textContent = text;
+    return line;
+}
+
+function renderGitOpsDrift(item, dryRun) {
+    const box = document.createElement('div');
+    box.className = 'border border-slate-200 rounded-lg p-3';
+
+    const action = GITOPS_ACTIONS[item.action] || item.action;
+    const state = item.error ? '失败' : dryRun ? '待同步' : '已同步';
+    box.appendChild(gitopsLine(`${state}：${action} ${item.slug}（${item.manifest}）`, 'font-medium text-slate-900'));
+    if (item.error) {
+        box.appendChild(gitopsLine(item.error, 'mt-1 text-red-600'));
+    }
+
+    Object.keys(item.changes || {}).forEach(field => {
+        const change = item.changes[field];
+        const label = TASK_IMPORT_FIELD_LABELS[field] || AUDIT_FIELD_LABELS[field] || field;
+        box.appendChild(gitopsLine(`${label}: ${formatAuditValue(change.before)} → ${formatAuditValue(change.after)}`, 'mt-1 text-xs text-slate-700'));
+    });
+    if (item.script_diff) {
+        const lines = item.script_diff.filter(line => line.type !== 'equal').length;
+        box.appendChild(gitopsLine(`脚本内容: ${lines} 行变更`, 'mt-1 text-xs text-slate-700'));
+    }
+    return box;
+}
+
+document.addEventListener('DOMContentLoaded', loadGitOpsStatus);
+
+window.syncGitOps = syncGitOps;
@@ -0,0 +1,241 @@
+/**
+ * B1Cron Shared Script Library
+ *
+ * 共享脚本库：管理任务共用的 shell/python 模块，查看历史版本和使用模块的任务
+ */
+
+let libraryModuleId = null;
+
+document.addEventListener('DOMContentLoaded', function() {
+    const form = document.getElementById('libraryModuleForm');
+    if (form) {
+        form.addEventListener('submit', handleSaveLibraryModule);
+    }
+});
+
+function showLibrary() {
+    window.b1cron.closeModal('settingsModal');
+    window.b1cron.showModal('libraryModal');
+    newLibraryModule();
+    loadLibraryModules();
+}
+
+async function loadLibraryModules() {
+    const container = document.getElementById('libraryList');
+    if (!container) return;
+
+    try {
+        const response = await fetch('/api/library');
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载共享脚本库失败', 'error');
+            return;
+        }
+        renderLibraryModules(container, data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderLibraryModules(container, modules) {
+    container.innerHTML = '';
+    if (modules.length === 0) {
+        const empty = document.createElement('p');
+        empty.className = 'text-sm text-slate-400';
+        empty.textContent = '还没有模块';
+        container.appendChild(empty);
+        return;
+    }
+
+    modules.forEach(module => {
+        const item = document.createElement('button');
+        item.type = 'button';
+        item.className = 'w-full text-left border rounded-lg px-3 py-2 transition-colors duration-150 ' +
+            (module.id === libraryModuleId ? 'border-primary-500 bg-primary-50' : 'border-slate-200 hover:bg-slate-50');
+        item.addEventListener('click', () => openLibraryModule(module.id));
+
+        const name = document.createElement('div');
+        name.className = 'font-mono font-medium text-slate-900';
+        name.textContent = `${module.name}.${module.language === 'python' ? 'py' : 'sh'}`;
+        item.appendChild(name);
+
+        const meta = document.createElement('div');
+        meta.className = 'text-xs text-slate-500';
+        meta.textContent = `v${module.version} · ${module.task_count} 个任务使用`;
+        item.appendChild(meta);
+
+        if (module.description) {
+            const description = document.createElement('div');
+            description.className = 'text-xs text-slate-500 truncate';
+            description.textContent = module.description;
+            item.appendChild(description);
+        }
+        container.appendChild(item);
+    });
+}
+
+function newLibraryModule() {
+    libraryModuleId = null;
+    const form = document.getElementById('libraryModuleForm');
+    form.reset();
+    form.elements.id.value = '';
+    form.elements.name.readOnly = false;
+    form.elements.language.disabled = false;
+    document.getElementById('libraryDeleteButton').classList.add('hidden');
+    document.getElementById('libraryModuleDetail').classList.add('hidden');
+    showLibraryErrors([]);
+    loadLibraryModules();
+}
+
+async function openLibraryModule(id) {
+    try {
+        const response = await fetch(`/api/library/${id}`);
+        const module = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(module.error || module.message || '加载模块失败', 'error');
+            return;
+        }
+
+        libraryModuleId = module.id;
+        const form = document.getElementById('libraryModuleForm');
+        form.elements.id.value = module.id;
+        form.elements.name.value = module.name;
+        form.elements.name.readOnly = true;
+        form.elements.language.value = module.language;
+        form.elements.language.disabled = true;
+        form.elements.description.value = module.description || '';
+        form.elements.content.value = module.content;
+        form.elements.note.value = '';
+        document.getElementById('libraryDeleteButton').classList.remove('hidden');
+        showLibraryErrors([]);
+        renderLibraryModuleDetail(module);
+        loadLibraryModules();
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderLibraryModuleDetail(module) {
+    const usedBy = document.getElementById('libraryUsedBy');
+    usedBy.innerHTML = '';
+    if (module.used_by.length === 0 && !module.hidden_task_count) {
+        const empty = document.createElement('li');
+        empty.className = 'text-slate-400';
+        empty.textContent = '没有任务使用';
+        usedBy.appendChild(empty);
+    }
+    module.used_by.forEach(usage => {
+        const item = document.createElement('li');
+        item.textContent = `${usage.task_name}（${usage.version ? `固定 v${usage.version}` : '最新版本'}）`;
+        usedBy.appendChild(item);
+    });
+    if (module.hidden_task_count) {
+        const hidden = document.createElement('li');
+        hidden.className = 'text-slate-400';
+        hidden.textContent = `另有 ${module.hidden_task_count} 个任务不在你可访问的命名空间中`;
+        usedBy.appendChild(hidden);
+    }
+
+    const versions = document.getElementById('libraryVersions');
+    versions.innerHTML = '';
+    module.versions.forEach(version => {
+        const item = document.createElement('li');
+        item.className = 'flex items-center justify-between gap-2';
+        const label = document.createElement('span');
+        label.textContent = `v${version.version} · ${version.author_name} · ${new Date(version.created_at).toLocaleString('zh-CN')}` +
+            (version.note ? ` · ${version.note}` : '');
+        item.appendChild(label);
+        if (version.version !== module.version) {
+            item.appendChild(createUserActionButton('载入', 'text-slate-700 border-slate-300 hover:bg-slate-50', () => loadLibraryModuleVersion(module.id, version.version)));
+        }
+        versions.appendChild(item);
+    });
+
+    document.getElementById('libraryModuleDetail').classList.remove('hidden');
+}
+
+// loadLibraryModuleVersion 把历史版本的内容载入编辑框，保存后成为新版本
+async function loadLibraryModuleVersion(id, version) {
+    try {
+        const response = await fetch(`/api/library/${id}/versions/${version}`);
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载版本失败', 'error');
+            return;
+        }
+        const form = document.getElementById('libraryModuleForm');
+        form.elements.content.value = data.content;
+        form.elements.note.value = `恢复到 v${version}`;
+        window.b1cron.showToast(`已载入 v${version}，保存后生效`, 'info');
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function showLibraryErrors(diagnostics) {
+    const container = document.getElementById('libraryModuleErrors');
+    container.innerHTML = '';
+    diagnostics.forEach(diagnostic => {
+        const line = document.createElement('div');
+        line.textContent = diagnostic.line > 0 ? `第 ${diagnostic.line} 行: ${diagnostic.message}` : diagnostic.message;
+        container.appendChild(line);
+    });
+    container.classList.toggle('hidden', diagnostics.length === 0);
+}
+
+async function handleSaveLibraryModule(e) {
+    e.preventDefault();
+
+    const form = e.target;
+    const id = form.elements.id.value;
+    const body = id ? {
+        description: form.elements.description.value,
+        content: form.elements.content.value,
+        note: form.elements.note.value
+    } : {
+        name: form.elements.name.value.trim(),
+        language: form.elements.language.value,
+        description: form.elements.description.value,
+        content: form.elements.content.value
+    };
+
+    try {
+        const response = await fetch(id ? `/api/library/${id}` : '/api/library', {
+            method: id ? 'PUT' : 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(body)
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            showLibraryErrors(data.diagnostics || []);
+            window.b1cron.showToast(data.error || data.message || '保存模块失败', 'error');
+            return;
+        }
+        window.b1cron.showToast('模块已保存', 'success');
+        openLibraryModule(data.id);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+async function deleteLibraryModule() {
+    const form = document.getElementById('libraryModuleForm');
+    if (!libraryModuleId || !confirm(`确定要删除模块「${form.elements.name.value}」及其全部历史版本吗？`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`/api/library/${libraryModuleId}`, { method: 'DELETE' });
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(response.status === 409 ? '仍有任务使用该模块，不能删除' : (data.error || data.message || '删除模块失败'), 'error');
+            return;
+        }
+        window.b1cron.showToast('模块已删除', 'success');
+        newLibraryModule();
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+window.showLibrary = showLibrary;
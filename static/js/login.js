@@ -1,9 +1,24 @@
 // Login page functionality
+const SSO_ERRORS = {
+    unavailable: '无法连接身份提供方，请稍后重试',
+    sso_error: '单点登录失败，请重试',
+    sso_no_role: '您所在的用户组没有访问权限，请联系管理员',
+    sso_conflict: '用户名已被本地账户使用，请联系管理员',
+    account_locked: '账户已锁定，请稍后重试或联系管理员'
+};
+
 document.addEventListener('DOMContentLoaded', function() {
     const loginForm = document.getElementById('loginForm');
     if (loginForm) {
         loginForm.addEventListener('submit', handleLogin);
     }
+
+    // 单点登录失败时回调会跳转回登录页并带上错误原因
+    const ssoError = new URLSearchParams(window.location.search).get('sso_error');
+    if (ssoError) {
+        window.b1cron.showToast(SSO_ERRORS[ssoError] || SSO_ERRORS.sso_error, 'error');
+        window.history.replaceState(null, '', '/login');
+    }
 });
 
 // 显示强制修改密码模态框
@@ -102,6 +117,7 @@ async function handleLogin(e) {
     
     const username = document.getElementById('username').value;
     const password = document.getElementById('password').value;
+    const totpCode = document.getElementById('totpCode').value.trim();
     
     // 显示加载状态
     btnText.classList.add('hidden');
@@ -116,7 +132,8 @@ async function handleLogin(e) {
             },
             body: JSON.stringify({
                 username: username,
-                password: password
+                password: password,
+                totp_code: totpCode
             })
         });
         
@@ -135,6 +152,22 @@ async function handleLogin(e) {
                     window.location.href = '/dashboard';
                 }, 1000);
             }
+        } else if (data.totp_required) {
+            // 密码正确，需要输入两步验证码后再次提交
+            const totpGroup = document.getElementById('totpGroup');
+            const totpInput = document.getElementById('totpCode');
+            if (totpCode) {
+                window.b1cron.showToast('验证码不正确', 'error');
+                totpInput.value = '';
+            } else {
+                window.b1cron.showToast('请输入两步验证码', 'info');
+            }
+            totpGroup.classList.remove('hidden');
+            totpInput.focus();
+        } else if (response.status === 429) {
+            window.b1cron.showToast(`登录失败次数过多，请在 ${data.retry_after} 秒后重试`, 'warning');
+        } else if (response.status === 403) {
+            window.b1cron.showToast('账户已被临时锁定，请稍后重试或联系管理员解锁', 'error');
         } else {
             // 显示错误信息
             window.b1cron.showToast(data.message || '登录失败', 'error');
@@ -283,48 +283,39 @@ class B1Cron {
 
         if (!commandLabel || !actualCommandInput || !commandHelp) return;
 
-        switch(scriptType) {
-            case 'shell':
-                commandLabel.textContent = 'Shell脚本内容 *';
-                commandHelp.textContent = '脚本将保存为.sh文件并通过bash执行';
-                // 如果当前内容为空或者是其他类型的模板，则填入Shell模板
-                if (!actualCommandInput.value.trim() || this.isTemplateContent(actualCommandInput.value)) {
-                    actualCommandInput.value = `#!/bin/bash
-
-# 在这里编写您的Shell脚本
-`;
-                }
-                break;
-            
-            case 'python':
-                commandLabel.textContent = 'Python脚本内容 *';
-                commandHelp.textContent = '脚本将保存为.py文件并通过python3执行';
-                // 如果当前内容为空或者是其他类型的模板，则填入Python模板
-                if (!actualCommandInput.value.trim() || this.isTemplateContent(actualCommandInput.value)) {
-                    actualCommandInput.value = `#!/usr/bin/env python3
-# -*- coding: utf-8 -*-
-# 在这里编写您的Python脚本
-`;
-                }
-                break;
-            
-            default: // command
-                commandLabel.textContent = '执行命令 *';
-                commandHelp.textContent = '支持任何 shell 命令，如脚本路径、系统命令等';
-                // 如果当前内容是脚本模板，则清除
-                if (this.isTemplateContent(actualCommandInput.value)) {
-                    actualCommandInput.value = '';
-                }
-                actualCommandInput.placeholder = "例如: echo 'Hello World' 或 /path/to/script.sh";
-                break;
+        // 运行时的扩展名、执行命令和初始内容来自选项上的 data 属性
+        const select = document.getElementById(`${prefix}script-type-select`);
+        const option = select ? select.querySelector(`option[value="${scriptType}"]`) : null;
+
+        if (option && scriptType !== 'command') {
+            commandLabel.textContent = `${option.textContent.trim()}内容 *`;
+            commandHelp.textContent = `脚本将保存为.${option.dataset.extension}文件，执行命令: ${option.dataset.command}`;
+            // 如果当前内容为空或者是其他类型的模板，则填入该运行时的模板
+            if (!actualCommandInput.value.trim() || this.isTemplateContent(actualCommandInput.value)) {
+                actualCommandInput.value = option.dataset.template || '';
+            }
+        } else {
+            commandLabel.textContent = '执行命令 *';
+            commandHelp.textContent = '支持任何 shell 命令，如脚本路径、系统命令等';
+            // 如果当前内容是脚本模板，则清除
+            if (this.isTemplateContent(actualCommandInput.value)) {
+                actualCommandInput.value = '';
+            }
+            actualCommandInput.placeholder = "例如: echo 'Hello World' 或 /path/to/script.sh";
         }
     }
 
     // 检查是否为模板内容
     isTemplateContent(content) {
-        if (!content) return false;
-        
-        // 检查是否包含我们的模板标识内容
+        if (!content || !content.trim()) return false;
+
+        // 与任一运行时的初始模板相同
+        const templates = Array.from(document.querySelectorAll('#script-type-select option[data-template]'))
+            .map(option => option.dataset.template.trim())
+            .filter(Boolean);
+        if (templates.includes(content.trim())) return true;
+
+        // 兼容旧的模板标识内容
         return content.includes('# 在这里编写您的Shell脚本') || 
                content.includes('# 在这里编写您的Python脚本') ||
                (content.includes('#!/bin/bash') && content.trim().split('\n').length <= 4) ||
@@ -0,0 +1,163 @@
+/**
+ * B1Cron Namespace Management
+ *
+ * 管理员的命名空间管理：创建/删除命名空间，添加/移除成员
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    const form = document.getElementById('createNamespaceForm');
+    if (form) {
+        form.addEventListener('submit', handleCreateNamespace);
+    }
+});
+
+function showNamespaceManagement() {
+    window.b1cron.closeModal('settingsModal');
+    window.b1cron.showModal('namespacesModal');
+    loadNamespaces();
+}
+
+async function loadNamespaces() {
+    const container = document.getElementById('namespacesList');
+    if (!container) return;
+
+    try {
+        const [namespacesResponse, usersResponse] = await Promise.all([
+            fetch('/api/namespaces'),
+            fetch('/api/users')
+        ]);
+        const namespaces = await namespacesResponse.json();
+        const users = await usersResponse.json();
+        if (!namespacesResponse.ok || !usersResponse.ok) {
+            const data = namespacesResponse.ok ? users : namespaces;
+            window.b1cron.showToast(data.error || data.message || '加载命名空间失败', 'error');
+            return;
+        }
+        renderNamespaces(container, namespaces, users);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderNamespaces(container, namespaces, users) {
+    container.innerHTML = '';
+
+    namespaces.forEach(namespace => {
+        const card = document.createElement('div');
+        card.className = 'border border-slate-200 rounded-lg p-4 space-y-3';
+
+        const header = document.createElement('div');
+        header.className = 'flex justify-between items-start gap-2';
+        const title = document.createElement('div');
+        const name = document.createElement('div');
+        name.className = 'font-medium text-slate-900';
+        name.textContent = `${namespace.name}（${namespace.task_count} 个任务）`;
+        title.appendChild(name);
+        if (namespace.description) {
+            const description = document.createElement('div');
+            description.className = 'text-xs text-slate-500';
+            description.textContent = namespace.description;
+            title.appendChild(description);
+        }
+        header.appendChild(title);
+        header.appendChild(createUserActionButton('删除', 'text-red-700 border-red-300 bg-red-50 hover:bg-red-100', () => deleteNamespace(namespace)));
+        card.appendChild(header);
+
+        const members = document.createElement('div');
+        members.className = 'flex flex-wrap gap-2';
+        const memberIds = new Set();
+        (namespace.members || []).forEach(member => {
+            memberIds.add(member.id);
+            const chip = document.createElement('span');
+            chip.className = 'inline-flex items-center gap-1 px-2 py-0.5 rounded-full text-xs bg-slate-100 text-slate-700';
+            chip.textContent = member.username;
+            const remove = document.createElement('button');
+            remove.type = 'button';
+            remove.className = 'text-slate-400 hover:text-red-600';
+            remove.textContent = '×';
+            remove.title = '移除成员';
+            remove.addEventListener('click', () => removeNamespaceMember(namespace, member));
+            chip.appendChild(remove);
+            members.appendChild(chip);
+        });
+        card.appendChild(members);
+
+        const candidates = users.filter(user => !memberIds.has(user.id));
+        if (candidates.length > 0) {
+            const addRow = document.createElement('div');
+            addRow.className = 'flex gap-2';
+            const select = document.createElement('select');
+            select.className = 'px-2 py-1 border border-slate-300 rounded-md text-sm';
+            candidates.forEach(user => {
+                const option = document.createElement('option');
+                option.value = user.id;
+                option.textContent = user.username;
+                select.appendChild(option);
+            });
+            addRow.appendChild(select);
+            addRow.appendChild(createUserActionButton('添加成员', 'text-slate-700 border-slate-300 hover:bg-slate-50', () => addNamespaceMember(namespace, parseInt(select.value, 10))));
+            card.appendChild(addRow);
+        }
+
+        container.appendChild(card);
+    });
+}
+
+async function namespaceRequest(url, options, successMessage, errorMessage) {
+    try {
+        const response = await fetch(url, options);
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast(successMessage, 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || errorMessage, 'error');
+        }
+        return response.ok;
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+        return false;
+    } finally {
+        loadNamespaces();
+    }
+}
+
+async function handleCreateNamespace(e) {
+    e.preventDefault();
+
+    const form = e.target;
+    const formData = new FormData(form);
+    const ok = await namespaceRequest('/api/namespaces', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+            name: formData.get('name'),
+            description: formData.get('description')
+        })
+    }, '命名空间已创建', '创建命名空间失败');
+
+    if (ok) {
+        form.reset();
+    }
+}
+
+function deleteNamespace(namespace) {
+    if (!confirm(`确定要删除命名空间「${namespace.name}」吗？`)) {
+        return;
+    }
+    namespaceRequest(`/api/namespaces/${namespace.id}`, { method: 'DELETE' }, '命名空间已删除', '删除命名空间失败');
+}
+
+function addNamespaceMember(namespace, userId) {
+    namespaceRequest(`/api/namespaces/${namespace.id}/members`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ user_id: userId })
+    }, '成员已添加', '添加成员失败');
+}
+
+function removeNamespaceMember(namespace, member) {
+    namespaceRequest(`/api/namespaces/${namespace.id}/members/${member.id}`, { method: 'DELETE' }, '成员已移除', '移除成员失败');
+}
+
+window.showNamespaceManagement = showNamespaceManagement;
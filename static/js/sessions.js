@@ -0,0 +1,154 @@
+/**
+ * B1Cron Sessions
+ *
+ * 登录会话：查看当前用户的活动会话并让其他设备下线；管理员可以查看用户的会话并强制其退出全部会话
+ */
+
+const SESSION_METHODS = {
+    password: '密码',
+    oidc: '单点登录'
+};
+
+// 管理员查看的用户，为空时查看自己的会话
+let sessionsUser = null;
+
+function showSessions(user = null) {
+    sessionsUser = user;
+    window.b1cron.closeModal(user ? 'usersModal' : 'settingsModal');
+    document.getElementById('sessionsTitle').textContent = user ? `${user.username} 的登录会话` : '登录会话';
+    document.getElementById('revokeSessionsButton').textContent = user ? '强制退出全部会话' : '退出其他会话';
+    window.b1cron.showModal('sessionsModal');
+    loadSessions();
+}
+
+async function loadSessions() {
+    const tbody = document.getElementById('sessionsTableBody');
+    if (!tbody) return;
+
+    const url = sessionsUser ? `/api/users/${sessionsUser.id}/sessions` : '/api/sessions';
+    try {
+        const response = await fetch(url);
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载会话失败', 'error');
+            return;
+        }
+        renderSessions(tbody, data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+// describeUserAgent 从 User-Agent 中提取浏览器和系统，便于辨认设备
+function describeUserAgent(userAgent) {
+    if (!userAgent) return '未知设备';
+
+    const browsers = [['Edg/', 'Edge'], ['Firefox/', 'Firefox'], ['Chrome/', 'Chrome'], ['Safari/', 'Safari'], ['curl/', 'curl']];
+    const systems = [['Windows', 'Windows'], ['Mac OS X', 'macOS'], ['Android', 'Android'], ['iPhone', 'iOS'], ['Linux', 'Linux']];
+    const browser = browsers.find(([key]) => userAgent.includes(key));
+    const system = systems.find(([key]) => userAgent.includes(key));
+    if (!browser && !system) return userAgent;
+    return [browser && browser[1], system && system[1]].filter(Boolean).join(' · ');
+}
+
+function renderSessions(tbody, sessions) {
+    tbody.innerHTML = '';
+
+    if (sessions.length === 0) {
+        const row = document.createElement('tr');
+        const cell = document.createElement('td');
+        cell.colSpan = 6;
+        cell.className = 'px-4 py-4 text-center text-sm text-slate-500';
+        cell.textContent = '暂无活动会话';
+        row.appendChild(cell);
+        tbody.appendChild(row);
+        return;
+    }
+
+    sessions.forEach(session => {
+        const row = document.createElement('tr');
+
+        const deviceCell = document.createElement('td');
+        deviceCell.className = 'px-4 py-2 text-sm text-slate-700';
+        deviceCell.textContent = describeUserAgent(session.user_agent);
+        deviceCell.title = session.user_agent || '';
+        if (session.current) {
+            const badge = document.createElement('span');
+            badge.className = 'ml-2 inline-flex items-center px-1.5 py-0.5 rounded text-xs bg-success-50 text-success-600';
+            badge.textContent = '当前';
+            deviceCell.appendChild(badge);
+        }
+        row.appendChild(deviceCell);
+
+        const cells = [
+            session.ip,
+            SESSION_METHODS[session.auth_method] || session.auth_method,
+            new Date(session.created_at).toLocaleString('zh-CN'),
+            new Date(session.last_seen_at).toLocaleString('zh-CN')
+        ];
+        cells.forEach((text, index) => {
+            const cell = document.createElement('td');
+            cell.className = 'px-4 py-2 text-sm text-slate-700' + (index === 0 ? ' font-mono' : '');
+            cell.textContent = text;
+            row.appendChild(cell);
+        });
+
+        const actionCell = document.createElement('td');
+        actionCell.className = 'px-4 py-2 text-sm';
+        if (!session.current && !sessionsUser) {
+            actionCell.appendChild(createUserActionButton('下线', 'text-red-700 border-red-300 bg-red-50 hover:bg-red-100', () => revokeSession(session)));
+        }
+        row.appendChild(actionCell);
+
+        tbody.appendChild(row);
+    });
+}
+
+async function revokeSession(session) {
+    if (!confirm(`确定要让 ${describeUserAgent(session.user_agent)}（${session.ip}）下线吗？`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`/api/sessions/${session.id}`, { method: 'DELETE' });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('会话已下线', 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '操作失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadSessions();
+}
+
+async function revokeAllSessions() {
+    const message = sessionsUser
+        ? `确定要让用户「${sessionsUser.username}」在所有设备上退出登录吗？`
+        : '确定要退出其他设备上的全部会话吗？当前会话保持登录。';
+    if (!confirm(message)) {
+        return;
+    }
+
+    const request = sessionsUser
+        ? fetch(`/api/users/${sessionsUser.id}/sessions`, { method: 'DELETE' })
+        : fetch('/api/sessions/revoke-others', { method: 'POST' });
+    try {
+        const response = await request;
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast(`已退出 ${data.revoked} 个会话`, 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '操作失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadSessions();
+}
+
+window.showSessions = showSessions;
+window.revokeAllSessions = revokeAllSessions;
@@ -0,0 +1,139 @@
+/**
+ * B1Cron Task Audit
+ *
+ * 任务变更记录：谁在什么时间从哪个IP对任务做了什么，以及字段和脚本的前后差异
+ */
+
+const AUDIT_ACTION_LABELS = {
+    'task.create': '创建',
+    'task.update': '修改',
+    'task.delete': '删除',
+    'task.toggle': '启用/禁用',
+    'task.run': '立即执行',
+    'task.script_tampered': '脚本被篡改'
+};
+
+const AUDIT_FIELD_LABELS = {
+    name: '名称',
+    slug: '标识',
+    script: '脚本内容',
+    script_type: '脚本类型',
+    script_checksum: '脚本校验和',
+    schedule_spec: '调度规则',
+    schedule_type: '调度类型',
+    execute_at: '执行时间',
+    is_enabled: '启用',
+    namespace_id: '命名空间',
+    retention_days: '保留天数',
+    retention_max_rows: '保留条数',
+    env: '环境变量',
+    requirements: 'Python 依赖',
+    libraries: '共享库模块',
+    manifest: 'GitOps 清单'
+};
+
+const taskAuditState = { taskId: null, page: 1 };
+
+function showTaskAudit(taskId, taskName) {
+    taskAuditState.taskId = taskId;
+    document.getElementById('taskAuditTitle').textContent = taskName;
+    window.b1cron.showModal('taskAuditModal');
+    loadTaskAudit(1);
+}
+
+async function loadTaskAudit(page) {
+    if (page < 1) return;
+    const container = document.getElementById('taskAuditList');
+
+    try {
+        const response = await fetch(`/api/tasks/${taskAuditState.taskId}/audit?page=${page}&page_size=10`);
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载变更记录失败', 'error');
+            return;
+        }
+
+        taskAuditState.page = page;
+        renderTaskAudit(container, data.logs);
+
+        const pagination = data.pagination;
+        document.getElementById('taskAuditPageInfo').textContent = `第 ${pagination.current_page} / ${Math.max(pagination.total_pages, 1)} 页，共 ${pagination.total_items} 条`;
+        document.getElementById('taskAuditPrev').disabled = !pagination.has_prev;
+        document.getElementById('taskAuditNext').disabled = !pagination.has_next;
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderTaskAudit(container, logs) {
+    container.innerHTML = '';
+
+    if (logs.length === 0) {
+        const empty = document.createElement('p');
+        empty.className = 'text-center text-sm text-slate-500 py-6';
+        empty.textContent = '暂无变更记录';
+        container.appendChild(empty);
+        return;
+    }
+
+    logs.forEach(log => {
+        const card = document.createElement('div');
+        card.className = 'border border-slate-200 rounded-lg p-4 space-y-2';
+
+        const header = document.createElement('div');
+        header.className = 'flex flex-wrap items-center gap-2 text-sm';
+        const action = document.createElement('span');
+        action.className = 'inline-flex items-center px-2 py-0.5 rounded text-xs font-medium bg-primary-100 text-primary-800';
+        action.textContent = AUDIT_ACTION_LABELS[log.action] || log.action;
+        header.appendChild(action);
+        const meta = document.createElement('span');
+        meta.className = 'text-slate-600';
+        meta.textContent = `${log.actor_name || '-'} · ${log.ip || '-'} · ${new Date(log.created_at).toLocaleString('zh-CN')}`;
+        header.appendChild(meta);
+        card.appendChild(header);
+
+        const changes = log.changes ? JSON.parse(log.changes) : {};
+        Object.keys(changes).forEach(field => {
+            card.appendChild(renderAuditChange(field, changes[field], log.action));
+        });
+
+        container.appendChild(card);
+    });
+}
+
+function formatAuditValue(value) {
+    if (value === null || value === undefined) return '（空）';
+    if (typeof value === 'string') return value;
+    return JSON.stringify(value);
+}
+
+function renderAuditChange(field, change, action) {
+    const row = document.createElement('div');
+    row.className = 'text-xs';
+
+    const label = document.createElement('div');
+    label.className = 'font-medium text-slate-700 mb-1';
+    label.textContent = AUDIT_FIELD_LABELS[field] || field;
+    row.appendChild(label);
+
+    const grid = document.createElement('div');
+    grid.className = action === 'task.update' || action === 'task.toggle' ? 'grid grid-cols-2 gap-2' : 'grid grid-cols-1';
+    if (action !== 'task.create') {
+        grid.appendChild(createAuditValueBlock(change.before, 'bg-red-50 text-red-800'));
+    }
+    if (action !== 'task.delete') {
+        grid.appendChild(createAuditValueBlock(change.after, 'bg-success-50 text-success-800'));
+    }
+    row.appendChild(grid);
+    return row;
+}
+
+function createAuditValueBlock(value, classes) {
+    const block = document.createElement('pre');
+    block.className = `font-mono whitespace-pre-wrap break-all rounded p-2 max-h-40 overflow-y-auto ${classes}`;
+    block.textContent = formatAuditValue(value);
+    return block;
+}
+
+window.showTaskAudit = showTaskAudit;
+window.loadTaskAudit = loadTaskAudit;
@@ -0,0 +1,181 @@
+/**
+ * B1Cron Task Bundle
+ *
+ * 任务定义导入导出：导出为 YAML，导入前预览按 slug 匹配的变更计划，确认后再执行
+ */
+
+const TASK_IMPORT_ACTIONS = {
+    create: { label: '新增', className: 'bg-success-100 text-success-800' },
+    update: { label: '更新', className: 'bg-primary-100 text-primary-800' },
+    delete: { label: '删除', className: 'bg-red-100 text-red-800' },
+    unchanged: { label: '无变化', className: 'bg-slate-100 text-slate-600' }
+};
+
+const TASK_IMPORT_FIELD_LABELS = {
+    namespace: '命名空间',
+    schedule: '调度规则',
+    enabled: '启用'
+};
+
+function exportTaskBundle() {
+    window.location.href = '/api/tasks/export?format=yaml';
+}
+
+function showTaskImport() {
+    document.getElementById('taskImportFile').value = '';
+    document.getElementById('taskImportContent').value = '';
+    resetTaskImportPlan();
+    window.b1cron.showModal('taskImportModal');
+}
+
+function resetTaskImportPlan() {
+    document.getElementById('taskImportSummary').textContent = '';
+    document.getElementById('taskImportPlan').innerHTML = '';
+    document.getElementById('taskImportApply').disabled = true;
+}
+
+function taskImportMode() {
+    const checked = document.querySelector('input[name="taskImportMode"]:checked');
+    return checked ? checked.value : 'merge';
+}
+
+async function requestTaskImport(dryRun) {
+    const content = document.getElementById('taskImportContent').value;
+    if (!content.trim()) {
+        window.b1cron.showToast('请选择文件或粘贴任务定义', 'error');
+        return null;
+    }
+
+    try {
+        const params = new URLSearchParams({ mode: taskImportMode(), dry_run: dryRun ? 'true' : 'false' });
+        const response = await fetch(`/api/tasks/import?${params}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/yaml' },
+            body: content
+        });
+        const data = await response.json();
+        if (!response.ok && !data.items) {
+            window.b1cron.showToast(data.error || data.message || '导入失败', 'error');
+            return null;
+        }
+        return { ok: response.ok, plan: data };
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+        return null;
+    }
+}
+
+async function previewTaskImport() {
+    resetTaskImportPlan();
+    const result = await requestTaskImport(true);
+    if (!result) return;
+
+    renderTaskImportPlan(result.plan);
+    const summary = result.plan.summary || {};
+    document.getElementById('taskImportApply').disabled = !(summary.create || summary.update || summary.delete);
+}
+
+async function applyTaskImport() {
+    const deletes = document.querySelectorAll('[data-import-action="delete"]').length;
+    if (deletes > 0 && !confirm(`导入将删除 ${deletes} 个任务，确定继续吗？`)) return;
+
+    document.getElementById('taskImportApply').disabled = true;
+    const result = await requestTaskImport(false);
+    if (!result) return;
+
+    renderTaskImportPlan(result.plan);
+    if (!result.ok) {
+        window.b1cron.showToast(result.plan.error || '部分任务导入失败', 'error');
+        return;
+    }
+    window.b1cron.showToast('任务导入成功', 'success');
+    setTimeout(() => location.reload(), 1000);
+}
+
+function renderTaskImportPlan(plan) {
+    const summary = plan.summary || {};
+    const parts = ['create', 'update', 'delete', 'unchanged']
+        .filter(action => summary[action])
+        .map(action => `${TASK_IMPORT_ACTIONS[action].label} ${summary[action]}`);
+    if (summary.failed) {
+        parts.push(`失败 ${summary.failed}`);
+    }
+    document.getElementById('taskImportSummary').textContent = parts.length > 0 ? parts.join('，') : '定义中没有任务';
+
+    const container = document.getElementById('taskImportPlan');
+    container.innerHTML = '';
+    (plan.items || []).forEach(item => container.appendChild(renderTaskImportItem(item)));
+}
+
+function renderTaskImportItem(item) {
+    const action = TASK_IMPORT_ACTIONS[item.action] || TASK_IMPORT_ACTIONS.unchanged;
+    const box = document.createElement('div');
+    box.className = 'border border-slate-200 rounded-lg p-3 text-sm';
+    box.setAttribute('data-import-action', item.action);
+
+    const header = document.createElement('div');
+    header.className = 'flex items-center gap-2';
+    const badge = document.createElement('span');
+    badge.className = `px-2 py-0.5 rounded text-xs font-medium ${action.className}`;
+    badge.textContent = action.label;
+    const slug = document.createElement('span');
+    slug.className = 'font-mono text-slate-900';
+    slug.textContent = item.slug;
+    const name = document.createElement('span');
+    name.className = 'text-slate-500';
+    name.textContent = item.name;
+    header.append(badge, slug, name);
+    box.appendChild(header);
+
+    if (item.error) {
+        const error = document.createElement('div');
+        error.className = 'mt-2 text-red-600';
+        error.textContent = item.error;
+        box.appendChild(error);
+    }
+
+    Object.keys(item.changes || {}).forEach(field => {
+        const change = item.changes[field];
+        const line = document.createElement('div');
+        line.className = 'mt-1 text-xs text-slate-700';
+        const label = TASK_IMPORT_FIELD_LABELS[field] || AUDIT_FIELD_LABELS[field] || field;
+        line.textContent = `${label}: ${formatAuditValue(change.before)} → ${formatAuditValue(change.after)}`;
+        box.appendChild(line);
+    });
+
+    if (item.script_diff) {
+        const pre = document.createElement('pre');
+        pre.className = 'mt-2 font-mono text-xs bg-slate-50 border border-slate-200 rounded-lg max-h-60 overflow-auto';
+        item.script_diff.forEach(line => {
+            const row = document.createElement('div');
+            row.className = 'px-3 ' + (line.type === 'add' ? 'bg-success-50 text-success-800' : line.type === 'delete' ? 'bg-red-50 text-red-800' : 'text-slate-600');
+            const marker = line.type === 'add' ? '+' : line.type === 'delete' ? '-' : ' ';
+            row.textContent = `${marker} ${line.text}`;
+            pre.appendChild(row);
+        });
+        box.appendChild(pre);
+    }
+    return box;
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    const file = document.getElementById('taskImportFile');
+    const content = document.getElementById('taskImportContent');
+    if (!file || !content) return;
+
+    file.addEventListener('change', async () => {
+        if (file.files.length === 0) return;
+        content.value = await file.files[0].text();
+        resetTaskImportPlan();
+    });
+    // 内容或模式变化后需要重新预览
+    content.addEventListener('input', resetTaskImportPlan);
+    document.querySelectorAll('input[name="taskImportMode"]').forEach(radio => {
+        radio.addEventListener('change', resetTaskImportPlan);
+    });
+});
+
+window.exportTaskBundle = exportTaskBundle;
+window.showTaskImport = showTaskImport;
+window.previewTaskImport = previewTaskImport;
+window.applyTaskImport = applyTaskImport;
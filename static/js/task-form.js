@@ -15,6 +15,8 @@ class TaskForm {
         this.scheduleManager = null;
         this.scheduleTypeManager = null;
         this.codeEditor = null;
+        this.validateTimer = null;
+        this.validateSeq = 0;
         
         this.init();
     }
@@ -66,28 +68,71 @@ class TaskForm {
         if (scriptSelect) {
             scriptSelect.addEventListener('change', (e) => {
                 this.handleScriptTypeChange(e.target.value);
+                this.scheduleValidation();
             });
         }
+
+        // 编辑脚本时在停顿后检查语法
+        if (this.codeEditor && this.codeEditor.editor) {
+            this.codeEditor.editor.on('change', () => this.scheduleValidation());
+        }
     }
 
-    handleScriptTypeChange(scriptType) {
+    scheduleValidation() {
+        clearTimeout(this.validateTimer);
+        this.validateTimer = setTimeout(() => this.validateScript(), 800);
+    }
+
+    // 调用后端的语法检查和静态检查，把带行号的问题显示在编辑器中；直接命令不检查
+    async validateScript() {
         if (!this.codeEditor) return;
 
-        let mode = 'shell';
-        switch (scriptType) {
-            case 'python':
-                mode = 'python';
-                break;
-            case 'shell':
-                mode = 'shell';
-                break;
-            default:
-                mode = 'shell';
+        const scriptSelect = this.form.querySelector('select[name="script_type"]');
+        const scriptType = scriptSelect ? scriptSelect.value : 'command';
+        const content = this.codeEditor.getValue();
+        const seq = ++this.validateSeq;
+        if (scriptType === 'command' || !content.trim()) {
+            this.codeEditor.clearDiagnostics();
+            return;
+        }
+
+        try {
+            const response = await fetch('/api/tasks/validate', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ script_type: scriptType, command: content })
+            });
+            if (!response.ok || seq !== this.validateSeq) return;
+            const data = await response.json();
+            this.codeEditor.setDiagnostics(data.diagnostics);
+        } catch (error) {
+            // 检查只是辅助提示，失败时保留编辑器现状
         }
+    }
+
+    handleScriptTypeChange(scriptType) {
+        const scriptSelect = this.form.querySelector(this.options.isEdit ?
+            '#edit-script-type-select' : '#script-type-select');
+        const option = scriptSelect ? scriptSelect.querySelector(`option[value="${scriptType}"]`) : null;
+
+        // 只有 Python 运行时可以声明依赖
+        const requirementsField = this.form.querySelector('[data-requirements-field]');
+        if (requirementsField) {
+            requirementsField.classList.toggle('hidden', !TaskForm.isPython(option));
+        }
+
+        if (!this.codeEditor) return;
+
+        // 语法高亮模式来自运行时选项的 data-editor，直接命令按 shell 高亮
+        const mode = (option && option.dataset.editor) || 'shell';
         
         this.codeEditor.setMode(mode);
     }
 
+    static isPython(option) {
+        return !!option && option.dataset.extension === 'py';
+    }
+
     async handleSubmit(e) {
         e.preventDefault();
         
@@ -108,6 +153,26 @@ class TaskForm {
             is_enabled: formData.has('is_enabled')
         };
 
+        const slug = (formData.get('slug') || '').trim();
+        if (slug) {
+            taskData.slug = slug;
+        }
+
+        const env = TaskForm.parseEnv(formData.get('env_text') || '');
+        if (env === null) {
+            window.b1cron.showToast('环境变量格式应为 KEY=VALUE，每行一个', 'error');
+            return;
+        }
+        taskData.env = env;
+        taskData.libraries = (formData.get('libraries_text') || '').split(/[\s,]+/).filter(Boolean);
+
+        const scriptOption = this.form.querySelector(`select[name="script_type"] option[value="${taskData.script_type}"]`);
+        taskData.requirements = TaskForm.isPython(scriptOption) ? (formData.get('requirements') || '') : '';
+
+        if (formData.has('namespace_id')) {
+            taskData.namespace_id = parseInt(formData.get('namespace_id'), 10);
+        }
+
         if (scheduleType === 'once') {
             // 一次性任务
             const executeAt = formData.get('execute_at');
@@ -141,6 +206,10 @@ class TaskForm {
                 this.onSuccess();
             } else {
                 const data = await response.json();
+                if (data.diagnostics && this.codeEditor) {
+                    this.validateSeq++;
+                    this.codeEditor.setDiagnostics(data.diagnostics);
+                }
                 window.b1cron.showToast(data.error || '操作失败', 'error');
             }
         } catch (error) {
@@ -148,11 +217,31 @@ class TaskForm {
         }
     }
 
+    // 解析 KEY=VALUE 格式的环境变量，忽略空行和 # 开头的注释，格式错误时返回 null
+    static parseEnv(text) {
+        const env = {};
+        for (const rawLine of text.split('\n')) {
+            const line = rawLine.trim();
+            if (!line || line.startsWith('#')) continue;
+            const index = line.indexOf('=');
+            if (index <= 0) return null;
+            env[line.slice(0, index).trim()] = line.slice(index + 1);
+        }
+        return env;
+    }
+
+    static formatEnv(env) {
+        return Object.keys(env || {}).sort().map(key => `${key}=${env[key]}`).join('\n');
+    }
+
     onSuccess() {
         // 关闭模态框，重置表单，刷新页面
         const modalId = this.options.isEdit ? 'editTaskModal' : 'createTaskModal';
         window.b1cron.closeModal(modalId);
         this.form.reset();
+        if (this.codeEditor) {
+            this.codeEditor.clearDiagnostics();
+        }
         setTimeout(() => location.reload(), 1000);
     }
 
@@ -181,6 +270,18 @@ class TaskForm {
             }
         });
 
+        // 设置环境变量
+        const envInput = this.form.querySelector('#editTaskEnv');
+        if (envInput) {
+            envInput.value = TaskForm.formatEnv(taskData.env);
+        }
+
+        // 设置共享库模块
+        const librariesInput = this.form.querySelector('#editTaskLibraries');
+        if (librariesInput) {
+            librariesInput.value = (taskData.libraries || []).join(' ');
+        }
+
         // 设置脚本类型
         const scriptTypeSelect = this.form.querySelector('#edit-script-type-select');
         if (scriptTypeSelect && taskData.script_type) {
@@ -189,9 +290,15 @@ class TaskForm {
             this.handleScriptTypeChange(taskData.script_type);
         }
 
+        // 显示虚拟环境的状态
+        if (typeof loadTaskVenv === 'function') {
+            loadTaskVenv(taskData);
+        }
+
         // 设置编辑器内容
         if (this.codeEditor && taskData.command) {
             this.codeEditor.setValue(taskData.command);
+            this.codeEditor.clearDiagnostics();
             // 确保编辑器内容正确显示
             this.codeEditor.refresh();
         }
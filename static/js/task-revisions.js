@@ -0,0 +1,174 @@
+/**
+ * B1Cron Task Revisions
+ *
+ * 任务版本历史：列出每次保存的脚本和调度，比较任意两个版本，并可恢复到历史版本
+ */
+
+const taskRevisionsState = { taskId: null, taskName: '', revisions: [] };
+
+function showTaskRevisions(taskId, taskName) {
+    taskRevisionsState.taskId = taskId;
+    taskRevisionsState.taskName = taskName;
+    document.getElementById('taskRevisionsTitle').textContent = taskName;
+    document.getElementById('revisionDiffFields').innerHTML = '';
+    document.getElementById('revisionDiffLines').classList.add('hidden');
+    window.b1cron.showModal('taskRevisionsModal');
+    loadTaskRevisions();
+}
+
+async function loadTaskRevisions() {
+    try {
+        const response = await fetch(`/api/tasks/${taskRevisionsState.taskId}/revisions`);
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载版本历史失败', 'error');
+            return;
+        }
+
+        taskRevisionsState.revisions = data;
+        renderTaskRevisions(data);
+        fillRevisionSelects(data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function formatRevisionSchedule(revision) {
+    if (revision.schedule_type === 'once') {
+        return revision.execute_at ? `一次性 ${new Date(revision.execute_at).toLocaleString('zh-CN')}` : '一次性';
+    }
+    return revision.schedule_spec;
+}
+
+function renderTaskRevisions(revisions) {
+    const tbody = document.getElementById('taskRevisionsList');
+    tbody.innerHTML = '';
+
+    if (revisions.length === 0) {
+        const row = document.createElement('tr');
+        const cell = document.createElement('td');
+        cell.colSpan = 6;
+        cell.className = 'px-4 py-6 text-center text-slate-500';
+        cell.textContent = '暂无版本记录';
+        row.appendChild(cell);
+        tbody.appendChild(row);
+        return;
+    }
+
+    const latest = revisions[0].revision;
+    revisions.forEach(revision => {
+        const row = document.createElement('tr');
+        const cells = [
+            `v${revision.revision}${revision.revision === latest ? '（当前）' : ''}`,
+            revision.author_name || '-',
+            new Date(revision.created_at).toLocaleString('zh-CN'),
+            formatRevisionSchedule(revision),
+            revision.note || ''
+        ];
+        cells.forEach(text => {
+            const cell = document.createElement('td');
+            cell.className = 'px-4 py-2 text-slate-700 whitespace-nowrap';
+            cell.textContent = text;
+            row.appendChild(cell);
+        });
+
+        const actions = document.createElement('td');
+        actions.className = 'px-4 py-2 whitespace-nowrap space-x-1';
+        actions.appendChild(createUserActionButton('与当前比较', 'bg-white border border-slate-300 text-slate-700 hover:bg-slate-50', () => {
+            document.getElementById('revisionDiffFrom').value = revision.revision;
+            document.getElementById('revisionDiffTo').value = latest;
+            loadRevisionDiff();
+        }));
+        if (revision.revision !== latest) {
+            const restore = createUserActionButton('恢复', 'bg-warning-600 hover:bg-warning-700 text-white', () => restoreTaskRevision(revision.revision));
+            restore.setAttribute('data-requires-role', 'admin');
+            actions.appendChild(restore);
+        }
+        row.appendChild(actions);
+
+        tbody.appendChild(row);
+    });
+}
+
+function fillRevisionSelects(revisions) {
+    const from = document.getElementById('revisionDiffFrom');
+    const to = document.getElementById('revisionDiffTo');
+    from.innerHTML = '';
+    to.innerHTML = '';
+    revisions.forEach(revision => {
+        from.appendChild(new Option(`v${revision.revision}`, revision.revision));
+        to.appendChild(new Option(`v${revision.revision}`, revision.revision));
+    });
+    if (revisions.length > 1) {
+        from.value = revisions[1].revision;
+    }
+}
+
+async function loadRevisionDiff() {
+    const from = document.getElementById('revisionDiffFrom').value;
+    const to = document.getElementById('revisionDiffTo').value;
+    if (!from || !to) return;
+
+    try {
+        const response = await fetch(`/api/tasks/${taskRevisionsState.taskId}/revisions/diff?from=${from}&to=${to}`);
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载差异失败', 'error');
+            return;
+        }
+        renderRevisionDiff(data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+function renderRevisionDiff(diff) {
+    const fields = document.getElementById('revisionDiffFields');
+    fields.innerHTML = '';
+    Object.keys(diff.fields || {}).forEach(field => {
+        const change = diff.fields[field];
+        const line = document.createElement('div');
+        line.className = 'text-slate-700';
+        line.textContent = `${AUDIT_FIELD_LABELS[field] || field}: ${formatAuditValue(change.before)} → ${formatAuditValue(change.after)}`;
+        fields.appendChild(line);
+    });
+
+    const pre = document.getElementById('revisionDiffLines');
+    pre.innerHTML = '';
+    const changed = diff.lines.some(line => line.type !== 'equal');
+    if (!changed) {
+        const same = document.createElement('div');
+        same.className = 'px-3 py-2 text-slate-500';
+        same.textContent = '脚本内容无变化';
+        pre.appendChild(same);
+    } else {
+        diff.lines.forEach(line => {
+            const row = document.createElement('div');
+            row.className = 'px-3 ' + (line.type === 'add' ? 'bg-success-50 text-success-800' : line.type === 'delete' ? 'bg-red-50 text-red-800' : 'text-slate-600');
+            const marker = line.type === 'add' ? '+' : line.type === 'delete' ? '-' : ' ';
+            row.textContent = `${marker} ${line.text}`;
+            pre.appendChild(row);
+        });
+    }
+    pre.classList.remove('hidden');
+}
+
+async function restoreTaskRevision(revision) {
+    if (!confirm(`确定要将任务「${taskRevisionsState.taskName}」恢复到版本 v${revision} 吗？`)) return;
+
+    try {
+        const response = await fetch(`/api/tasks/${taskRevisionsState.taskId}/revisions/${revision}/restore`, { method: 'POST' });
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '恢复失败', 'error');
+            return;
+        }
+        window.b1cron.showToast(`已恢复到版本 v${revision}`, 'success');
+        loadTaskRevisions();
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+window.showTaskRevisions = showTaskRevisions;
+window.loadRevisionDiff = loadRevisionDiff;
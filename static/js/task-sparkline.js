@@ -0,0 +1,92 @@
+/**
+ * B1Cron Task Sparkline
+ *
+ * 在任务列表中为每个任务绘制最近14天的平均耗时迷你趋势图
+ */
+
+const SPARKLINE_DAYS = 14;
+const SPARKLINE_WIDTH = 120;
+const SPARKLINE_HEIGHT = 20;
+const SPARKLINE_BATCH = 200;
+
+document.addEventListener('DOMContentLoaded', function() {
+    renderSparklines(document);
+
+    // HTMX 替换任务行（创建、启用/禁用）后重新绘制
+    document.body.addEventListener('htmx:afterSwap', function(e) {
+        renderSparklines(e.detail.elt.closest('tr') || e.detail.elt);
+    });
+});
+
+// renderSparklines 批量取回 root 中所有尚未绘制的任务统计，每次请求最多 SPARKLINE_BATCH 个任务
+function renderSparklines(root) {
+    const elements = Array.from(root.querySelectorAll('.task-sparkline[data-task-id]'))
+        .filter(element => !element.dataset.rendered);
+    elements.forEach(element => { element.dataset.rendered = 'true'; });
+
+    for (let i = 0; i < elements.length; i += SPARKLINE_BATCH) {
+        loadSparklines(elements.slice(i, i + SPARKLINE_BATCH));
+    }
+}
+
+async function loadSparklines(elements) {
+    try {
+        const ids = elements.map(element => element.dataset.taskId).join(',');
+        const response = await fetch(`/api/tasks/stats?ids=${ids}`);
+        if (!response.ok) return;
+
+        const statsByTask = {};
+        (await response.json()).forEach(stats => { statsByTask[stats.task_id] = stats; });
+        elements.forEach(element => {
+            const stats = statsByTask[element.dataset.taskId];
+            if (!stats) return;
+            element.innerHTML = buildSparklineSvg(stats);
+            element.title = buildSparklineTitle(stats);
+        });
+    } catch (error) {
+        console.error('Failed to load task stats:', error);
+    }
+}
+
+function buildSparklineSvg(stats) {
+    const trend = (stats.trend || []).slice(-SPARKLINE_DAYS);
+    if (!trend.some(day => day.total > 0)) {
+        return '<span class="text-xs text-slate-400">暂无执行</span>';
+    }
+
+    const maxDuration = Math.max(1, ...trend.map(day => day.avg_duration));
+    const step = SPARKLINE_WIDTH / Math.max(1, trend.length - 1);
+    const points = trend.map((day, i) => {
+        const x = (i * step).toFixed(1);
+        const y = (SPARKLINE_HEIGHT - 2 - (day.avg_duration / maxDuration) * (SPARKLINE_HEIGHT - 4)).toFixed(1);
+        return `${x},${y}`;
+    });
+
+    // 有失败的日期用红点标出
+    const failures = trend.map((day, i) => {
+        if (!day.failed) return '';
+        const [x, y] = points[i].split(',');
+        return `<circle cx="${x}" cy="${y}" r="1.8" fill="#dc2626"></circle>`;
+    }).join('');
+
+    const rate = stats.windows && stats.windows['7d'] ? stats.windows['7d'].success_rate : 100;
+    const color = rate >= 95 ? '#16a34a' : (rate >= 80 ? '#d97706' : '#dc2626');
+
+    return `<svg width="${SPARKLINE_WIDTH}" height="${SPARKLINE_HEIGHT}" viewBox="0 0 ${SPARKLINE_WIDTH} ${SPARKLINE_HEIGHT}">
+        <polyline fill="none" stroke="${color}" stroke-width="1.5" points="${points.join(' ')}"></polyline>
+        ${failures}
+    </svg>`;
+}
+
+function buildSparklineTitle(stats) {
+    const windows = stats.windows || {};
+    const rate = key => windows[key] && windows[key].total > 0 ? `${windows[key].success_rate.toFixed(1)}%` : '--';
+    const duration = stats.duration || {};
+    const streaks = stats.failure_streaks || {};
+
+    return [
+        `成功率 24h: ${rate('24h')}  7d: ${rate('7d')}  30d: ${rate('30d')}`,
+        `耗时 p50: ${duration.p50 || 0}ms  p95: ${duration.p95 || 0}ms  max: ${duration.max || 0}ms`,
+        `连续失败: 当前 ${streaks.current || 0} 次，最长 ${streaks.longest || 0} 次`
+    ].join('\n');
+}
@@ -0,0 +1,77 @@
+/**
+ * B1Cron Python 虚拟环境
+ *
+ * 编辑任务时显示声明了依赖的 Python 任务的虚拟环境状态、构建日志，并可以重新构建
+ */
+
+const VENV_STATES = {
+    ready: '已就绪',
+    building: '构建中…',
+    failed: '构建失败',
+    missing: '未构建（首次执行时构建）'
+};
+
+let venvTaskId = null;
+let venvPollTimer = null;
+
+async function loadTaskVenv(task) {
+    venvTaskId = task.id;
+    clearTimeout(venvPollTimer);
+    const panel = document.getElementById('editTaskVenv');
+    if (!panel) return;
+    panel.classList.add('hidden');
+    document.getElementById('editTaskVenvLog').classList.add('hidden');
+    if (!task.requirements) return;
+
+    try {
+        const response = await fetch(`/api/tasks/${task.id}/venv`);
+        if (!response.ok) return;
+        renderTaskVenv(await response.json());
+    } catch (error) {
+        console.error('Failed to load virtualenv status:', error);
+    }
+}
+
+function renderTaskVenv(status) {
+    document.getElementById('editTaskVenv').classList.remove('hidden');
+    const state = document.getElementById('editTaskVenvState');
+    let text = VENV_STATES[status.state] || status.state;
+    if (status.state === 'ready' && status.built_at) {
+        text += `，${new Date(status.built_at).toLocaleString()} 构建`;
+    }
+    state.textContent = text;
+    state.className = 'font-medium ' + (status.state === 'failed' ? 'text-red-600' : status.state === 'ready' ? 'text-success-600' : 'text-slate-600');
+
+    const log = document.getElementById('editTaskVenvLog');
+    log.textContent = status.log || '还没有构建日志';
+    if (status.state === 'failed') {
+        log.classList.remove('hidden');
+    }
+
+    // 构建中时定期刷新
+    clearTimeout(venvPollTimer);
+    if (status.state === 'building') {
+        const taskId = venvTaskId;
+        venvPollTimer = setTimeout(() => loadTaskVenv({ id: taskId, requirements: true }), 2000);
+    }
+}
+
+function toggleVenvLog() {
+    document.getElementById('editTaskVenvLog').classList.toggle('hidden');
+}
+
+async function rebuildTaskVenv() {
+    if (!venvTaskId) return;
+    try {
+        const response = await fetch(`/api/tasks/${venvTaskId}/venv/rebuild`, { method: 'POST' });
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || '重新构建失败', 'error');
+            return;
+        }
+        window.b1cron.showToast('已开始重新构建虚拟环境', 'success');
+        renderTaskVenv(data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
@@ -0,0 +1,136 @@
+/**
+ * B1Cron Two-Factor Authentication
+ *
+ * 当前用户的 TOTP 两步验证：扫码开通、恢复码管理和关闭
+ */
+
+document.addEventListener('DOMContentLoaded', function() {
+    const forms = {
+        enableTwoFactorForm: handleEnableTwoFactor,
+        regenerateRecoveryCodesForm: handleRegenerateRecoveryCodes,
+        disableTwoFactorForm: handleDisableTwoFactor
+    };
+    Object.keys(forms).forEach(id => {
+        const form = document.getElementById(id);
+        if (form) {
+            form.addEventListener('submit', forms[id]);
+        }
+    });
+});
+
+function showTwoFactor() {
+    window.b1cron.closeModal('settingsModal');
+    window.b1cron.showModal('twoFactorModal');
+    document.getElementById('recoveryCodesBox').classList.add('hidden');
+    document.getElementById('recoveryCodesList').textContent = '';
+    loadTwoFactorStatus();
+}
+
+function showTwoFactorSection(section) {
+    ['twoFactorDisabled', 'twoFactorSetup', 'twoFactorEnabled'].forEach(id => {
+        document.getElementById(id).classList.toggle('hidden', id !== section);
+    });
+}
+
+async function twoFactorRequest(url, method, payload) {
+    const options = { method: method };
+    if (payload) {
+        options.headers = { 'Content-Type': 'application/json' };
+        options.body = JSON.stringify(payload);
+    }
+    const response = await fetch(url, options);
+    const data = await response.json();
+    if (!response.ok) {
+        throw new Error(data.error || data.message || '操作失败');
+    }
+    return data;
+}
+
+async function loadTwoFactorStatus() {
+    try {
+        const data = await twoFactorRequest('/api/2fa', 'GET');
+        document.getElementById('recoveryCodesRemaining').textContent = data.recovery_codes_remaining;
+        showTwoFactorSection(data.enabled ? 'twoFactorEnabled' : 'twoFactorDisabled');
+    } catch (error) {
+        window.b1cron.showToast(error.message, 'error');
+    }
+}
+
+async function beginTwoFactorSetup() {
+    try {
+        const data = await twoFactorRequest('/api/2fa/setup', 'POST');
+        document.getElementById('twoFactorQRCode').src = data.qr_code;
+        document.getElementById('twoFactorSecret').textContent = data.secret;
+        document.getElementById('enableTwoFactorCode').value = '';
+        showTwoFactorSection('twoFactorSetup');
+        document.getElementById('enableTwoFactorCode').focus();
+    } catch (error) {
+        window.b1cron.showToast(error.message, 'error');
+    }
+}
+
+function showRecoveryCodes(codes) {
+    document.getElementById('recoveryCodesList').textContent = codes.join('\n');
+    document.getElementById('recoveryCodesBox').classList.remove('hidden');
+}
+
+async function handleEnableTwoFactor(e) {
+    e.preventDefault();
+    const code = document.getElementById('enableTwoFactorCode').value.trim();
+
+    try {
+        const data = await twoFactorRequest('/api/2fa/enable', 'POST', { code: code });
+        window.b1cron.showToast('两步验证已开启', 'success');
+        showRecoveryCodes(data.recovery_codes);
+        loadTwoFactorStatus();
+    } catch (error) {
+        window.b1cron.showToast(error.message, 'error');
+    }
+}
+
+async function handleRegenerateRecoveryCodes(e) {
+    e.preventDefault();
+    const input = document.getElementById('regenerateRecoveryCode');
+
+    try {
+        const data = await twoFactorRequest('/api/2fa/recovery-codes', 'POST', { code: input.value.trim() });
+        window.b1cron.showToast('恢复码已重新生成', 'success');
+        input.value = '';
+        showRecoveryCodes(data.recovery_codes);
+        loadTwoFactorStatus();
+    } catch (error) {
+        window.b1cron.showToast(error.message, 'error');
+    }
+}
+
+async function handleDisableTwoFactor(e) {
+    e.preventDefault();
+    if (!confirm('确定要关闭两步验证吗？关闭后只需密码即可登录。')) return;
+
+    const form = e.target;
+    try {
+        await twoFactorRequest('/api/2fa/disable', 'POST', {
+            password: document.getElementById('disableTwoFactorPassword').value,
+            code: document.getElementById('disableTwoFactorCode').value.trim()
+        });
+        window.b1cron.showToast('两步验证已关闭', 'success');
+        form.reset();
+        document.getElementById('recoveryCodesBox').classList.add('hidden');
+        loadTwoFactorStatus();
+    } catch (error) {
+        window.b1cron.showToast(error.message, 'error');
+    }
+}
+
+async function copyRecoveryCodes() {
+    try {
+        await navigator.clipboard.writeText(document.getElementById('recoveryCodesList').textContent);
+        window.b1cron.showToast('已复制到剪贴板', 'success');
+    } catch (error) {
+        window.b1cron.showToast('复制失败，请手动复制', 'error');
+    }
+}
+
+window.showTwoFactor = showTwoFactor;
+window.beginTwoFactorSetup = beginTwoFactorSetup;
+window.copyRecoveryCodes = copyRecoveryCodes;
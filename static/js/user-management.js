@@ -0,0 +1,267 @@
+/**
+ * B1Cron User Management
+ *
+ * 管理员的用户管理：列出用户、添加用户、修改角色、重置密码和两步验证、查看会话、解锁和删除用户
+ */
+
+const USER_ROLES = ['viewer', 'operator', 'admin'];
+
+const LOGIN_FAILURE_REASONS = {
+    unknown_user: '用户不存在',
+    bad_password: '密码错误',
+    account_locked: '账户已锁定',
+    rate_limited: '尝试过于频繁',
+    sso_error: '单点登录失败',
+    sso_no_role: '用户组无对应角色',
+    sso_conflict: '用户名已被占用'
+};
+
+document.addEventListener('DOMContentLoaded', function() {
+    const form = document.getElementById('createUserForm');
+    if (form) {
+        form.addEventListener('submit', handleCreateUser);
+    }
+});
+
+function showUserManagement() {
+    window.b1cron.closeModal('settingsModal');
+    window.b1cron.showModal('usersModal');
+    loadUsers();
+}
+
+async function loadUsers() {
+    const tbody = document.getElementById('usersTableBody');
+    if (!tbody) return;
+
+    try {
+        const response = await fetch('/api/users');
+        const data = await response.json();
+        if (!response.ok) {
+            window.b1cron.showToast(data.error || data.message || '加载用户失败', 'error');
+            return;
+        }
+        renderUsers(tbody, data);
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadFailedLogins();
+}
+
+async function loadFailedLogins() {
+    const container = document.getElementById('failedLoginsList');
+    if (!container) return;
+
+    try {
+        const response = await fetch('/api/audit?action=login.failure&page_size=20');
+        const data = await response.json();
+        if (!response.ok) return;
+
+        container.innerHTML = '';
+        if (data.logs.length === 0) {
+            const empty = document.createElement('div');
+            empty.className = 'px-3 py-2 text-slate-500';
+            empty.textContent = '暂无失败的登录';
+            container.appendChild(empty);
+            return;
+        }
+
+        data.logs.forEach(log => {
+            const changes = log.changes ? JSON.parse(log.changes) : {};
+            const reason = changes.reason ? changes.reason.after : '';
+            const row = document.createElement('div');
+            row.className = 'px-3 py-2 text-slate-700';
+            row.textContent = `${new Date(log.created_at).toLocaleString('zh-CN')} · ${log.actor_name || '-'} · ${log.ip || '-'} · ${LOGIN_FAILURE_REASONS[reason] || reason || '-'}`;
+            container.appendChild(row);
+        });
+    } catch (error) {
+        // 失败登录列表只是辅助信息，加载失败不提示
+    }
+}
+
+function renderUsers(tbody, users) {
+    tbody.innerHTML = '';
+
+    users.forEach(user => {
+        const row = document.createElement('tr');
+
+        const nameCell = document.createElement('td');
+        nameCell.className = 'px-4 py-2 text-sm text-slate-900';
+        nameCell.textContent = user.username;
+        if (user.auth_provider === 'oidc') {
+            const ssoBadge = document.createElement('span');
+            ssoBadge.className = 'ml-2 inline-flex items-center px-1.5 py-0.5 rounded text-xs bg-primary-50 text-primary-700';
+            ssoBadge.textContent = 'SSO';
+            ssoBadge.title = '单点登录用户，角色在每次登录时按用户组同步';
+            nameCell.appendChild(ssoBadge);
+        }
+        const locked = user.locked_until && new Date(user.locked_until) > new Date();
+        if (locked || user.failed_logins > 0) {
+            const badge = document.createElement('span');
+            badge.className = `ml-2 inline-flex items-center px-1.5 py-0.5 rounded text-xs ${locked ? 'bg-red-100 text-red-800' : 'bg-warning-100 text-warning-800'}`;
+            badge.textContent = locked
+                ? `🔒 锁定至 ${new Date(user.locked_until).toLocaleTimeString('zh-CN')}`
+                : `失败 ${user.failed_logins} 次`;
+            nameCell.appendChild(badge);
+        }
+        row.appendChild(nameCell);
+
+        const roleCell = document.createElement('td');
+        roleCell.className = 'px-4 py-2';
+        const roleSelect = document.createElement('select');
+        roleSelect.className = 'px-2 py-1 border border-slate-300 rounded-md text-sm';
+        USER_ROLES.forEach(role => {
+            const option = document.createElement('option');
+            option.value = role;
+            option.textContent = role;
+            option.selected = role === user.role;
+            roleSelect.appendChild(option);
+        });
+        roleSelect.addEventListener('change', () => updateUser(user.id, { role: roleSelect.value }));
+        roleCell.appendChild(roleSelect);
+        row.appendChild(roleCell);
+
+        const actionCell = document.createElement('td');
+        actionCell.className = 'px-4 py-2 flex gap-2';
+        if (user.auth_provider !== 'oidc') {
+            actionCell.appendChild(createUserActionButton('重置密码', 'text-slate-700 border-slate-300 hover:bg-slate-50', () => resetUserPassword(user)));
+        }
+        if (user.totp_enabled) {
+            actionCell.appendChild(createUserActionButton('重置两步验证', 'text-slate-700 border-slate-300 hover:bg-slate-50', () => resetUserTwoFactor(user)));
+        }
+        actionCell.appendChild(createUserActionButton('会话', 'text-slate-700 border-slate-300 hover:bg-slate-50', () => showSessions(user)));
+        if (locked || user.failed_logins > 0) {
+            actionCell.appendChild(createUserActionButton('解锁', 'text-warning-700 border-warning-300 bg-warning-50 hover:bg-warning-100', () => unlockUser(user)));
+        }
+        actionCell.appendChild(createUserActionButton('删除', 'text-red-700 border-red-300 bg-red-50 hover:bg-red-100', () => deleteUser(user)));
+        row.appendChild(actionCell);
+
+        tbody.appendChild(row);
+    });
+}
+
+function createUserActionButton(text, classes, onClick) {
+    const button = document.createElement('button');
+    button.type = 'button';
+    button.className = `px-2 py-1 border rounded-md text-xs font-medium transition-colors duration-150 ${classes}`;
+    button.textContent = text;
+    button.addEventListener('click', onClick);
+    return button;
+}
+
+async function handleCreateUser(e) {
+    e.preventDefault();
+
+    const form = e.target;
+    const formData = new FormData(form);
+
+    try {
+        const response = await fetch('/api/users', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                username: formData.get('username'),
+                password: formData.get('password'),
+                role: formData.get('role')
+            })
+        });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('用户已添加，首次登录需修改密码', 'success');
+            form.reset();
+            loadUsers();
+        } else {
+            window.b1cron.showToast(data.error || data.message || '添加用户失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+}
+
+async function updateUser(userId, payload) {
+    try {
+        const response = await fetch(`/api/users/${userId}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(payload)
+        });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('用户已更新', 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '更新用户失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadUsers();
+}
+
+function resetUserPassword(user) {
+    const password = prompt(`为用户「${user.username}」设置新密码（至少6位），用户的全部会话将立即失效，下次登录需修改密码：`);
+    if (password === null) return;
+    if (password.length < 6) {
+        window.b1cron.showToast('密码至少6位', 'error');
+        return;
+    }
+    updateUser(user.id, { password: password });
+}
+
+async function deleteUser(user) {
+    if (!confirm(`确定要删除用户「${user.username}」吗？`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`/api/users/${user.id}`, { method: 'DELETE' });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('用户已删除', 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '删除用户失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadUsers();
+}
+
+async function unlockUser(user) {
+    try {
+        const response = await fetch(`/api/users/${user.id}/unlock`, { method: 'POST' });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast(`用户「${user.username}」已解锁`, 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '解锁失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadUsers();
+}
+
+async function resetUserTwoFactor(user) {
+    if (!confirm(`确定要关闭用户「${user.username}」的两步验证吗？用户需要重新设置认证器。`)) {
+        return;
+    }
+
+    try {
+        const response = await fetch(`/api/users/${user.id}/2fa`, { method: 'DELETE' });
+        const data = await response.json();
+
+        if (response.ok) {
+            window.b1cron.showToast('两步验证已重置', 'success');
+        } else {
+            window.b1cron.showToast(data.error || data.message || '重置两步验证失败', 'error');
+        }
+    } catch (error) {
+        window.b1cron.showToast('网络错误，请重试', 'error');
+    }
+    loadUsers();
+}
+
+window.showUserManagement = showUserManagement;